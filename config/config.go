@@ -25,10 +25,55 @@ type JWTConfig struct {
 	ExpireHours int    `mapstructure:"expire_hours"`
 }
 
+// AdminConfig 管理员配置
+type AdminConfig struct {
+	UserIDs []uint `mapstructure:"user_ids"` // 拥有管理员权限的用户ID
+}
+
+// DuplicateConfig 创建垃圾桶时的重复检测配置
+type DuplicateConfig struct {
+	RadiusM float64 `mapstructure:"radius_m"` // 该距离（米）以内已有垃圾桶时提示可能重复
+}
+
+// SyncConfig 离线增量同步配置
+type SyncConfig struct {
+	ChangeRetentionDays int `mapstructure:"change_retention_days"` // 变更日志保留天数，超过该时间没有同步的客户端需要重新全量同步
+}
+
+// ServiceAreaConfig 服务范围（地理围栏）配置，polygons 和 geojson_file 都未配置时不限制范围
+// 两者同时配置时服务范围为它们的并集
+type ServiceAreaConfig struct {
+	Polygons    [][][]float64 `mapstructure:"polygons"`     // 多边形列表，每个多边形为 [经度, 纬度] 点组成的外环
+	GeoJSONFile string        `mapstructure:"geojson_file"` // GeoJSON 文件路径，支持 Polygon、MultiPolygon、Feature 和 FeatureCollection
+	CoordSys    string        `mapstructure:"coord_sys"`    // 多边形坐标使用的坐标系，默认wgs84
+}
+
+// LifecycleConfig 垃圾桶生命周期配置
+type LifecycleConfig struct {
+	MissingReportThreshold int `mapstructure:"missing_report_threshold"` // 该数量的用户报告不存在后自动变为 reported_missing
+}
+
+// RankingConfig 附近搜索按综合得分排序（sort=score）时的权重配置
+// 综合得分 = 距离权重*距离得分 + 评价权重*评价得分 + 新鲜度权重*新鲜度得分
+type RankingConfig struct {
+	DistanceWeight      float64 `mapstructure:"distance_weight"`        // 距离得分权重
+	QualityWeight       float64 `mapstructure:"quality_weight"`         // 评价得分权重
+	RecencyWeight       float64 `mapstructure:"recency_weight"`         // 新鲜度得分权重
+	DistanceScaleKm     float64 `mapstructure:"distance_scale_km"`      // 距离衰减尺度（公里），距离为该值时距离得分约为0.37
+	RecencyHalfLifeDays float64 `mapstructure:"recency_half_life_days"` // 新鲜度半衰期（天）
+	WilsonZ             float64 `mapstructure:"wilson_z"`               // Wilson置信区间的z值，1.96对应95%置信度
+}
+
 // System 定义项目配置文件结构体
 type System struct {
-	GinConfig    *GinConfig    `mapstructure:"gin"`
-	AmapConfig   *AmapConfig   `mapstructure:"amap"`
-	UploadConfig *UploadConfig `mapstructure:"upload"`
-	JWTConfig    *JWTConfig    `mapstructure:"jwt"`
+	GinConfig         *GinConfig         `mapstructure:"gin"`
+	AmapConfig        *AmapConfig        `mapstructure:"amap"`
+	UploadConfig      *UploadConfig      `mapstructure:"upload"`
+	JWTConfig         *JWTConfig         `mapstructure:"jwt"`
+	RankingConfig     *RankingConfig     `mapstructure:"ranking"`
+	DuplicateConfig   *DuplicateConfig   `mapstructure:"duplicate"`
+	AdminConfig       *AdminConfig       `mapstructure:"admin"`
+	SyncConfig        *SyncConfig        `mapstructure:"sync"`
+	ServiceAreaConfig *ServiceAreaConfig `mapstructure:"service_area"`
+	LifecycleConfig   *LifecycleConfig   `mapstructure:"lifecycle"`
 }
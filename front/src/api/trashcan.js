@@ -5,10 +5,11 @@ import request from '@/utils/request'
  * @param {number} lat - 纬度
  * @param {number} lng - 经度
  * @param {number} radius - 搜索半径（公里），默认5
- * @param {number} limit - 返回数量限制，默认10
+ * @param {number} limit - 每页数量，默认10
+ * @param {string} cursor - 上一页返回的 next_cursor，查询第一页时不传
  * @returns {Promise}
  */
-export function getNearbyTrashCans(lat, lng, radius = 5, limit = 10) {
+export function getNearbyTrashCans(lat, lng, radius = 5, limit = 10, cursor = '') {
   return request({
     url: '/trashcans/nearby',
     method: 'get',
@@ -16,7 +17,47 @@ export function getNearbyTrashCans(lat, lng, radius = 5, limit = 10) {
       lat,
       lng,
       radius,
-      limit
+      limit,
+      cursor: cursor || undefined
+    }
+  })
+}
+
+/**
+ * 获取地图视野范围内的垃圾桶
+ * 返回数据中 truncated 为 true 时表示结果被截断，需要放大地图后再查询
+ * @param {object} bounds - 视野范围 { swLat, swLng, neLat, neLng }，swLng 大于 neLng 表示跨越180度经线
+ * @returns {Promise}
+ */
+export function getTrashCansInBounds({ swLat, swLng, neLat, neLng }) {
+  return request({
+    url: '/trashcans/in-bounds',
+    method: 'get',
+    params: {
+      sw_lat: swLat,
+      sw_lng: swLng,
+      ne_lat: neLat,
+      ne_lng: neLng
+    }
+  })
+}
+
+/**
+ * 按缩放级别获取视野范围内的垃圾桶聚合结果
+ * @param {object} bounds - 视野范围 { swLat, swLng, neLat, neLng }
+ * @param {number} zoom - 地图缩放级别
+ * @returns {Promise}
+ */
+export function getTrashCanClusters({ swLat, swLng, neLat, neLng }, zoom) {
+  return request({
+    url: '/trashcans/clusters',
+    method: 'get',
+    params: {
+      sw_lat: swLat,
+      sw_lng: swLng,
+      ne_lat: neLat,
+      ne_lng: neLng,
+      zoom
     }
   })
 }
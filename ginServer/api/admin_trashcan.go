@@ -0,0 +1,427 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/internal/modules/importer"
+	"template/utils"
+)
+
+// errMergeInvalid 合并参数与数据不符，错误信息可以直接返回给客户端
+type errMergeInvalid struct {
+	msg string
+}
+
+func (e errMergeInvalid) Error() string {
+	return e.msg
+}
+
+// mergeResult 合并结果统计
+type mergeResult struct {
+	MovedVotes   int `json:"moved_votes"`   // 转移到保留垃圾桶的投票数
+	RemovedVotes int `json:"removed_votes"` // 同一用户重复投票被删除的数量
+	Images       int `json:"images"`        // 移入保留垃圾桶图库的图片数量
+}
+
+// MergeTrashCans 将多个重复的垃圾桶合并为一个（管理员）
+// POST /api/admin/trashcans/merge
+// 请求体：{"survivor_id": 1, "merge_ids": [2, 3], "coordinate": "average", "coordinate_id": 0, "image_id": 0}
+// 在同一事务中完成：更新保留垃圾桶的坐标和封面、被合并垃圾桶的图库移入保留垃圾桶、投票转移到保留垃圾桶
+// （同一用户对多个垃圾桶都投过票时只保留一票）、为被合并的ID留下指向保留垃圾桶的重定向记录，最后删除被合并的垃圾桶
+func MergeTrashCans(c *gin.Context) {
+	var req request.MergeTrashCansRequest
+	if !common.BindJSON(c, &req) {
+		return
+	}
+	if req.Coordinate == "average" && req.CoordinateID != 0 {
+		common.ParamErrorWithMessage("参数 coordinate=average 时不能指定 coordinate_id", c)
+		return
+	}
+
+	mergeIDs := make([]uint, 0, len(req.MergeIDs))
+	seen := map[uint]bool{req.SurvivorID: true}
+	for _, id := range req.MergeIDs {
+		if id == req.SurvivorID {
+			common.ParamErrorWithMessage("参数 merge_ids 不能包含 survivor_id", c)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			mergeIDs = append(mergeIDs, id)
+		}
+	}
+
+	adminID := c.MustGet("userID").(uint)
+
+	var result mergeResult
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = mergeTrashCans(tx, req, mergeIDs, adminID)
+		return err
+	})
+	var invalid errMergeInvalid
+	if errors.As(err, &invalid) {
+		common.ParamErrorWithMessage(invalid.msg, c)
+		return
+	}
+	if err != nil {
+		global.SugarLogger.Errorf("合并垃圾桶失败: %v", err)
+		common.FailWithMessage("合并失败", c)
+		return
+	}
+
+	trashCans, err := loadTrashCans([]uint{req.SurvivorID})
+	if err != nil || len(trashCans) == 0 {
+		global.SugarLogger.Errorf("查询合并后的垃圾桶失败: %v", err)
+		common.FailWithMessage("合并成功，但查询合并结果失败", c)
+		return
+	}
+	coordSys, _ := coordSysParam(c)
+
+	data := map[string]interface{}{
+		"trash_can":     newTrashCanItems(trashCans, coordSys)[0],
+		"merged_ids":    mergeIDs,
+		"moved_votes":   result.MovedVotes,
+		"removed_votes": result.RemovedVotes,
+		"images":        result.Images,
+	}
+	common.OkWithDetailed(data, "合并成功", c)
+}
+
+// mergeTrashCans 在事务中执行合并
+func mergeTrashCans(tx *gorm.DB, req request.MergeTrashCansRequest, mergeIDs []uint, adminID uint) (mergeResult, error) {
+	var result mergeResult
+	allIDs := append([]uint{req.SurvivorID}, mergeIDs...)
+
+	var rows []model.TrashCan
+	if err := tx.Where("id IN ?", allIDs).Find(&rows).Error; err != nil {
+		return result, err
+	}
+	byID := make(map[uint]model.TrashCan, len(rows))
+	for _, tc := range rows {
+		byID[tc.ID] = tc
+	}
+	for _, id := range allIDs {
+		if _, ok := byID[id]; !ok {
+			return result, errMergeInvalid{fmt.Sprintf("垃圾桶 %d 不存在", id)}
+		}
+	}
+	survivor := byID[req.SurvivorID]
+
+	var votes []model.TrashCanLike
+	if err := tx.Where("trash_can_id IN ?", allIDs).Order("id").Find(&votes).Error; err != nil {
+		return result, err
+	}
+
+	// 坐标
+	lat, lng := survivor.Latitude, survivor.Longitude
+	switch {
+	case req.CoordinateID != 0:
+		picked, ok := byID[req.CoordinateID]
+		if !ok {
+			return result, errMergeInvalid{"参数 coordinate_id 必须是参与合并的垃圾桶"}
+		}
+		lat, lng = picked.Latitude, picked.Longitude
+	case req.Coordinate == "average":
+		// 重复的垃圾桶彼此距离很近，直接取经纬度的算术平均
+		lat, lng = 0, 0
+		for _, tc := range rows {
+			lat += tc.Latitude
+			lng += tc.Longitude
+		}
+		lat /= float64(len(rows))
+		lng /= float64(len(rows))
+	}
+
+	// 图片：被合并垃圾桶的图库全部移入保留的垃圾桶，按参与合并的顺序排在后面；
+	// 封面为指定垃圾桶的封面，或有图片的垃圾桶中评价最好的垃圾桶的封面
+	imageOwner, err := pickMergeImage(req, allIDs, byID, votes)
+	if err != nil {
+		return result, err
+	}
+	var images []model.TrashCanImage
+	if err := tx.Where("trash_can_id IN ?", allIDs).Find(&images).Error; err != nil {
+		return result, err
+	}
+	position := make(map[uint]int, len(allIDs))
+	for i, id := range allIDs {
+		position[id] = i
+	}
+	sort.Slice(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if position[a.TrashCanID] != position[b.TrashCanID] {
+			return position[a.TrashCanID] < position[b.TrashCanID]
+		}
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.ID < b.ID
+	})
+	var cover *model.TrashCanImage
+	for i := range images {
+		image := &images[i]
+		if imageOwner != 0 && image.TrashCanID == imageOwner && image.ImagePath == byID[imageOwner].ImagePath {
+			cover = image
+		}
+		if image.TrashCanID != survivor.ID {
+			result.Images++
+		}
+		if err := tx.Model(image).Updates(map[string]interface{}{
+			"trash_can_id": survivor.ID,
+			"sort_order":   i,
+		}).Error; err != nil {
+			return result, err
+		}
+	}
+	if cover == nil && len(images) > 0 {
+		cover = &images[0]
+	}
+
+	updates := map[string]interface{}{
+		"latitude":  lat,
+		"longitude": lng,
+	}
+	// 垃圾分类取全部垃圾桶的并集；保留垃圾桶缺少地址或描述时使用被合并垃圾桶的
+	categories := survivor.Categories
+	for _, id := range mergeIDs {
+		categories |= byID[id].Categories
+	}
+	updates["categories"] = categories
+	for _, id := range mergeIDs {
+		if survivor.Address == "" && updates["address"] == nil && byID[id].Address != "" {
+			updates["address"] = byID[id].Address
+		}
+		if survivor.Description == "" && updates["description"] == nil && byID[id].Description != "" {
+			updates["description"] = byID[id].Description
+		}
+	}
+	if err := tx.Model(&survivor).Updates(updates).Error; err != nil {
+		return result, err
+	}
+	if err := setTrashCanCover(tx, survivor.ID, cover); err != nil {
+		return result, err
+	}
+
+	// 投票：每个用户只保留一票，优先保留对保留垃圾桶的投票，否则保留最近的一票
+	keep := make(map[uint]model.TrashCanLike)
+	for _, v := range votes {
+		kept, ok := keep[v.UserID]
+		if !ok || (kept.TrashCanID != survivor.ID && (v.TrashCanID == survivor.ID || v.ID > kept.ID)) {
+			keep[v.UserID] = v
+		}
+	}
+	var removeVoteIDs, moveVoteIDs []uint
+	for _, v := range votes {
+		if keep[v.UserID].ID != v.ID {
+			removeVoteIDs = append(removeVoteIDs, v.ID)
+		} else if v.TrashCanID != survivor.ID {
+			moveVoteIDs = append(moveVoteIDs, v.ID)
+		}
+	}
+	if len(removeVoteIDs) > 0 {
+		if err := tx.Where("id IN ?", removeVoteIDs).Delete(&model.TrashCanLike{}).Error; err != nil {
+			return result, err
+		}
+	}
+	if len(moveVoteIDs) > 0 {
+		if err := tx.Model(&model.TrashCanLike{}).
+			Where("id IN ?", moveVoteIDs).
+			Update("trash_can_id", survivor.ID).Error; err != nil {
+			return result, err
+		}
+	}
+	result.RemovedVotes, result.MovedVotes = len(removeVoteIDs), len(moveVoteIDs)
+
+	// 疑似重复记录：参与本次合并的记录视为已审核，其余记录改为指向保留垃圾桶
+	if err := tx.Model(&model.TrashCanDuplicate{}).
+		Where("trash_can_id IN ? AND duplicate_of_id IN ?", allIDs, allIDs).
+		Update("reviewed", true).Error; err != nil {
+		return result, err
+	}
+	if err := tx.Model(&model.TrashCanDuplicate{}).
+		Where("trash_can_id IN ?", mergeIDs).
+		Update("trash_can_id", survivor.ID).Error; err != nil {
+		return result, err
+	}
+	if err := tx.Model(&model.TrashCanDuplicate{}).
+		Where("duplicate_of_id IN ?", mergeIDs).
+		Update("duplicate_of_id", survivor.ID).Error; err != nil {
+		return result, err
+	}
+
+	// 重定向：之前合并到被合并垃圾桶的ID也改为直接指向保留垃圾桶
+	if err := tx.Model(&model.TrashCanRedirect{}).
+		Where("to_id IN ?", mergeIDs).
+		Update("to_id", survivor.ID).Error; err != nil {
+		return result, err
+	}
+	redirects := make([]model.TrashCanRedirect, 0, len(mergeIDs))
+	for _, id := range mergeIDs {
+		redirects = append(redirects, model.TrashCanRedirect{FromID: id, ToID: survivor.ID, MergedBy: adminID})
+	}
+	if err := tx.Create(&redirects).Error; err != nil {
+		return result, err
+	}
+
+	// 对被合并垃圾桶的不存在报告不适用于保留的垃圾桶，状态变更记录保留备查
+	if err := tx.Where("trash_can_id IN ?", mergeIDs).Delete(&model.TrashCanMissingReport{}).Error; err != nil {
+		return result, err
+	}
+
+	merged := make([]model.TrashCan, 0, len(mergeIDs))
+	for _, id := range mergeIDs {
+		merged = append(merged, byID[id])
+	}
+	if err := tx.Delete(&merged).Error; err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// pickMergeImage 选择合并后使用的图片，返回图片所属的垃圾桶ID，均没有图片时返回0
+// 未指定时选择点赞数减点踩数最高的，相同时优先保留垃圾桶，其次ID较小的
+func pickMergeImage(req request.MergeTrashCansRequest, ids []uint, byID map[uint]model.TrashCan, votes []model.TrashCanLike) (uint, error) {
+	if req.ImageID != 0 {
+		tc, ok := byID[req.ImageID]
+		if !ok {
+			return 0, errMergeInvalid{"参数 image_id 必须是参与合并的垃圾桶"}
+		}
+		if tc.ImagePath == "" {
+			return 0, errMergeInvalid{"参数 image_id 指定的垃圾桶没有图片"}
+		}
+		return tc.ID, nil
+	}
+
+	scores := make(map[uint]int)
+	for _, v := range votes {
+		scores[v.TrashCanID] += int(v.Type)
+	}
+	var best uint
+	for _, id := range ids {
+		if byID[id].ImagePath == "" {
+			continue
+		}
+		if best == 0 || scores[id] > scores[best] {
+			best = id
+		}
+	}
+	return best, nil
+}
+
+const (
+	maxImportFileSize = 10 * 1024 * 1024 // 导入文件大小上限
+	maxImportRows     = 20000            // 单次最多导入的记录数
+)
+
+// ImportTrashCans 从CSV或GeoJSON文件批量导入垃圾桶（管理员）
+// POST /api/admin/trashcans/import
+// 表单参数：
+//   - file: 导入文件（必填）
+//   - format: csv 或 geojson，默认按文件扩展名判断
+//   - mapping: CSV列映射，如 "latitude=纬度,longitude=经度,address=地址,description=备注"，GeoJSON中映射属性名
+//   - coord_sys: 文件中经纬度的坐标系，CSV默认gcj02，GeoJSON默认wgs84
+//   - dry_run: 为true时只校验和检测重复，不写入数据库
+//
+// 返回逐行的导入结果：accepted=已导入，skipped=重复已跳过，failed=数据有误
+func ImportTrashCans(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		common.ParamErrorWithMessage("参数 file 不能为空", c)
+		return
+	}
+	if file.Size > maxImportFileSize {
+		common.ParamErrorWithMessage("文件大小超过限制（10MB）", c)
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file.Filename)) {
+		case ".csv":
+			format = importer.FormatCSV
+		case ".geojson", ".json":
+			format = importer.FormatGeoJSON
+		}
+	}
+	if format != importer.FormatCSV && format != importer.FormatGeoJSON {
+		common.ParamErrorWithMessage("参数 format 只能是 csv、geojson 之一", c)
+		return
+	}
+
+	mapping, err := importer.ParseColumnMapping(c.PostForm("mapping"))
+	if err != nil {
+		common.ParamErrorWithMessage(err.Error(), c)
+		return
+	}
+
+	// GeoJSON 规范要求使用WGS84坐标
+	coordSys := utils.CoordGCJ02
+	if format == importer.FormatGeoJSON {
+		coordSys = utils.CoordWGS84
+	}
+	if value := c.PostForm("coord_sys"); value != "" {
+		var ok bool
+		if coordSys, ok = utils.ParseCoordSys(value); !ok {
+			common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+			return
+		}
+	}
+
+	dryRun := false
+	if value := c.PostForm("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			common.ParamErrorWithMessage("参数 dry_run 格式错误", c)
+			return
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		global.SugarLogger.Errorf("打开导入文件失败: %v", err)
+		common.FailWithMessage("读取文件失败", c)
+		return
+	}
+	defer src.Close()
+
+	adminID := c.MustGet("userID").(uint)
+	report, err := importer.Import(global.DB, src, importer.Options{
+		Format:          format,
+		Mapping:         mapping,
+		CoordSys:        coordSys,
+		DryRun:          dryRun,
+		DuplicateRadius: duplicateRadius(),
+		MaxRows:         maxImportRows,
+		UserID:          &adminID,
+	})
+	var inputErr *importer.InputError
+	if errors.As(err, &inputErr) {
+		common.ParamErrorWithMessage(inputErr.Error(), c)
+		return
+	}
+	if err != nil {
+		global.SugarLogger.Errorf("导入垃圾桶失败: %v", err)
+		common.FailWithMessage("导入失败，未写入任何数据", c)
+		return
+	}
+
+	msg := "导入完成"
+	if dryRun {
+		msg = "试运行完成，未写入数据"
+	} else {
+		global.SugarLogger.Infof("管理员 %d 导入垃圾桶：%d 条导入，%d 条重复，%d 条失败",
+			adminID, report.Accepted, report.Skipped, report.Failed)
+	}
+	common.OkWithDetailed(report, msg, c)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"math"
+	"mime/multipart"
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+func TestMergeTrashCans(t *testing.T) {
+	r := setupTestServer(t)
+	r.POST("/admin/trashcans/merge", MergeTrashCans)
+	r.GET("/trashcans/:id", GetTrashCanDetail)
+	r.GET("/trashcans/:id/images", GetTrashCanImages)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, Latitude: 31.2300, Longitude: 121.4700, ImagePath: "a.jpg"},
+		{ID: 2, Latitude: 31.2302, Longitude: 121.4702, Address: "人民广场东门", ImagePath: "b.jpg"},
+		{ID: 3, Latitude: 31.2304, Longitude: 121.4704},
+		{ID: 4, Latitude: 31.2400, Longitude: 121.4800},
+	})
+	for _, image := range []model.TrashCanImage{
+		{TrashCanID: 1, ImagePath: "a.jpg", IsCover: true},
+		{TrashCanID: 2, ImagePath: "b.jpg", IsCover: true},
+		{TrashCanID: 2, ImagePath: "b2.jpg", SortOrder: 1},
+	} {
+		if err := global.DB.Create(&image).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 用户1对 #1 和 #2 都投了票，只保留对保留垃圾桶的一票；用户2对 #3 的投票转移到 #1
+	// #2 的评价最好，合并后使用它的图片
+	for _, vote := range []model.TrashCanLike{
+		{UserID: 1, TrashCanID: 1, Type: -1},
+		{UserID: 1, TrashCanID: 2, Type: 1},
+		{UserID: 2, TrashCanID: 2, Type: 1},
+		{UserID: 3, TrashCanID: 3, Type: 1},
+	} {
+		if err := global.DB.Create(&vote).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{"包含保留的垃圾桶", map[string]interface{}{"survivor_id": 1, "merge_ids": []uint{1, 2}}},
+		{"垃圾桶不存在", map[string]interface{}{"survivor_id": 1, "merge_ids": []uint{2, 99}}},
+		{"坐标参数冲突", map[string]interface{}{"survivor_id": 1, "merge_ids": []uint{2}, "coordinate": "average", "coordinate_id": 2}},
+		{"指定的图片不属于参与合并的垃圾桶", map[string]interface{}{"survivor_id": 1, "merge_ids": []uint{2}, "image_id": 4}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := doJSON(t, r, "POST", "/admin/trashcans/merge", tt.body, testAdminID); resp.Code != 4000 {
+				t.Errorf("响应 = %d %s, want 4000", resp.Code, resp.Msg)
+			}
+		})
+	}
+
+	var merged struct {
+		TrashCan     trashCanItem `json:"trash_can"`
+		MergedIDs    []uint       `json:"merged_ids"`
+		MovedVotes   int          `json:"moved_votes"`
+		RemovedVotes int          `json:"removed_votes"`
+		Images       int          `json:"images"`
+	}
+	decodeData(t, doJSON(t, r, "POST", "/admin/trashcans/merge",
+		map[string]interface{}{"survivor_id": 1, "merge_ids": []uint{2, 3, 3}, "coordinate": "average"}, testAdminID), &merged)
+	if len(merged.MergedIDs) != 2 || merged.MovedVotes != 2 || merged.RemovedVotes != 1 || merged.Images != 2 {
+		t.Errorf("合并结果 = %+v", merged)
+	}
+	survivor := merged.TrashCan
+	if math.Abs(survivor.Latitude-31.2302) > 1e-9 || math.Abs(survivor.Longitude-121.4702) > 1e-9 {
+		t.Errorf("合并后的坐标 = (%v, %v), want 平均值", survivor.Latitude, survivor.Longitude)
+	}
+	if survivor.Address != "人民广场东门" || survivor.ImagePath != "b.jpg" || survivor.ImageCount != 3 ||
+		survivor.LikeCount != 2 || survivor.DislikeCount != 1 {
+		t.Errorf("合并后的垃圾桶 = %+v", survivor)
+	}
+
+	var count int64
+	global.DB.Model(&model.TrashCan{}).Where("id IN ?", []uint{2, 3}).Count(&count)
+	if count != 0 {
+		t.Errorf("被合并的垃圾桶仍有 %d 个", count)
+	}
+	// 被合并的ID通过重定向查询到保留的垃圾桶
+	var detail struct {
+		ID uint `json:"id"`
+	}
+	decodeData(t, doRequest(t, r, "GET", "/trashcans/3", nil, "", 0), &detail)
+	if detail.ID != 1 {
+		t.Errorf("GET /trashcans/3 返回 #%d, want #1", detail.ID)
+	}
+	var gallery []trashCanImageItem
+	decodeData(t, doRequest(t, r, "GET", "/trashcans/1/images", nil, "", 0), &gallery)
+	if len(gallery) != 3 || gallery[1].ID != 2 || !gallery[1].IsCover {
+		t.Errorf("合并后的图库 = %+v, 应依次为 #1、#2 的图片并以 #2 的封面为封面", gallery)
+	}
+}
+
+func TestImportTrashCans(t *testing.T) {
+	r := setupTestServer(t)
+	r.POST("/admin/trashcans/import", ImportTrashCans)
+	r.GET("/trashcans/nearby", GetNearbyTrashCans)
+
+	createTestTrashCans(t, []model.TrashCan{{ID: 1, Latitude: 31.2304, Longitude: 121.4737}})
+	const csvData = "lat,lng,address\n" +
+		"31.23041,121.47371,与已有垃圾桶重复\n" +
+		"31.2500,121.4900,新位置\n" +
+		"abc,121.49,坐标有误\n"
+
+	upload := func(filename, data string, fields map[string]string) testResponse {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, err := w.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(data))
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		w.Close()
+		return doRequest(t, r, "POST", "/admin/trashcans/import", &body, w.FormDataContentType(), testAdminID)
+	}
+	type importReport struct {
+		DryRun   bool `json:"dry_run"`
+		Total    int  `json:"total"`
+		Accepted int  `json:"accepted"`
+		Skipped  int  `json:"skipped"`
+		Failed   int  `json:"failed"`
+		Rows     []struct {
+			Row         int    `json:"row"`
+			Status      string `json:"status"`
+			ID          uint   `json:"id"`
+			DuplicateOf uint   `json:"duplicate_of"`
+		} `json:"rows"`
+	}
+	countTrashCans := func() int64 {
+		var count int64
+		global.DB.Model(&model.TrashCan{}).Count(&count)
+		return count
+	}
+
+	for _, tt := range []struct {
+		name     string
+		filename string
+		fields   map[string]string
+	}{
+		{"无法判断格式", "data.txt", nil},
+		{"坐标系有误", "data.csv", map[string]string{"coord_sys": "epsg3857"}},
+		{"列映射有误", "data.csv", map[string]string{"mapping": "name=名称"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := upload(tt.filename, csvData, tt.fields); resp.Code != 4000 {
+				t.Errorf("响应 = %d %s, want 4000", resp.Code, resp.Msg)
+			}
+		})
+	}
+
+	var report importReport
+	decodeData(t, upload("data.csv", csvData, map[string]string{"dry_run": "true", "coord_sys": "gcj02"}), &report)
+	if !report.DryRun || report.Total != 3 || report.Accepted != 1 || report.Skipped != 1 || report.Failed != 1 {
+		t.Errorf("试运行报告 = %+v", report)
+	}
+	if n := countTrashCans(); n != 1 {
+		t.Fatalf("试运行后有 %d 个垃圾桶, want 1", n)
+	}
+
+	report = importReport{}
+	decodeData(t, upload("data.csv", csvData, map[string]string{"coord_sys": "gcj02"}), &report)
+	if report.DryRun || report.Accepted != 1 || report.Rows[0].DuplicateOf != 1 || report.Rows[1].ID == 0 {
+		t.Fatalf("导入报告 = %+v", report)
+	}
+	if n := countTrashCans(); n != 2 {
+		t.Errorf("导入后有 %d 个垃圾桶, want 2", n)
+	}
+	// 导入的垃圾桶直接生效，提交后即可在附近搜索中找到
+	var nearby struct {
+		List []trashCanItem `json:"list"`
+	}
+	decodeData(t, doRequest(t, r, "GET", "/trashcans/nearby?lat=31.25&lng=121.49&radius=0.1", nil, "", 0), &nearby)
+	if len(nearby.List) != 1 || nearby.List[0].ID != report.Rows[1].ID {
+		t.Errorf("附近的垃圾桶 = %+v, want 导入的 #%d", nearby.List, report.Rows[1].ID)
+	}
+}
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中使用参数名（form/json 标签）而不是结构体字段名
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(paramName)
+	}
+}
+
+// BindQuery 绑定并校验查询参数，失败时返回指明出错参数的 PARAM_ERROR
+func BindQuery(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		ParamErrorWithMessage(bindErrorMessage(c, obj, err), c)
+		return false
+	}
+	return true
+}
+
+// BindJSON 绑定并校验JSON请求体，失败时返回指明出错参数的 PARAM_ERROR
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		ParamErrorWithMessage(bindErrorMessage(c, obj, err), c)
+		return false
+	}
+	return true
+}
+
+// bindErrorMessage 将绑定错误转换为可读的错误信息
+func bindErrorMessage(c *gin.Context, obj interface{}, err error) string {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) && len(validationErrors) > 0 {
+		fe := validationErrors[0]
+		name := fe.Field()
+		isString := fe.Kind() == reflect.String
+		isList := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Array
+		switch fe.Tag() {
+		case "required":
+			return fmt.Sprintf("参数 %s 不能为空", name)
+		case "gte", "min":
+			if isString {
+				return fmt.Sprintf("参数 %s 长度不能小于 %s", name, fe.Param())
+			}
+			if isList {
+				return fmt.Sprintf("参数 %s 至少需要 %s 项", name, fe.Param())
+			}
+			return fmt.Sprintf("参数 %s 不能小于 %s", name, fe.Param())
+		case "lte", "max":
+			if isString {
+				return fmt.Sprintf("参数 %s 长度不能超过 %s", name, fe.Param())
+			}
+			if isList {
+				return fmt.Sprintf("参数 %s 最多 %s 项", name, fe.Param())
+			}
+			return fmt.Sprintf("参数 %s 不能大于 %s", name, fe.Param())
+		case "gt":
+			return fmt.Sprintf("参数 %s 必须大于 %s", name, fe.Param())
+		case "lt":
+			return fmt.Sprintf("参数 %s 必须小于 %s", name, fe.Param())
+		case "oneof":
+			return fmt.Sprintf("参数 %s 只能是 %s 之一", name, strings.ReplaceAll(fe.Param(), " ", "、"))
+		}
+		return fmt.Sprintf("参数 %s 无效", name)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return fmt.Sprintf("参数 %s 格式错误", typeError.Field)
+	}
+
+	// 类型转换错误不包含参数名，逐个参数重新绑定以找出出错的参数
+	if name := invalidQueryParam(c, obj); name != "" {
+		return fmt.Sprintf("参数 %s 格式错误", name)
+	}
+	return "参数错误"
+}
+
+// invalidQueryParam 找出无法转换为对应类型的查询参数
+func invalidQueryParam(c *gin.Context, obj interface{}) string {
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+	query := c.Request.URL.Query()
+	for _, name := range queryParamNames(t.Elem()) {
+		values, ok := query[name]
+		if !ok {
+			continue
+		}
+		probe := reflect.New(t.Elem()).Interface()
+		if err := binding.MapFormWithTag(probe, map[string][]string{name: values}, "form"); err != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// queryParamNames 返回结构体（含嵌入结构体）中全部 form 参数名
+func queryParamNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			names = append(names, queryParamNames(f.Type)...)
+			continue
+		}
+		if name := paramName(f); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// paramName 字段对应的参数名，优先使用 form 标签，其次使用 json 标签
+func paramName(f reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
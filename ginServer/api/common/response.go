@@ -13,12 +13,14 @@ type Response struct {
 }
 
 const (
-	SUCCESS        = 2000
-	SUCCESS_CANCEL = 2000
-	PARAM_ERROR    = 4000
-	PARAM_EMPTY    = 4001
-	ERROR          = 5000
-	ERROR_CANCEL   = 5001
+	SUCCESS              = 2000
+	SUCCESS_CANCEL       = 2000
+	PARAM_ERROR          = 4000
+	PARAM_EMPTY          = 4001
+	POSSIBLE_DUPLICATE   = 4002 // 附近已存在垃圾桶，可能是重复上传
+	OUTSIDE_SERVICE_AREA = 4003 // 坐标不在服务范围内
+	ERROR                = 5000
+	ERROR_CANCEL         = 5001
 )
 
 func Result(code int, data interface{}, msg string, c *gin.Context) {
@@ -50,10 +52,19 @@ func ParamError(c *gin.Context) {
 	Result(PARAM_ERROR, map[string]interface{}{}, "参数错误", c)
 }
 
+func ParamErrorWithMessage(message string, c *gin.Context) {
+	Result(PARAM_ERROR, map[string]interface{}{}, message, c)
+}
+
 func Fail(c *gin.Context) {
 	Result(ERROR, map[string]interface{}{}, "操作失败", c)
 }
 
+// OutsideServiceArea 坐标不在服务范围内
+func OutsideServiceArea(c *gin.Context) {
+	Result(OUTSIDE_SERVICE_AREA, map[string]interface{}{}, "该位置不在服务范围内", c)
+}
+
 func FailWithMessage(message string, c *gin.Context) {
 	Result(ERROR, map[string]interface{}{}, message, c)
 }
@@ -64,3 +75,7 @@ func FailWithDetailed(data interface{}, message string, c *gin.Context) {
 func FailWithAuthority(c *gin.Context) {
 	c.JSON(http.StatusUnauthorized, nil)
 }
+
+func FailWithForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, Response{ERROR, map[string]interface{}{}, "没有权限"})
+}
@@ -1,129 +1,496 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"template/ginServer/api/common"
+	"template/ginServer/middle"
 	"template/ginServer/model"
+	"template/ginServer/model/request"
 	"template/global"
+	"template/internal/modules/geofence"
+	"template/internal/modules/spatial"
 	"template/utils"
 )
 
-// GetNearbyTrashCans 获取附近的垃圾桶
-// GET /api/trashcans/nearby?lat=39.9&lng=116.4&radius=5&limit=10
+// GetNearbyTrashCans 获取附近的垃圾桶，按距离由近到远分页返回
+// GET /api/trashcans/nearby?lat=39.9&lng=116.4&radius=5&limit=10&coord_sys=gcj02&cursor=xxx
+// coord_sys 可选 wgs84、gcj02、bd09，默认gcj02，同时作用于请求参数和返回结果中的经纬度
+// cursor 为上一页返回的 next_cursor，查询下一页时其余参数需与上一页保持一致
+// sort 可选 distance（默认，按距离）或 score（按距离、评价和新鲜度的综合得分，权重见 config.yml 中的 ranking）
+// sort=score 时只对半径内最近的 scoreCandidateLimit 个垃圾桶排序，还有更远的垃圾桶时返回的 truncated 为 true
+// 筛选条件：has_image、min_like_ratio、created_from、created_to、uploader_id、q、category、status，见 request.TrashCanFilter
+// 未指定 status 时只返回公开的（active、reported_missing）垃圾桶；
+// 指定了 pending_review、removed 或 all 时，管理员可以看到全部，其他登录用户只能额外看到自己上传的
 func GetNearbyTrashCans(c *gin.Context) {
-	// 获取查询参数
-	latStr := c.Query("lat")
-	lngStr := c.Query("lng")
-	radiusStr := c.DefaultQuery("radius", "5") // 默认搜索半径5公里
-	limitStr := c.DefaultQuery("limit", "10")  // 默认返回10个
+	var query request.NearbyTrashCanQuery
+	if !common.BindQuery(c, &query) {
+		return
+	}
 
-	// 解析参数
-	lat, err := strconv.ParseFloat(latStr, 64)
-	if err != nil {
-		common.ParamError(c)
+	coordSys, ok := utils.ParseCoordSys(query.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("参数 coord_sys 只能是 wgs84、gcj02、bd09 之一", c)
+		return
+	}
+	filters, ok := trashCanFilterScopes(c, query.TrashCanFilter)
+	if !ok {
 		return
 	}
 
-	lng, err := strconv.ParseFloat(lngStr, 64)
+	lat, lng := utils.ToCanonicalCoord(*query.Lat, *query.Lng, coordSys)
+	if !geofence.Contains(lat, lng) {
+		common.OutsideServiceArea(c)
+		return
+	}
+	radius, limit := query.Radius, query.Limit
+
+	// 从上一页最后一个垃圾桶之后继续查询
+	var cursor *nearbyCursor
+	if query.Cursor != "" {
+		decoded, err := decodeNearbyCursor(query.Cursor)
+		if err != nil {
+			common.ParamErrorWithMessage("参数 cursor 无效", c)
+			return
+		}
+		if decoded.Lat != lat || decoded.Lng != lng || decoded.Radius != radius || decoded.Sort != query.Sort ||
+			decoded.Query != nearbyQueryHash(c) {
+			common.ParamErrorWithMessage("参数 cursor 与查询条件不一致", c)
+			return
+		}
+		cursor = &decoded
+	}
+
+	// 通过空间索引查询半径范围内的垃圾桶，只回表查询需要返回的记录
+	var matched []nearbyResult
+	var hasMore, truncated bool
+	var err error
+	var now time.Time
+	if query.Sort == sortByScore {
+		now = time.Now()
+		if cursor != nil {
+			now = time.Unix(0, cursor.Now)
+		}
+		matched, hasMore, truncated, err = nearbyByScore(lat, lng, radius, limit, filters, cursor, now)
+	} else {
+		matched, hasMore, err = nearbyByDistance(lat, lng, radius, limit, filters, cursor)
+	}
 	if err != nil {
-		common.ParamError(c)
+		global.SugarLogger.Errorf("查询垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
 		return
 	}
 
-	radius, _ := strconv.ParseFloat(radiusStr, 64)
-	limit, _ := strconv.Atoi(limitStr)
+	nextCursor := ""
+	if hasMore {
+		next := nearbyCursor{Lat: lat, Lng: lng, Radius: radius, Sort: query.Sort, Last: matched[len(matched)-1].Result, Query: nearbyQueryHash(c)}
+		if query.Sort == sortByScore {
+			next.Now = now.UnixNano()
+			next.Score = matched[len(matched)-1].Score.Total
+		}
+		nextCursor = encodeNearbyCursor(next)
+	}
 
-	// 查询所有垃圾桶
-	var trashCans []model.TrashCan
-	if err := global.DB.Find(&trashCans).Error; err != nil {
+	// 只查询需要返回的垃圾桶详情
+	trashCanIDs := make([]uint, 0, len(matched))
+	matchedMap := make(map[uint]nearbyResult, len(matched))
+	for _, p := range matched {
+		trashCanIDs = append(trashCanIDs, p.ID)
+		matchedMap[p.ID] = p
+	}
+	trashCans, err := loadTrashCans(trashCanIDs)
+	if err != nil {
 		global.SugarLogger.Errorf("查询垃圾桶失败: %v", err)
 		common.FailWithMessage("查询失败", c)
 		return
 	}
 
-	// 获取所有垃圾桶的ID列表
-	var trashCanIDs []uint
-	for _, tc := range trashCans {
-		trashCanIDs = append(trashCanIDs, tc.ID)
+	type TrashCanWithDistance struct {
+		trashCanItem
+		Distance float64          `json:"distance"`        // 距离（公里）
+		Score    *scoreComponents `json:"score,omitempty"` // 综合得分（仅 sort=score 时返回）
 	}
 
-	// 统计每个垃圾桶的点赞和点踩数量
-	likeCounts := make(map[uint]int64)
-	dislikeCounts := make(map[uint]int64)
-	if len(trashCanIDs) > 0 {
-		var likes []struct {
-			TrashCanID uint
-			Count      int64
-		}
-		global.DB.Model(&model.TrashCanLike{}).
-			Where("trash_can_id IN ? AND type = ?", trashCanIDs, 1).
-			Select("trash_can_id, COUNT(*) as count").
-			Group("trash_can_id").
-			Scan(&likes)
-		for _, l := range likes {
-			likeCounts[l.TrashCanID] = l.Count
+	results := make([]TrashCanWithDistance, 0, len(trashCans))
+	for _, item := range newTrashCanItems(trashCans, coordSys) {
+		m := matchedMap[item.ID]
+		results = append(results, TrashCanWithDistance{
+			trashCanItem: item,
+			Distance:     m.Distance,
+			Score:        m.Score,
+		})
+	}
+
+	result := map[string]interface{}{
+		"list":        results,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+		"truncated":   truncated,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// trashCanFilter 由筛选参数转换的查询条件
+// status 为nil时只查询公开的垃圾桶，与空间索引的范围一致，从索引中取候选时无需再按状态过滤
+type trashCanFilter struct {
+	scopes []func(*gorm.DB) *gorm.DB // 状态以外的条件，以及只包含公开状态时的状态条件
+	status func(*gorm.DB) *gorm.DB   // 包含非公开状态时的状态和可见范围条件
+}
+
+// all 直接在数据库中查询时使用的全部条件
+func (f trashCanFilter) all() []func(*gorm.DB) *gorm.DB {
+	scopes := append([]func(*gorm.DB) *gorm.DB{}, f.scopes...)
+	if f.status == nil {
+		return append(scopes, model.PublicStatus)
+	}
+	return append(scopes, f.status)
+}
+
+// trashCanFilterScopes 将筛选参数转换为查询条件，参数之间有冲突时返回参数错误
+func trashCanFilterScopes(c *gin.Context, filter request.TrashCanFilter) (trashCanFilter, bool) {
+	var scopes []func(*gorm.DB) *gorm.DB
+	if filter.HasImage != nil {
+		scopes = append(scopes, model.HasImage(*filter.HasImage))
+	}
+	if filter.MinLikeRatio != nil && *filter.MinLikeRatio > 0 {
+		scopes = append(scopes, model.MinLikeRatio(*filter.MinLikeRatio))
+	}
+
+	var from, to time.Time
+	if filter.CreatedFrom != nil {
+		from = *filter.CreatedFrom
+	}
+	if filter.CreatedTo != nil && !filter.CreatedTo.IsZero() {
+		to = filter.CreatedTo.AddDate(0, 0, 1) // 包含结束当天
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		common.ParamErrorWithMessage("参数 created_from 不能晚于 created_to", c)
+		return trashCanFilter{}, false
+	}
+	if !from.IsZero() || !to.IsZero() {
+		scopes = append(scopes, model.CreatedBetween(from, to))
+	}
+
+	if filter.UploaderID != nil {
+		scopes = append(scopes, model.UploadedBy(*filter.UploaderID))
+	}
+	if q := strings.TrimSpace(filter.Q); q != "" {
+		scopes = append(scopes, model.MatchText(q))
+	}
+	if strings.TrimSpace(filter.Category) != "" {
+		categories, err := model.ParseTrashCanCategories(filter.Category)
+		if err != nil {
+			common.ParamErrorWithMessage("参数 category 有误: "+err.Error(), c)
+			return trashCanFilter{}, false
 		}
+		scopes = append(scopes, model.HasCategories(categories))
+	}
 
-		var dislikes []struct {
-			TrashCanID uint
-			Count      int64
+	statuses := model.TrashCanStatuses
+	switch status := strings.TrimSpace(filter.Status); status {
+	case "":
+		return trashCanFilter{scopes: scopes}, true
+	case "all":
+	default:
+		var err error
+		statuses, err = model.ParseTrashCanStatuses(status)
+		if err == nil && len(statuses) == 0 {
+			err = fmt.Errorf("至少需要指定一个状态")
+		}
+		if err != nil {
+			common.ParamErrorWithMessage("参数 status 有误: "+err.Error(), c)
+			return trashCanFilter{}, false
 		}
-		global.DB.Model(&model.TrashCanLike{}).
-			Where("trash_can_id IN ? AND type = ?", trashCanIDs, -1).
-			Select("trash_can_id, COUNT(*) as count").
-			Group("trash_can_id").
-			Scan(&dislikes)
-		for _, d := range dislikes {
-			dislikeCounts[d.TrashCanID] = d.Count
+	}
+	scopes = append(scopes, model.HasStatus(statuses...))
+	for _, status := range statuses {
+		if !status.Public() {
+			return trashCanFilter{scopes: scopes, status: viewerScope(c)}, true
 		}
 	}
+	return trashCanFilter{scopes: scopes}, true
+}
 
-	// 计算距离并筛选
-	type TrashCanWithDistance struct {
-		model.TrashCan
-		Distance     float64 `json:"distance"`      // 距离（公里）
-		ImageURL     string  `json:"image_url"`     // 图片URL
-		LikeCount    int64   `json:"like_count"`    // 点赞数
-		DislikeCount int64   `json:"dislike_count"` // 点踩数
+// viewerScope 当前用户可以看到的垃圾桶：管理员可以看到全部，登录用户可以看到公开的及自己上传的，未登录用户只能看到公开的
+func viewerScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	userID, exists := c.Get("userID")
+	switch {
+	case !exists:
+		return model.PublicStatus
+	case middle.IsAdmin(userID.(uint)):
+		return func(db *gorm.DB) *gorm.DB { return db }
+	default:
+		return model.VisibleTo(userID.(uint))
 	}
+}
 
-	var results []TrashCanWithDistance
-	for _, tc := range trashCans {
-		distance := utils.CalculateDistance(lat, lng, tc.Latitude, tc.Longitude)
-		if distance <= radius {
-			results = append(results, TrashCanWithDistance{
-				TrashCan:     tc,
-				Distance:     distance,
-				ImageURL:     utils.GetImageURL(tc.ImagePath),
-				LikeCount:    likeCounts[tc.ID],
-				DislikeCount: dislikeCounts[tc.ID],
-			})
+// nearestMatching 查询按（距离, ID）排序位于 after 之后、满足筛选条件的最近 k 个垃圾桶
+// 空间索引中只有公开的垃圾桶，默认条件下直接查询索引；有其他筛选条件时按批次由近到远从索引取出候选，
+// 再到数据库中过滤，直到凑满 k 个或半径内已无更多垃圾桶；需要包含非公开的垃圾桶时在数据库中按范围查询
+func nearestMatching(lat, lng float64, k int, radius float64, after *spatial.Result, filter trashCanFilter) ([]spatial.Result, error) {
+	if filter.status != nil {
+		return queryNearestAfter(lat, lng, k, radius, after, filter.all())
+	}
+	filters := filter.scopes
+	if len(filters) == 0 {
+		return spatial.NearestAfter(lat, lng, k, radius, after)
+	}
+
+	batchSize := k * 4
+	if batchSize < 64 {
+		batchSize = 64
+	}
+	matched := make([]spatial.Result, 0, k)
+	for len(matched) < k {
+		batch, err := spatial.NearestAfter(lat, lng, batchSize, radius, after)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]uint, 0, len(batch))
+		for _, r := range batch {
+			ids = append(ids, r.ID)
+		}
+		var found []uint
+		if err := global.DB.Model(&model.TrashCan{}).
+			Scopes(filters...).
+			Where("id IN ?", ids).
+			Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+		foundSet := make(map[uint]struct{}, len(found))
+		for _, id := range found {
+			foundSet[id] = struct{}{}
+		}
+		for _, r := range batch {
+			if _, ok := foundSet[r.ID]; ok {
+				matched = append(matched, r)
+				if len(matched) == k {
+					break
+				}
+			}
+		}
+
+		if len(batch) < batchSize {
+			break
+		}
+		after = &batch[len(batch)-1]
+		if batchSize < 4096 {
+			batchSize *= 2
 		}
 	}
+	return matched, nil
+}
 
-	// 按距离排序（冒泡排序，简单实现）
-	for i := 0; i < len(results)-1; i++ {
-		for j := 0; j < len(results)-i-1; j++ {
-			if results[j].Distance > results[j+1].Distance {
-				results[j], results[j+1] = results[j+1], results[j]
+// queryNearestAfter 在数据库中查询半径范围内满足条件、按（距离, ID）排序位于 after 之后最近的 k 个垃圾桶
+func queryNearestAfter(lat, lng float64, k int, radius float64, after *spatial.Result, filters []func(*gorm.DB) *gorm.DB) ([]spatial.Result, error) {
+	results, err := spatial.QueryWithin(global.DB.Scopes(filters...), lat, lng, radius)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]spatial.Result, 0, k)
+	for _, r := range results {
+		if r.After(after) {
+			matched = append(matched, r)
+			if len(matched) == k {
+				break
 			}
 		}
 	}
+	return matched, nil
+}
+
+// nearbyCursorScope 附近查询游标的签名作用域
+const nearbyCursorScope = "trashcans/nearby:v2"
+
+// nearbyCursorSize 附近查询游标编码后的字节数
+const nearbyCursorSize = 8*8 + 1
+
+// nearbyCursor 附近查询的分页游标，记录查询条件和上一页最后一个垃圾桶的排序键与ID
+// 下一页只返回排在其后的垃圾桶，因此期间新增的垃圾桶不会导致重复
+type nearbyCursor struct {
+	Lat    float64
+	Lng    float64
+	Radius float64
+	Sort   string
+	Now    int64 // 按得分排序时第一页的查询时间（Unix纳秒），保证各页的新鲜度得分一致
+	Last   spatial.Result
+	Score  float64 // 按得分排序时上一页最后一个垃圾桶的得分
+	Query  uint64  // 其余查询参数（筛选条件、坐标系等）的摘要，见 nearbyQueryHash
+}
+
+// encodeNearbyCursor 将游标编码为带签名的字符串
+func encodeNearbyCursor(cursor nearbyCursor) string {
+	payload := make([]byte, 0, nearbyCursorSize)
+	for _, v := range []float64{cursor.Lat, cursor.Lng, cursor.Radius, cursor.Last.Distance, cursor.Score} {
+		payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(v))
+	}
+	payload = binary.BigEndian.AppendUint64(payload, uint64(cursor.Last.ID))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(cursor.Now))
+	payload = binary.BigEndian.AppendUint64(payload, cursor.Query)
+	sortFlag := byte(0)
+	if cursor.Sort == sortByScore {
+		sortFlag = 1
+	}
+	payload = append(payload, sortFlag)
+	return utils.SignCursor(cursorSecret(), nearbyCursorScope, payload)
+}
 
-	// 限制返回数量
-	if len(results) > limit {
-		results = results[:limit]
+// decodeNearbyCursor 校验签名并解析游标
+func decodeNearbyCursor(s string) (nearbyCursor, error) {
+	var cursor nearbyCursor
+	payload, err := utils.VerifyCursor(cursorSecret(), nearbyCursorScope, s)
+	if err != nil {
+		return cursor, err
+	}
+	if len(payload) != nearbyCursorSize {
+		return cursor, utils.ErrInvalidCursor
+	}
+	values := make([]float64, 5)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.BigEndian.Uint64(payload[i*8:]))
+	}
+	cursor.Lat, cursor.Lng, cursor.Radius, cursor.Last.Distance, cursor.Score = values[0], values[1], values[2], values[3], values[4]
+	cursor.Last.ID = uint(binary.BigEndian.Uint64(payload[40:]))
+	cursor.Now = int64(binary.BigEndian.Uint64(payload[48:]))
+	cursor.Query = binary.BigEndian.Uint64(payload[56:])
+	cursor.Sort = sortByDistance
+	if payload[64] == 1 {
+		cursor.Sort = sortByScore
 	}
+	return cursor, nil
+}
+
+// nearbyQueryHash 计算除 cursor 和 limit 以外全部查询参数的摘要，翻页时筛选条件等参数变化会导致游标失效
+// 参数按名称排序后编码，顺序不同但内容相同的查询得到相同的摘要
+func nearbyQueryHash(c *gin.Context) uint64 {
+	query := c.Request.URL.Query()
+	query.Del("cursor")
+	query.Del("limit")
+	sum := sha256.Sum256([]byte(query.Encode()))
+	return binary.BigEndian.Uint64(sum[:8])
+}
 
-	common.OkWithData(results, c)
+// cursorSecret 分页游标的签名密钥，复用JWT密钥
+func cursorSecret() []byte {
+	return []byte(global.CONFIG.JWTConfig.Secret)
+}
+
+// loadTrashCans 按ID批量查询垃圾桶，按传入ID的顺序返回，不存在的ID会被忽略
+func loadTrashCans(ids []uint) ([]model.TrashCan, error) {
+	if len(ids) == 0 {
+		return []model.TrashCan{}, nil
+	}
+
+	var rows []model.TrashCan
+	if err := global.DB.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	trashCanMap := make(map[uint]model.TrashCan, len(rows))
+	for _, tc := range rows {
+		trashCanMap[tc.ID] = tc
+	}
+
+	trashCans := make([]model.TrashCan, 0, len(rows))
+	for _, id := range ids {
+		if tc, ok := trashCanMap[id]; ok {
+			trashCans = append(trashCans, tc)
+		}
+	}
+	return trashCans, nil
+}
+
+// trashCanItem 列表类接口返回的垃圾桶信息
+type trashCanItem struct {
+	model.TrashCan
+	ImageURL     string `json:"image_url"`     // 封面图片URL
+	ImageCount   int64  `json:"image_count"`   // 图库中的图片数量
+	LikeCount    int64  `json:"like_count"`    // 点赞数
+	DislikeCount int64  `json:"dislike_count"` // 点踩数
+}
+
+// newTrashCanItems 为垃圾桶列表补充封面URL、图片数量和点赞点踩数量，并将经纬度转换为指定坐标系，保持原有顺序
+func newTrashCanItems(trashCans []model.TrashCan, coordSys utils.CoordSys) []trashCanItem {
+	ids := make([]uint, 0, len(trashCans))
+	for _, tc := range trashCans {
+		ids = append(ids, tc.ID)
+	}
+	likeCounts, dislikeCounts := countVotes(ids)
+	imageCounts := countImages(ids)
+
+	items := make([]trashCanItem, 0, len(trashCans))
+	for _, tc := range trashCans {
+		tc.Latitude, tc.Longitude = utils.FromCanonicalCoord(tc.Latitude, tc.Longitude, coordSys)
+		items = append(items, trashCanItem{
+			TrashCan:     tc,
+			ImageURL:     utils.GetImageURL(tc.ImagePath),
+			ImageCount:   imageCounts[tc.ID],
+			LikeCount:    likeCounts[tc.ID],
+			DislikeCount: dislikeCounts[tc.ID],
+		})
+	}
+	return items
+}
+
+// coordSysParam 读取 coord_sys 参数（表单或查询参数），未传时为统一存储的坐标系
+func coordSysParam(c *gin.Context) (utils.CoordSys, bool) {
+	value, ok := c.GetPostForm("coord_sys")
+	if !ok {
+		value = c.Query("coord_sys")
+	}
+	return utils.ParseCoordSys(value)
+}
+
+// countVotes 统计指定垃圾桶的点赞和点踩数量
+func countVotes(ids []uint) (map[uint]int64, map[uint]int64) {
+	likeCounts := make(map[uint]int64, len(ids))
+	dislikeCounts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return likeCounts, dislikeCounts
+	}
+
+	var votes []struct {
+		TrashCanID   uint
+		LikeCount    int64
+		DislikeCount int64
+	}
+	if err := global.DB.Model(&model.TrashCanLike{}).
+		Where("trash_can_id IN ?", ids).
+		Select("trash_can_id, " +
+			"SUM(CASE WHEN type = 1 THEN 1 ELSE 0 END) AS like_count, " +
+			"SUM(CASE WHEN type = -1 THEN 1 ELSE 0 END) AS dislike_count").
+		Group("trash_can_id").
+		Scan(&votes).Error; err != nil {
+		global.SugarLogger.Errorf("统计点赞数量失败: %v", err)
+		return likeCounts, dislikeCounts
+	}
+	for _, v := range votes {
+		likeCounts[v.TrashCanID] = v.LikeCount
+		dislikeCounts[v.TrashCanID] = v.DislikeCount
+	}
+	return likeCounts, dislikeCounts
 }
 
 // CreateTrashCan 创建新垃圾桶
 // POST /api/trashcans
+// 表单参数 coord_sys 指定上传经纬度的坐标系，默认gcj02
+// 附近已有垃圾桶时返回 POSSIBLE_DUPLICATE 及候选列表，表单参数 force=true 时仍然创建并记录为疑似重复
+// 表单参数 categories 为逗号分隔的垃圾分类（recyclable、hazardous、wet、residual），不传时为 unknown
 func CreateTrashCan(c *gin.Context) {
 	// 解析表单数据
 	latStr := c.PostForm("latitude")
@@ -149,7 +516,58 @@ func CreateTrashCan(c *gin.Context) {
 		return
 	}
 
-	// 处理图片上传
+	if err := utils.ValidateCoordinate(lat, lng); err != nil {
+		common.ParamErrorWithMessage(err.Error(), c)
+		return
+	}
+
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	lat, lng = utils.ToCanonicalCoord(lat, lng, coordSys)
+	if !geofence.Contains(lat, lng) {
+		common.OutsideServiceArea(c)
+		return
+	}
+
+	force := false
+	if forceStr := c.PostForm("force"); forceStr != "" {
+		if force, err = strconv.ParseBool(forceStr); err != nil {
+			common.ParamErrorWithMessage("参数 force 格式错误", c)
+			return
+		}
+	}
+
+	categories, err := model.ParseTrashCanCategories(c.PostForm("categories"))
+	if err != nil {
+		common.ParamErrorWithMessage("参数 categories 有误: "+err.Error(), c)
+		return
+	}
+
+	// 附近已有垃圾桶时提示可能重复，用户确认后带 force=true 重新提交才创建
+	duplicates, err := findPossibleDuplicates(lat, lng)
+	if err != nil {
+		global.SugarLogger.Errorf("检查重复垃圾桶失败: %v", err)
+		common.FailWithMessage("创建失败", c)
+		return
+	}
+	if len(duplicates) > 0 && !force {
+		respondPossibleDuplicates(c, duplicates, coordSys)
+		return
+	}
+
+	// 从中间件获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+
+	userIDUint := userID.(uint)
+
+	// 所有检查通过后才保存图片，之后失败时需要删除已保存的图片
 	var imagePath string
 	file, err := c.FormFile("image")
 	if err == nil {
@@ -175,45 +593,137 @@ func CreateTrashCan(c *gin.Context) {
 		}
 	}
 
-	// 从中间件获取用户ID
-	userID, exists := c.Get("userID")
-	if !exists {
-		common.FailWithAuthority(c)
-		return
+	// 用户上传的垃圾桶需要管理员审核，管理员上传的直接生效
+	status := model.StatusPendingReview
+	if middle.IsAdmin(userIDUint) {
+		status = model.StatusActive
 	}
 
-	userIDUint := userID.(uint)
-
 	// 创建垃圾桶记录
 	trashCan := model.TrashCan{
-		UserID:      &userIDUint,
-		Latitude:    lat,
-		Longitude:   lng,
-		Address:     address,
-		Description: description,
-		ImagePath:   imagePath,
+		UserID:         &userIDUint,
+		Latitude:       lat,
+		Longitude:      lng,
+		SourceCoordSys: string(coordSys),
+		Address:        address,
+		Description:    description,
+		ImagePath:      imagePath,
+		Categories:     categories,
+		Status:         status,
 	}
 
-	if err := global.DB.Create(&trashCan).Error; err != nil {
+	// 强制创建的疑似重复垃圾桶与创建操作在同一事务中记录，供管理员审核
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&trashCan).Error; err != nil {
+			return err
+		}
+		if imagePath != "" {
+			image := model.TrashCanImage{TrashCanID: trashCan.ID, UserID: &userIDUint, ImagePath: imagePath}
+			if err := addTrashCanImage(tx, &image, true); err != nil {
+				return err
+			}
+		}
+		if len(duplicates) == 0 {
+			return nil
+		}
+		records := make([]model.TrashCanDuplicate, 0, len(duplicates))
+		for _, d := range duplicates {
+			records = append(records, model.TrashCanDuplicate{
+				TrashCanID:    trashCan.ID,
+				DuplicateOfID: d.ID,
+				Distance:      d.Distance * 1000,
+				UserID:        userIDUint,
+			})
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
 		global.SugarLogger.Errorf("创建垃圾桶失败: %v", err)
+		if imagePath != "" {
+			if err := os.Remove(imagePath); err != nil {
+				global.SugarLogger.Warnf("删除图片文件失败: %v", err)
+			}
+		}
 		common.FailWithMessage("创建失败", c)
 		return
 	}
 
-	// 返回创建结果
+	// 返回创建结果，经纬度使用请求的坐标系
+	respLat, respLng := utils.FromCanonicalCoord(trashCan.Latitude, trashCan.Longitude, coordSys)
 	result := map[string]interface{}{
-		"id":        trashCan.ID,
-		"latitude":  trashCan.Latitude,
-		"longitude": trashCan.Longitude,
-		"address":   trashCan.Address,
-		"image_url": utils.GetImageURL(trashCan.ImagePath),
+		"id":         trashCan.ID,
+		"latitude":   respLat,
+		"longitude":  respLng,
+		"address":    trashCan.Address,
+		"image_url":  utils.GetImageURL(trashCan.ImagePath),
+		"categories": trashCan.Categories,
+		"status":     trashCan.Status,
+	}
+	if len(duplicates) > 0 {
+		duplicateOf := make([]uint, 0, len(duplicates))
+		for _, d := range duplicates {
+			duplicateOf = append(duplicateOf, d.ID)
+		}
+		result["duplicate_of"] = duplicateOf
 	}
 
 	common.OkWithDetailed(result, "创建成功", c)
 }
 
+// defaultDuplicateRadius 未配置时重复检测的默认半径（米）
+const defaultDuplicateRadius = 10
+
+// duplicateRadius 重复检测半径（米）
+func duplicateRadius() float64 {
+	if cfg := global.CONFIG.DuplicateConfig; cfg != nil && cfg.RadiusM > 0 {
+		return cfg.RadiusM
+	}
+	return defaultDuplicateRadius
+}
+
+// findPossibleDuplicates 查找重复检测半径内已有的垃圾桶（包括待审核的，不包括已撤除的），按距离由近到远排序
+// 空间索引中只有公开的垃圾桶，因此直接在数据库中按范围查询
+func findPossibleDuplicates(lat, lng float64) ([]spatial.Result, error) {
+	return spatial.QueryWithin(global.DB.Scopes(model.NotRemoved), lat, lng, duplicateRadius()/1000)
+}
+
+// respondPossibleDuplicates 返回可能重复的提示及附近已有的垃圾桶，客户端可以为已有垃圾桶投票，或带 force=true 重新提交
+func respondPossibleDuplicates(c *gin.Context, duplicates []spatial.Result, coordSys utils.CoordSys) {
+	ids := make([]uint, 0, len(duplicates))
+	distances := make(map[uint]float64, len(duplicates))
+	for _, d := range duplicates {
+		ids = append(ids, d.ID)
+		distances[d.ID] = d.Distance * 1000
+	}
+	trashCans, err := loadTrashCans(ids)
+	if err != nil {
+		global.SugarLogger.Errorf("查询垃圾桶失败: %v", err)
+		common.FailWithMessage("创建失败", c)
+		return
+	}
+
+	type DuplicateCandidate struct {
+		trashCanItem
+		Distance float64 `json:"distance"` // 与提交位置的距离（米）
+	}
+
+	candidates := make([]DuplicateCandidate, 0, len(trashCans))
+	for _, item := range newTrashCanItems(trashCans, coordSys) {
+		candidates = append(candidates, DuplicateCandidate{
+			trashCanItem: item,
+			Distance:     math.Round(distances[item.ID]*10) / 10,
+		})
+	}
+
+	data := map[string]interface{}{
+		"radius":     duplicateRadius(),
+		"candidates": candidates,
+	}
+	common.Result(common.POSSIBLE_DUPLICATE, data, "附近已存在垃圾桶，可能是重复上传，确认不是同一个后可强制创建", c)
+}
+
 // GetTrashCanDetail 获取垃圾桶详情
-// GET /api/trashcans/:id
+// GET /api/trashcans/:id?coord_sys=gcj02
 func GetTrashCanDetail(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -222,8 +732,23 @@ func GetTrashCanDetail(c *gin.Context) {
 		return
 	}
 
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+
 	var trashCan model.TrashCan
 	if err := global.DB.First(&trashCan, id).Error; err != nil {
+		// 已被合并的垃圾桶通过重定向记录找到合并后的垃圾桶
+		var redirect model.TrashCanRedirect
+		if global.DB.First(&redirect, "from_id = ?", id).Error != nil ||
+			global.DB.First(&trashCan, redirect.ToID).Error != nil {
+			common.FailWithMessage("垃圾桶不存在", c)
+			return
+		}
+	}
+	if !canViewTrashCan(c, trashCan) {
 		common.FailWithMessage("垃圾桶不存在", c)
 		return
 	}
@@ -232,36 +757,55 @@ func GetTrashCanDetail(c *gin.Context) {
 	var likeCount int64
 	var dislikeCount int64
 	global.DB.Model(&model.TrashCanLike{}).
-		Where("trash_can_id = ? AND type = ?", id, 1).
+		Where("trash_can_id = ? AND type = ?", trashCan.ID, 1).
 		Count(&likeCount)
 	global.DB.Model(&model.TrashCanLike{}).
-		Where("trash_can_id = ? AND type = ?", id, -1).
+		Where("trash_can_id = ? AND type = ?", trashCan.ID, -1).
 		Count(&dislikeCount)
 
+	// 图库中的图片数量和封面
+	var images []model.TrashCanImage
+	global.DB.Where("trash_can_id = ?", trashCan.ID).Order("sort_order, id").Find(&images)
+	var cover *trashCanImageItem
+	for _, item := range newTrashCanImageItems(images) {
+		if item.IsCover {
+			cover = &item
+			break
+		}
+	}
+
 	// 获取当前用户的操作状态（如果已登录）
 	var userAction int8 = 0 // 0=未操作, 1=点赞, -1=点踩
 	userID, exists := c.Get("userID")
 	if exists {
 		var like model.TrashCanLike
-		if err := global.DB.Where("user_id = ? AND trash_can_id = ?", userID, id).First(&like).Error; err == nil {
+		if err := global.DB.Where("user_id = ? AND trash_can_id = ?", userID, trashCan.ID).First(&like).Error; err == nil {
 			userAction = like.Type
 		}
 	}
 
 	// 构建返回数据
+	respLat, respLng := utils.FromCanonicalCoord(trashCan.Latitude, trashCan.Longitude, coordSys)
 	result := map[string]interface{}{
 		"id":            trashCan.ID,
-		"latitude":      trashCan.Latitude,
-		"longitude":     trashCan.Longitude,
+		"latitude":      respLat,
+		"longitude":     respLng,
 		"address":       trashCan.Address,
 		"description":   trashCan.Description,
 		"image_url":     utils.GetImageURL(trashCan.ImagePath),
+		"image_count":   len(images),
+		"cover":         cover, // 封面图片（含说明和上传者），没有图片时为null，全部图片见 /api/trashcans/:id/images
+		"categories":    trashCan.Categories,
+		"status":        trashCan.Status, // 生命周期状态，变更记录见 /api/trashcans/:id/status-logs
 		"like_count":    likeCount,
 		"dislike_count": dislikeCount,
 		"user_action":   userAction, // 当前用户的操作：0=未操作, 1=点赞, -1=点踩
 		"created_at":    trashCan.CreatedAt,
 		"updated_at":    trashCan.UpdatedAt,
 	}
+	if trashCan.ID != uint(id) {
+		result["redirected_from"] = id
+	}
 
 	common.OkWithData(result, c)
 }
@@ -296,9 +840,7 @@ func GetUserTrashCans(c *gin.Context) {
 
 	// 查询总数
 	var total int64
-	if err := global.DB.Model(&model.TrashCan{}).
-		Where("user_id = ?", userIDUint).
-		Count(&total).Error; err != nil {
+	if err := userTrashCansQuery(userIDUint).Count(&total).Error; err != nil {
 		global.SugarLogger.Errorf("查询垃圾桶总数失败: %v", err)
 		common.FailWithMessage("查询失败", c)
 		return
@@ -313,8 +855,8 @@ func GetUserTrashCans(c *gin.Context) {
 	// 查询列表数据
 	var trashCans []model.TrashCan
 	offset := (page - 1) * pageSize
-	if err := global.DB.Where("user_id = ?", userIDUint).
-		Order("created_at DESC").
+	if err := userTrashCansQuery(userIDUint).
+		Order(userTrashCansOrder).
 		Offset(offset).
 		Limit(pageSize).
 		Find(&trashCans).Error; err != nil {
@@ -360,8 +902,17 @@ func GetUserTrashCans(c *gin.Context) {
 	common.OkWithData(result, c)
 }
 
+// userTrashCansOrder 用户上传的垃圾桶按上传时间倒序排列
+const userTrashCansOrder = "created_at DESC"
+
+// userTrashCansQuery 查询指定用户上传的垃圾桶
+func userTrashCansQuery(userID uint) *gorm.DB {
+	return global.DB.Model(&model.TrashCan{}).Where("user_id = ?", userID)
+}
+
 // UpdateTrashCan 更新垃圾桶信息
 // PUT /api/trashcans/:id
+// 表单参数 latitude、longitude 可选，需同时提供，coord_sys 指定其坐标系；categories 可选，不传时保持不变
 func UpdateTrashCan(c *gin.Context) {
 	// 获取垃圾桶ID
 	idStr := c.Param("id")
@@ -390,49 +941,85 @@ func UpdateTrashCan(c *gin.Context) {
 	address := c.PostForm("address")
 	description := c.PostForm("description")
 
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+
 	// 更新地址和描述
 	updates := map[string]interface{}{
 		"address":     address,
 		"description": description,
 	}
 
-	// 处理图片上传（如果提供了新图片）
-	file, err := c.FormFile("image")
-	if err == nil {
-		// 有图片上传，先删除旧图片
-		if trashCan.ImagePath != "" {
-			if err := os.Remove(trashCan.ImagePath); err != nil {
-				global.SugarLogger.Warnf("删除旧图片失败: %v", err)
-				// 继续执行，不中断流程
-			}
+	// 更新位置（如果提供了经纬度）
+	latStr, hasLat := c.GetPostForm("latitude")
+	lngStr, hasLng := c.GetPostForm("longitude")
+	if hasLat || hasLng {
+		lat, latErr := strconv.ParseFloat(latStr, 64)
+		lng, lngErr := strconv.ParseFloat(lngStr, 64)
+		if latErr != nil || lngErr != nil {
+			common.ParamError(c)
+			return
 		}
-
-		// 保存新图片
-		uploadDir := global.CONFIG.UploadConfig.ImageDir
-		if uploadDir == "" {
-			uploadDir = "uploads/trashcans"
+		if err := utils.ValidateCoordinate(lat, lng); err != nil {
+			common.ParamErrorWithMessage(err.Error(), c)
+			return
 		}
+		lat, lng = utils.ToCanonicalCoord(lat, lng, coordSys)
+		if !geofence.Contains(lat, lng) {
+			common.OutsideServiceArea(c)
+			return
+		}
+		updates["latitude"] = lat
+		updates["longitude"] = lng
+		updates["source_coord_sys"] = string(coordSys)
+	}
 
-		// 确保上传目录存在
-		if err := utils.EnsureUploadDir(uploadDir); err != nil {
-			global.SugarLogger.Errorf("创建上传目录失败: %v", err)
-			common.FailWithMessage("创建上传目录失败", c)
+	// 更新垃圾分类（如果提供了 categories），传空值或 unknown 表示分类未知
+	if categoriesStr, ok := c.GetPostForm("categories"); ok {
+		categories, err := model.ParseTrashCanCategories(categoriesStr)
+		if err != nil {
+			common.ParamErrorWithMessage("参数 categories 有误: "+err.Error(), c)
 			return
 		}
+		updates["categories"] = categories
+	}
 
-		// 保存图片
-		imagePath, err := utils.SaveImage(file, uploadDir)
+	// 处理图片上传（如果提供了新图片），新图片添加到图库并设为封面，原有图片仍保留在图库中
+	var newImage *model.TrashCanImage
+	file, err := c.FormFile("image")
+	if err == nil {
+		// 确保上传目录存在并保存图片
+		imagePath, err := saveUploadedImage(file)
 		if err != nil {
 			global.SugarLogger.Errorf("保存图片失败: %v", err)
 			common.FailWithMessage("保存图片失败: "+err.Error(), c)
 			return
 		}
-
-		updates["image_path"] = imagePath
+		newImage = &model.TrashCanImage{TrashCanID: trashCan.ID, UserID: &userIDUint, ImagePath: imagePath}
 	}
 
 	// 更新数据库
-	if err := global.DB.Model(&trashCan).Updates(updates).Error; err != nil {
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&trashCan).Updates(updates).Error; err != nil {
+			return err
+		}
+		if newImage != nil {
+			return addTrashCanImage(tx, newImage, true)
+		}
+		return nil
+	}); err != nil {
+		if newImage != nil {
+			if err := os.Remove(newImage.ImagePath); err != nil {
+				global.SugarLogger.Warnf("删除图片文件失败: %v", err)
+			}
+		}
+		if errors.Is(err, errTooManyImages) {
+			common.ParamErrorWithMessage(err.Error()+"，请先删除不需要的图片", c)
+			return
+		}
 		global.SugarLogger.Errorf("更新垃圾桶失败: %v", err)
 		common.FailWithMessage("更新失败", c)
 		return
@@ -442,13 +1029,15 @@ func UpdateTrashCan(c *gin.Context) {
 	global.DB.First(&trashCan, id)
 
 	// 返回更新结果
+	respLat, respLng := utils.FromCanonicalCoord(trashCan.Latitude, trashCan.Longitude, coordSys)
 	result := map[string]interface{}{
 		"id":          trashCan.ID,
-		"latitude":    trashCan.Latitude,
-		"longitude":   trashCan.Longitude,
+		"latitude":    respLat,
+		"longitude":   respLng,
 		"address":     trashCan.Address,
 		"description": trashCan.Description,
 		"image_url":   utils.GetImageURL(trashCan.ImagePath),
+		"categories":  trashCan.Categories,
 		"updated_at":  trashCan.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 
@@ -481,20 +1070,42 @@ func DeleteTrashCan(c *gin.Context) {
 		return
 	}
 
-	// 删除关联的图片文件
-	if trashCan.ImagePath != "" {
-		if err := os.Remove(trashCan.ImagePath); err != nil {
-			global.SugarLogger.Warnf("删除图片文件失败: %v", err)
-			// 继续执行，不中断流程
-		}
+	images, err := loadTrashCanImages(global.DB, trashCan.ID)
+	if err != nil {
+		global.SugarLogger.Errorf("查询垃圾桶图片失败: %v", err)
+		common.FailWithMessage("删除失败", c)
+		return
 	}
 
-	// 删除数据库记录
-	if err := global.DB.Delete(&trashCan).Error; err != nil {
+	// 删除数据库记录及图库、不存在报告、疑似重复记录，以及指向该垃圾桶的合并重定向
+	// 状态变更记录与合并时一样保留备查
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&trashCan).Error; err != nil {
+			return err
+		}
+		for _, related := range []interface{}{&model.TrashCanImage{}, &model.TrashCanMissingReport{}} {
+			if err := tx.Where("trash_can_id = ?", trashCan.ID).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("trash_can_id = ? OR duplicate_of_id = ?", trashCan.ID, trashCan.ID).
+			Delete(&model.TrashCanDuplicate{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("to_id = ?", trashCan.ID).Delete(&model.TrashCanRedirect{}).Error
+	}); err != nil {
 		global.SugarLogger.Errorf("删除垃圾桶失败: %v", err)
 		common.FailWithMessage("删除失败", c)
 		return
 	}
 
+	// 删除关联的图片文件
+	for _, image := range images {
+		if err := os.Remove(image.ImagePath); err != nil {
+			global.SugarLogger.Warnf("删除图片文件失败: %v", err)
+			// 继续执行，不中断流程
+		}
+	}
+
 	common.OkWithMessage("删除成功", c)
 }
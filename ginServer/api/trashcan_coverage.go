@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/internal/modules/coverage"
+)
+
+// GetCoverageGaps 覆盖缺口分析：在范围内按网格采样，返回离最近的垃圾桶超过阈值距离的区域，按面积由大到小排序
+// GET /api/trashcans/coverage-gaps?sw_lat=31.1&sw_lng=121.3&ne_lat=31.3&ne_lng=121.6&threshold=300&coord_sys=gcj02
+// 每个区域返回轮廓（GeoJSON MultiPolygon 坐标）、面积和离最近垃圾桶最远的位置，供选址增设垃圾桶参考
+func GetCoverageGaps(c *gin.Context) {
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	box, ok := parseBoundingBox(c, coordSys)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	var query request.TrashCanCoverageQuery
+	if !common.BindQuery(c, &query) {
+		return
+	}
+
+	result, err := coverage.Analyze(coverage.Options{
+		Box:        box,
+		Threshold:  query.Threshold,
+		Step:       query.Step,
+		MaxRegions: query.Limit,
+		WithCells:  query.Cells,
+	})
+	if err != nil {
+		var inputErr *coverage.InputError
+		switch {
+		case errors.As(err, &inputErr):
+			common.ParamErrorWithMessage(inputErr.Error(), c)
+		case errors.Is(err, coverage.ErrNoTrashCans):
+			common.FailWithMessage(err.Error(), c)
+		default:
+			global.SugarLogger.Errorf("覆盖缺口分析失败: %v", err)
+			common.FailWithMessage("分析失败", c)
+		}
+		return
+	}
+
+	for i, region := range result.Regions {
+		result.Regions[i] = region.Convert(coordSys)
+	}
+	common.OkWithData(result, c)
+}
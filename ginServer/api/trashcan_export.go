@@ -0,0 +1,435 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/utils"
+)
+
+// exportFlushEvery 导出时每写出多少条记录刷新一次缓冲，让客户端尽早收到数据
+const exportFlushEvery = 500
+
+// exportRow 导出的一条垃圾桶记录，包含点赞点踩数量
+type exportRow struct {
+	model.TrashCan
+	LikeCount    int64
+	DislikeCount int64
+}
+
+// trashCanExport 导出的查询条件
+type trashCanExport struct {
+	db       *gorm.DB            // 已应用筛选条件的查询
+	order    string              // 排序方式
+	area     *utils.MultiPolygon // 多边形范围，数据库中只能按外接矩形粗筛，需要逐条判断
+	coordSys utils.CoordSys      // 导出使用的坐标系
+	format   exportFormat        // 导出文件格式
+	baseURL  string              // 服务的访问地址，用于生成图片和详情的完整链接
+}
+
+// newTrashCanExport 解析导出参数，在 base 查询的基础上应用筛选条件，参数错误时已返回错误响应
+func newTrashCanExport(c *gin.Context, base *gorm.DB, order string) (trashCanExport, bool) {
+	format, ok := exportFormatParam(c)
+	if !ok {
+		common.ParamErrorWithMessage("参数 format 只能是 geojson、kml、gpx 之一", c)
+		return trashCanExport{}, false
+	}
+
+	var query request.TrashCanExportQuery
+	if !common.BindQuery(c, &query) {
+		return trashCanExport{}, false
+	}
+	coordSys, ok := utils.ParseCoordSys(query.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+		return trashCanExport{}, false
+	}
+
+	filter, ok := trashCanFilterScopes(c, query.TrashCanFilter)
+	if !ok {
+		return trashCanExport{}, false
+	}
+	scopes := filter.all()
+	export := trashCanExport{
+		order:    order,
+		coordSys: coordSys,
+		format:   format,
+		baseURL:  requestBaseURL(c),
+	}
+
+	// 矩形范围，与视野查询的参数相同，四个参数需要同时提供
+	for _, key := range []string{"sw_lat", "sw_lng", "ne_lat", "ne_lng"} {
+		if _, ok := c.GetQuery(key); ok {
+			box, ok := parseBoundingBox(c, coordSys)
+			if !ok {
+				common.ParamErrorWithMessage("参数 sw_lat、sw_lng、ne_lat、ne_lng 需要同时提供且为有效的经纬度", c)
+				return trashCanExport{}, false
+			}
+			scopes = append(scopes, model.InBoundingBox(box))
+			break
+		}
+	}
+
+	if query.Polygon != "" {
+		area, err := utils.ParsePolygonGeoJSON([]byte(query.Polygon))
+		if err != nil {
+			common.ParamErrorWithMessage(err.Error(), c)
+			return trashCanExport{}, false
+		}
+		area = area.ToCanonicalCoord(coordSys)
+		scopes = append(scopes, model.InBoundingBox(area.BoundingBox()))
+		export.area = &area
+	}
+
+	export.db = base.Scopes(scopes...)
+	return export, true
+}
+
+// each 按排序逐条读取满足条件的垃圾桶，经纬度已转换为导出使用的坐标系
+// 使用数据库游标逐行读取，不会把全部数据加载到内存中
+func (e trashCanExport) each(fn func(row exportRow) error) error {
+	rows, err := e.db.
+		Select("trash_cans.*, " +
+			"(SELECT COUNT(*) FROM trash_can_likes WHERE trash_can_likes.trash_can_id = trash_cans.id AND trash_can_likes.type = 1) AS like_count, " +
+			"(SELECT COUNT(*) FROM trash_can_likes WHERE trash_can_likes.trash_can_id = trash_cans.id AND trash_can_likes.type = -1) AS dislike_count").
+		Order(e.order).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row exportRow
+		if err := global.DB.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if e.area != nil && !e.area.Contains(row.Latitude, row.Longitude) {
+			continue
+		}
+		row.Latitude, row.Longitude = utils.FromCanonicalCoord(row.Latitude, row.Longitude, e.coordSys)
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// imageURL 垃圾桶图片的完整URL，没有图片时为空
+func (e trashCanExport) imageURL(row exportRow) string {
+	if row.ImagePath == "" {
+		return ""
+	}
+	return e.baseURL + utils.GetImageURL(row.ImagePath)
+}
+
+// detailURL 垃圾桶详情的完整URL
+func (e trashCanExport) detailURL(row exportRow) string {
+	return fmt.Sprintf("%s/api/trashcans/%d", e.baseURL, row.ID)
+}
+
+// write 以导出格式流式写出全部数据，数据边查询边写出
+// 响应头发出后出错无法再返回错误信息，只能记录日志并截断响应，客户端得到的文件不完整
+func (e trashCanExport) write(c *gin.Context, filename string) {
+	c.Header("Content-Type", e.format.contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, e.format.ext))
+	c.Status(200)
+
+	w := bufio.NewWriter(c.Writer)
+	count := 0
+	err := e.format.begin(w, e)
+	if err == nil {
+		err = e.each(func(row exportRow) error {
+			if err := e.format.row(w, e, row, count); err != nil {
+				return err
+			}
+			count++
+			if count%exportFlushEvery == 0 {
+				if err := w.Flush(); err != nil {
+					return err
+				}
+				c.Writer.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = e.format.end(w, e)
+	}
+	if err != nil {
+		global.SugarLogger.Errorf("导出%s失败（已写出 %d 条）: %v", e.format.ext, count, err)
+	}
+	w.Flush()
+}
+
+// exportFormat 导出文件格式
+type exportFormat struct {
+	ext         string
+	contentType string
+	begin       func(w *bufio.Writer, e trashCanExport) error
+	row         func(w *bufio.Writer, e trashCanExport, row exportRow, index int) error
+	end         func(w *bufio.Writer, e trashCanExport) error
+}
+
+// exportFormats 支持的导出格式，按扩展名索引
+var exportFormats = map[string]exportFormat{
+	"geojson": {
+		ext:         "geojson",
+		contentType: "application/geo+json; charset=utf-8",
+		begin:       beginGeoJSON,
+		row:         writeGeoJSONFeature,
+		end:         endGeoJSON,
+	},
+	"kml": {
+		ext:         "kml",
+		contentType: "application/vnd.google-earth.kml+xml; charset=utf-8",
+		begin:       beginKML,
+		row:         writeKMLPlacemark,
+		end:         endKML,
+	},
+	"gpx": {
+		ext:         "gpx",
+		contentType: "application/gpx+xml; charset=utf-8",
+		begin:       beginGPX,
+		row:         writeGPXWaypoint,
+		end:         endGPX,
+	},
+}
+
+// exportFormatParam 导出格式，请求路径带扩展名（如 export.kml）时按扩展名，否则按 format 参数，默认 geojson
+func exportFormatParam(c *gin.Context) (exportFormat, bool) {
+	name := strings.TrimPrefix(path.Ext(c.Request.URL.Path), ".")
+	if name == "" {
+		name = c.DefaultQuery("format", "geojson")
+	}
+	format, ok := exportFormats[strings.ToLower(name)]
+	return format, ok
+}
+
+// geoJSONFeature 导出的 GeoJSON 要素
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         uint                   `json:"id"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // [经度, 纬度]
+}
+
+func beginGeoJSON(w *bufio.Writer, e trashCanExport) error {
+	_, err := w.WriteString(`{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func writeGeoJSONFeature(w *bufio.Writer, e trashCanExport, row exportRow, index int) error {
+	if index > 0 {
+		w.WriteString(",")
+	}
+	feature := geoJSONFeature{
+		Type: "Feature",
+		ID:   row.ID,
+		Geometry: geoJSONPoint{
+			Type:        "Point",
+			Coordinates: [2]float64{row.Longitude, row.Latitude},
+		},
+		Properties: map[string]interface{}{
+			"id":            row.ID,
+			"address":       row.Address,
+			"description":   row.Description,
+			"categories":    row.Categories.Names(),
+			"image_url":     e.imageURL(row),
+			"detail_url":    e.detailURL(row),
+			"like_count":    row.LikeCount,
+			"dislike_count": row.DislikeCount,
+			"created_at":    row.CreatedAt.Format(time.RFC3339),
+			"updated_at":    row.UpdatedAt.Format(time.RFC3339),
+		},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(feature)
+}
+
+func endGeoJSON(w *bufio.Writer, e trashCanExport) error {
+	_, err := w.WriteString("]}\n")
+	return err
+}
+
+// exportName 航点或地标的名称，优先使用地址
+func exportName(row exportRow) string {
+	if row.Address != "" {
+		return row.Address
+	}
+	return fmt.Sprintf("垃圾桶 #%d", row.ID)
+}
+
+// formatCoord 格式化经纬度，保留7位小数（约1厘米）
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', 7, 64)
+}
+
+// kmlPlacemark KML地标，description 中的HTML由编码器转义，Google Earth 会按HTML显示
+type kmlPlacemark struct {
+	XMLName      xml.Name  `xml:"Placemark"`
+	ID           string    `xml:"id,attr"`
+	Name         string    `xml:"name"`
+	Description  string    `xml:"description"`
+	ExtendedData []kmlData `xml:"ExtendedData>Data"`
+	Coordinates  string    `xml:"Point>coordinates"` // 经度,纬度
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+func beginKML(w *bufio.Writer, e trashCanExport) error {
+	_, err := w.WriteString(xml.Header +
+		`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>垃圾桶</name>` + "\n")
+	return err
+}
+
+func writeKMLPlacemark(w *bufio.Writer, e trashCanExport, row exportRow, index int) error {
+	var desc strings.Builder
+	if row.Description != "" {
+		desc.WriteString(xmlText(row.Description))
+		desc.WriteString("<br/>")
+	}
+	if imageURL := e.imageURL(row); imageURL != "" {
+		fmt.Fprintf(&desc, `<img src="%s" width="240"/><br/>`, xmlText(imageURL))
+	}
+	fmt.Fprintf(&desc, `<a href="%s">查看详情</a>`, xmlText(e.detailURL(row)))
+
+	placemark := kmlPlacemark{
+		ID:          fmt.Sprintf("trashcan-%d", row.ID),
+		Name:        exportName(row),
+		Description: desc.String(),
+		ExtendedData: []kmlData{
+			{Name: "id", Value: strconv.FormatUint(uint64(row.ID), 10)},
+			{Name: "categories", Value: row.Categories.String()},
+			{Name: "like_count", Value: strconv.FormatInt(row.LikeCount, 10)},
+			{Name: "dislike_count", Value: strconv.FormatInt(row.DislikeCount, 10)},
+			{Name: "created_at", Value: row.CreatedAt.Format(time.RFC3339)},
+		},
+		Coordinates: formatCoord(row.Longitude) + "," + formatCoord(row.Latitude),
+	}
+	if err := xml.NewEncoder(w).Encode(placemark); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+func endKML(w *bufio.Writer, e trashCanExport) error {
+	_, err := w.WriteString("</Document></kml>\n")
+	return err
+}
+
+// gpxWaypoint GPX航点，子元素顺序需符合 GPX 1.1 规范
+type gpxWaypoint struct {
+	XMLName xml.Name `xml:"wpt"`
+	Lat     string   `xml:"lat,attr"`
+	Lon     string   `xml:"lon,attr"`
+	Time    string   `xml:"time"`
+	Name    string   `xml:"name"`
+	Desc    string   `xml:"desc,omitempty"`
+	Link    gpxLink  `xml:"link"`
+	Type    string   `xml:"type"`
+}
+
+type gpxLink struct {
+	Href string `xml:"href,attr"`
+	Text string `xml:"text"`
+}
+
+func beginGPX(w *bufio.Writer, e trashCanExport) error {
+	_, err := fmt.Fprintf(w, "%s"+`<gpx version="1.1" creator="trashcan" xmlns="http://www.topografix.com/GPX/1/1">`+
+		"<metadata><name>垃圾桶</name><time>%s</time></metadata>\n", xml.Header, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func writeGPXWaypoint(w *bufio.Writer, e trashCanExport, row exportRow, index int) error {
+	waypoint := gpxWaypoint{
+		Lat:  formatCoord(row.Latitude),
+		Lon:  formatCoord(row.Longitude),
+		Time: row.CreatedAt.UTC().Format(time.RFC3339),
+		Name: exportName(row),
+		Desc: row.Description,
+		Link: gpxLink{Href: e.detailURL(row), Text: "查看详情"},
+		Type: "trashcan",
+	}
+	if err := xml.NewEncoder(w).Encode(waypoint); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+func endGPX(w *bufio.Writer, e trashCanExport) error {
+	_, err := w.WriteString("</gpx>\n")
+	return err
+}
+
+// xmlText 转义XML文本
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ExportTrashCans 导出垃圾桶数据，支持 GeoJSON、KML、GPX 格式
+// GET /api/trashcans/export?format=geojson&sw_lat=&sw_lng=&ne_lat=&ne_lng=&polygon=&coord_sys=wgs84
+// GET /api/trashcans/export.geojson、/api/trashcans/export.kml、/api/trashcans/export.gpx
+// 支持与视野查询相同的矩形范围、与区域查询相同的多边形范围（polygon 为 GeoJSON），以及附近搜索的筛选参数
+// GeoJSON 可直接在QGIS等GIS软件中打开，KML 用于 Google Earth，GPX 可导入手持GPS设备
+func ExportTrashCans(c *gin.Context) {
+	export, ok := newTrashCanExport(c, global.DB.Model(&model.TrashCan{}), "trash_cans.id")
+	if !ok {
+		return
+	}
+	export.write(c, "trashcans")
+}
+
+// ExportUserTrashCans 导出当前用户上传的垃圾桶，与 GetUserTrashCans 使用相同的查询和排序
+// GET /api/users/me/trashcans/export?format=gpx 或 /api/users/me/trashcans/export.gpx 等，参数同 ExportTrashCans
+// 与 ExportTrashCans 一样未指定 status 时只导出公开的垃圾桶，status=all 导出自己上传的全部垃圾桶
+func ExportUserTrashCans(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	export, ok := newTrashCanExport(c, userTrashCansQuery(userID.(uint)), userTrashCansOrder)
+	if !ok {
+		return
+	}
+	export.write(c, "my-trashcans")
+}
+
+// requestBaseURL 根据请求推断服务的访问地址，用于在导出文件中生成完整的链接
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+const (
+	heatmapLayoutGrid    = "grid"
+	heatmapLayoutHex     = "hex"
+	heatmapMaxResolution = 24
+	heatmapBlockCells    = 32    // 缓存块的边长（网格数），按块计算和缓存聚合结果
+	heatmapMaxCells      = 20000 // 单次请求范围内最多的网格数量
+)
+
+// heatmapRowSpacing 六边形网格的行距（网格边长为1），奇数行向右偏移半个网格
+var heatmapRowSpacing = math.Sqrt(3) / 2
+
+// heatmapKey 热力图缓存键，同一分辨率和网格形状下按块缓存
+type heatmapKey struct {
+	Layout     string
+	Resolution int
+	X          int
+	Y          int
+}
+
+// heatmapCell 一个网格的聚合结果，X、Y 为网格在该分辨率下的列号和行号
+type heatmapCell struct {
+	X            int
+	Y            int
+	Count        int64
+	LikeCount    int64
+	DislikeCount int64
+	model.TrashCanCategoryCounts
+}
+
+// heatmapCache 热力图缓存，垃圾桶位置、点赞数、分类等变化时清除受影响的块
+var heatmapCache = utils.NewTTLCache[heatmapKey, []heatmapCell](5*time.Minute, 20000)
+
+func init() {
+	spatial.TrashCanIndex.OnChange(invalidateHeatmapBlocks)
+}
+
+// heatmapBlockSize 返回指定分辨率下缓存块的边长（网格数）和每行的块数
+func heatmapBlockSize(resolution int) (int, int) {
+	n := 1 << resolution
+	size := min(n, heatmapBlockCells)
+	return size, n / size
+}
+
+// invalidateHeatmapBlocks 清除变更前后位置所在的缓存块；六边形可能跨越块的边界，因此相邻一个网格内的块也一并清除
+func invalidateHeatmapBlocks(old, new *spatial.Point) {
+	if old == nil && new == nil {
+		heatmapCache.Clear()
+		return
+	}
+	for _, p := range []*spatial.Point{old, new} {
+		if p == nil {
+			continue
+		}
+		for resolution := 1; resolution <= heatmapMaxResolution; resolution++ {
+			size, blocks := heatmapBlockSize(resolution)
+			px, py := utils.LngLatToPixel(p.Lat, p.Lng, resolution)
+			u, v := px/utils.TileSize, py/utils.TileSize
+			for _, du := range []float64{-1, 1} {
+				for _, dv := range []float64{-1, 1} {
+					x := (floorDiv(int(math.Floor(u+du)), size)%blocks + blocks) % blocks
+					y := max(0, min(blocks-1, floorDiv(int(math.Floor(v+dv)), size)))
+					for _, layout := range []string{heatmapLayoutGrid, heatmapLayoutHex} {
+						heatmapCache.Delete(heatmapKey{layout, resolution, x, y})
+					}
+				}
+			}
+		}
+	}
+}
+
+// heatmapCellCenter 返回网格中心在该分辨率下的坐标（以网格边长为单位）
+func heatmapCellCenter(layout string, x, y int) (float64, float64) {
+	if layout == heatmapLayoutHex {
+		u := float64(x)
+		if y%2 != 0 {
+			u += 0.5
+		}
+		return u, float64(y) * heatmapRowSpacing
+	}
+	return float64(x) + 0.5, float64(y) + 0.5
+}
+
+// GetTrashCanHeatmap 将范围内的垃圾桶数量（可选点赞点踩合计、各垃圾分类数量）聚合到规则网格上，用于绘制密度热力图
+// GET /api/trashcans/heatmap?sw_lat=31.1&sw_lng=121.3&ne_lat=31.3&ne_lng=121.6&resolution=15&layout=hex&votes=true&categories=true&coord_sys=gcj02
+// 网格划分在Web墨卡托平面上，resolution 级的网格与同级瓦片一样大，地图缩放级别为 z 时 resolution=z+3 约为32像素一格
+func GetTrashCanHeatmap(c *gin.Context) {
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	box, ok := parseBoundingBox(c, coordSys)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	var query request.TrashCanHeatmapQuery
+	if !common.BindQuery(c, &query) {
+		return
+	}
+
+	// 范围换算为网格坐标，按网格的半宽、半高向外扩展，使与范围相交但中心在范围外的网格也能返回
+	resolution := query.Resolution
+	n := 1 << resolution
+	halfWidth, halfHeight := 0.5, 0.5
+	if query.Layout == heatmapLayoutHex {
+		halfHeight = heatmapRowSpacing * 2 / 3
+	}
+	px0, py0 := utils.LngLatToPixel(box.MaxLat, box.MinLng, resolution)
+	px1, py1 := utils.LngLatToPixel(box.MinLat, box.MaxLng, resolution)
+	u0, v0 := px0/utils.TileSize-halfWidth, py0/utils.TileSize-halfHeight
+	u1, v1 := px1/utils.TileSize+halfWidth, py1/utils.TileSize+halfHeight
+	if box.CrossesAntimeridian() {
+		u1 += float64(n)
+	}
+	if (u1-u0)*(v1-v0) > heatmapMaxCells {
+		common.ParamErrorWithMessage("范围相对分辨率过大，请缩小范围或降低 resolution", c)
+		return
+	}
+
+	size, blocks := heatmapBlockSize(resolution)
+	var blockXs []int
+	seen := make(map[int]bool)
+	for bx := floorDiv(int(math.Floor(u0-1)), size); bx <= floorDiv(int(math.Floor(u1+1)), size); bx++ {
+		x := (bx%blocks + blocks) % blocks
+		if !seen[x] {
+			seen[x] = true
+			blockXs = append(blockXs, x)
+		}
+	}
+	by0 := max(0, floorDiv(int(math.Floor(v0-1)), size))
+	by1 := min(blocks-1, floorDiv(int(math.Floor(v1+1)), size))
+
+	fields := []string{"lat", "lng", "count"}
+	if query.Votes {
+		fields = append(fields, "like_count", "dislike_count")
+	}
+	if query.Categories {
+		fields = append(fields, "recyclable", "hazardous", "wet", "residual", "unknown")
+	}
+	cells := make([][]float64, 0)
+	var total, maxCount int64
+	for by := by0; by <= by1; by++ {
+		for _, bx := range blockXs {
+			key := heatmapKey{query.Layout, resolution, bx, by}
+			blockCells, ok := heatmapCache.Get(key)
+			if !ok {
+				var err error
+				if blockCells, err = heatmapBlock(key); err != nil {
+					global.SugarLogger.Errorf("聚合热力图失败: %v", err)
+					common.FailWithMessage("查询失败", c)
+					return
+				}
+				heatmapCache.Set(key, blockCells)
+			}
+
+			for _, cell := range blockCells {
+				u, v := heatmapCellCenter(query.Layout, cell.X, cell.Y)
+				if u < u0 {
+					u += float64(n)
+				}
+				if u < u0 || u > u1 || v < v0 || v > v1 {
+					continue
+				}
+				lat, lng := utils.PixelToLngLat(u*utils.TileSize, v*utils.TileSize, resolution)
+				lat, lng = utils.FromCanonicalCoord(lat, lng, coordSys)
+				row := []float64{roundCoord(lat), roundCoord(lng), float64(cell.Count)}
+				if query.Votes {
+					row = append(row, float64(cell.LikeCount), float64(cell.DislikeCount))
+				}
+				if query.Categories {
+					for _, count := range cell.TrashCanCategoryCounts.Values() {
+						row = append(row, float64(count))
+					}
+				}
+				cells = append(cells, row)
+				total += cell.Count
+				maxCount = max(maxCount, cell.Count)
+			}
+		}
+	}
+
+	// 网格边长（米），墨卡托网格的实际大小随纬度变化，取范围中心处的值
+	centerLat := (box.MinLat + box.MaxLat) / 2
+	cellSize := utils.CalculateDistance(centerLat, 0, centerLat, 360/float64(n)) * 1000
+
+	result := map[string]interface{}{
+		"layout":     query.Layout,
+		"resolution": resolution,
+		"cell_size":  math.Round(cellSize),
+		"fields":     fields, // cells 中每个数组依次对应的字段，lat、lng 为网格中心
+		"cells":      cells,
+		"total":      total,
+		"max_count":  maxCount,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// roundCoord 经纬度保留6位小数（约0.1米），减小响应体积
+func roundCoord(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// heatmapBlock 在数据库中按网格聚合一个缓存块内的垃圾桶
+// 先把经纬度投影为该分辨率下的网格坐标 u、v，正方形网格直接取整分组；
+// 六边形网格的中心构成两组错开的矩形点阵，分别取最近的中心后比较距离，得到所属的六边形
+func heatmapBlock(key heatmapKey) ([]heatmapCell, error) {
+	n := 1 << key.Resolution
+	size, blocks := heatmapBlockSize(key.Resolution)
+
+	// 查询范围比块大一个网格，覆盖中心在块内但跨越块边界的六边形
+	u0, u1 := float64(key.X*size-1), float64((key.X+1)*size+1)
+	v0, v1 := math.Max(0, float64(key.Y*size-1)), math.Min(float64(n), float64((key.Y+1)*size+1))
+	maxLat, minLng := utils.PixelToLngLat(u0*utils.TileSize, v0*utils.TileSize, key.Resolution)
+	minLat, maxLng := utils.PixelToLngLat(u1*utils.TileSize, v1*utils.TileSize, key.Resolution)
+	box := utils.BoundingBox{MinLat: minLat, MaxLat: maxLat, MinLng: minLng, MaxLng: maxLng}
+	if blocks == 1 {
+		box.MinLng, box.MaxLng = -180, 180
+	}
+
+	scale := strconv.Itoa(n)
+	u := "((longitude + 180.0) / 360.0 * " + scale + ")"
+	v := fmt.Sprintf("((1 - ln(tan(pi() / 4 + MAX(MIN(latitude, %[1]v), -%[1]v) * pi() / 360)) / pi()) / 2 * %[2]s)", utils.MaxMercatorLat, scale)
+	likeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", 1)
+	dislikeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", -1)
+	points := global.DB.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(box), model.PublicStatus).
+		Select(u+" AS u, "+v+" AS v, categories, (?) AS like_count, (?) AS dislike_count", likeQuery, dislikeQuery)
+
+	var rows []heatmapCell
+	totals := "COUNT(*) AS count, SUM(like_count) AS like_count, SUM(dislike_count) AS dislike_count, " +
+		model.CategoryCountsSelect("categories")
+	var err error
+	if key.Layout == heatmapLayoutHex {
+		// A 组中心 (i, k·√3) 对应偶数行，B 组中心 (i+0.5, k·√3+√3/2) 对应奇数行
+		spacing := strconv.FormatFloat(2*heatmapRowSpacing, 'g', -1, 64)
+		candidates := global.DB.Table("(?) AS p", points).
+			Select("u, v, categories, like_count, dislike_count, round(u) AS ax, round(v / " + spacing + ") AS ak, " +
+				"round(u - 0.5) AS bx, round(v / " + spacing + " - 0.5) AS bk")
+		nearerA := fmt.Sprintf("(u - ax) * (u - ax) + (v - ak * %[1]s) * (v - ak * %[1]s) <= "+
+			"(u - bx - 0.5) * (u - bx - 0.5) + (v - (bk + 0.5) * %[1]s) * (v - (bk + 0.5) * %[1]s)", spacing)
+		err = global.DB.Table("(?) AS c", candidates).
+			Select("CAST(CASE WHEN " + nearerA + " THEN ax ELSE bx END AS INTEGER) AS x, " +
+				"CAST(CASE WHEN " + nearerA + " THEN 2 * ak ELSE 2 * bk + 1 END AS INTEGER) AS y, " + totals).
+			Group("x, y").
+			Scan(&rows).Error
+	} else {
+		err = global.DB.Table("(?) AS p", points).
+			Select("CAST(floor(u) AS INTEGER) AS x, MIN(CAST(floor(v) AS INTEGER), " + strconv.Itoa(n-1) + ") AS y, " + totals).
+			Group("x, y").
+			Scan(&rows).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 列号按经度循环，合并180度经线两侧的同一网格，只保留中心在本块内的网格
+	merged := make(map[[2]int]int)
+	cells := make([]heatmapCell, 0)
+	for _, row := range rows {
+		row.X = (row.X%n + n) % n
+		cu, cv := heatmapCellCenter(key.Layout, row.X, row.Y)
+		bx := int(math.Floor(cu)) / size
+		by := max(0, min(blocks-1, floorDiv(int(math.Floor(cv)), size)))
+		if bx != key.X || by != key.Y {
+			continue
+		}
+		if i, ok := merged[[2]int{row.X, row.Y}]; ok {
+			cell := &cells[i]
+			cell.Count += row.Count
+			cell.LikeCount += row.LikeCount
+			cell.DislikeCount += row.DislikeCount
+			cell.Merge(row.TrashCanCategoryCounts)
+			continue
+		}
+		merged[[2]int{row.X, row.Y}] = len(cells)
+		cells = append(cells, row)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells, nil
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+func TestGetTrashCanHeatmapCache(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/heatmap", GetTrashCanHeatmap)
+
+	createTestTrashCans(t, []model.TrashCan{{ID: 1, Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive}})
+	// 每个网格依次为 count、like_count、dislike_count、recyclable、hazardous、wet、residual、unknown
+	heatmap := func() string {
+		t.Helper()
+		var result struct {
+			Cells [][]float64 `json:"cells"`
+		}
+		decodeData(t, doRequest(t, r, "GET", "/trashcans/heatmap?sw_lat=31.2&sw_lng=121.4&ne_lat=31.3&ne_lng=121.5&resolution=10&votes=true&categories=true", nil, "", 0), &result)
+		counts := make([][]float64, 0, len(result.Cells))
+		for _, cell := range result.Cells {
+			counts = append(counts, cell[2:])
+		}
+		return fmt.Sprint(counts)
+	}
+	if got := heatmap(); got != "[[1 0 0 0 0 0 0 1]]" {
+		t.Fatalf("热力图 = %s", got)
+	}
+
+	// 点赞点踩、分类和状态的变化不改变位置，同样需要清除缓存
+	if err := global.DB.Create(&model.TrashCanLike{UserID: 1, TrashCanID: 1, Type: -1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := heatmap(); got != "[[1 0 1 0 0 0 0 1]]" {
+		t.Errorf("点踩后的热力图 = %s", got)
+	}
+	if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("categories", model.CategoryWet|model.CategoryResidual).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := heatmap(); got != "[[1 0 1 0 0 1 1 0]]" {
+		t.Errorf("修改分类后的热力图 = %s", got)
+	}
+	if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("status", model.StatusRemoved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := heatmap(); got != "[]" {
+		t.Errorf("撤除后的热力图 = %s", got)
+	}
+}
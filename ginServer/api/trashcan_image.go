@@ -0,0 +1,398 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"template/ginServer/api/common"
+	"template/ginServer/middle"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/utils"
+)
+
+const (
+	maxTrashCanImages  = 20  // 每个垃圾桶最多的图片数量
+	maxImageCaptionLen = 200 // 图片说明的最大长度（字符）
+)
+
+// errTooManyImages 垃圾桶的图片数量已达到上限
+var errTooManyImages = fmt.Errorf("每个垃圾桶最多 %d 张图片", maxTrashCanImages)
+
+// trashCanImageItem 图库中的一张图片
+type trashCanImageItem struct {
+	model.TrashCanImage
+	ImageURL string `json:"image_url"`
+}
+
+// newTrashCanImageItems 为图片补充URL
+func newTrashCanImageItems(images []model.TrashCanImage) []trashCanImageItem {
+	items := make([]trashCanImageItem, 0, len(images))
+	for _, image := range images {
+		items = append(items, trashCanImageItem{TrashCanImage: image, ImageURL: utils.GetImageURL(image.ImagePath)})
+	}
+	return items
+}
+
+// loadTrashCanImages 按图库顺序查询垃圾桶的全部图片
+func loadTrashCanImages(db *gorm.DB, trashCanID uint) ([]model.TrashCanImage, error) {
+	var images []model.TrashCanImage
+	err := db.Where("trash_can_id = ?", trashCanID).Order("sort_order, id").Find(&images).Error
+	return images, err
+}
+
+// countImages 统计指定垃圾桶的图片数量
+func countImages(ids []uint) map[uint]int64 {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts
+	}
+	var rows []struct {
+		TrashCanID uint
+		Count      int64
+	}
+	if err := global.DB.Model(&model.TrashCanImage{}).
+		Select("trash_can_id, COUNT(*) AS count").
+		Where("trash_can_id IN ?", ids).
+		Group("trash_can_id").
+		Scan(&rows).Error; err != nil {
+		global.SugarLogger.Errorf("统计图片数量失败: %v", err)
+		return counts
+	}
+	for _, row := range rows {
+		counts[row.TrashCanID] = row.Count
+	}
+	return counts
+}
+
+// canManageTrashCan 垃圾桶的上传者和管理员可以管理垃圾桶的图库
+func canManageTrashCan(userID uint, trashCan model.TrashCan) bool {
+	return (trashCan.UserID != nil && *trashCan.UserID == userID) || middle.IsAdmin(userID)
+}
+
+// canViewTrashCan 按ID查询时，待审核的垃圾桶只有上传者和管理员可见，其他状态的所有人可见
+func canViewTrashCan(c *gin.Context, trashCan model.TrashCan) bool {
+	if trashCan.Status != model.StatusPendingReview {
+		return true
+	}
+	userID, exists := c.Get("userID")
+	return exists && canManageTrashCan(userID.(uint), trashCan)
+}
+
+// saveUploadedImage 将上传的图片保存到配置的上传目录，返回图片路径
+func saveUploadedImage(file *multipart.FileHeader) (string, error) {
+	uploadDir := global.CONFIG.UploadConfig.ImageDir
+	if uploadDir == "" {
+		uploadDir = "uploads/trashcans"
+	}
+	if err := utils.EnsureUploadDir(uploadDir); err != nil {
+		return "", fmt.Errorf("创建上传目录失败: %v", err)
+	}
+	return utils.SaveImage(file, uploadDir)
+}
+
+// addTrashCanImage 将图片添加到图库末尾，cover 为true或垃圾桶还没有封面时设为封面
+// 图片数量在同一事务中检查，已达到上限时返回 errTooManyImages，避免并发上传超出上限
+func addTrashCanImage(tx *gorm.DB, image *model.TrashCanImage, cover bool) error {
+	var last struct {
+		SortOrder int
+		Covers    int64
+		Count     int64
+	}
+	if err := tx.Model(&model.TrashCanImage{}).
+		Select("COALESCE(MAX(sort_order), -1) AS sort_order, COALESCE(SUM(is_cover), 0) AS covers, COUNT(*) AS count").
+		Where("trash_can_id = ?", image.TrashCanID).
+		Scan(&last).Error; err != nil {
+		return err
+	}
+	if last.Count >= maxTrashCanImages {
+		return errTooManyImages
+	}
+	image.SortOrder = last.SortOrder + 1
+	image.IsCover = false
+	if err := tx.Create(image).Error; err != nil {
+		return err
+	}
+	if cover || last.Covers == 0 {
+		return setTrashCanCover(tx, image.TrashCanID, image)
+	}
+	return nil
+}
+
+// setTrashCanCover 将图片设为垃圾桶的封面，并同步 TrashCan.ImagePath；cover 为nil表示垃圾桶已没有图片
+func setTrashCanCover(tx *gorm.DB, trashCanID uint, cover *model.TrashCanImage) error {
+	var coverID uint
+	imagePath := ""
+	if cover != nil {
+		coverID, imagePath = cover.ID, cover.ImagePath
+		cover.IsCover = true
+	}
+	if err := tx.Model(&model.TrashCanImage{}).
+		Where("trash_can_id = ? AND is_cover <> (id = ?)", trashCanID, coverID).
+		Update("is_cover", gorm.Expr("id = ?", coverID)).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.TrashCan{ID: trashCanID}).Update("image_path", imagePath).Error
+}
+
+// resetTrashCanCover 以图库中的第一张图片作为封面
+func resetTrashCanCover(tx *gorm.DB, trashCanID uint) error {
+	images, err := loadTrashCanImages(tx, trashCanID)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return setTrashCanCover(tx, trashCanID, nil)
+	}
+	return setTrashCanCover(tx, trashCanID, &images[0])
+}
+
+// trashCanParam 解析路径参数 id 并查询当前用户可见的垃圾桶，失败时已返回错误响应
+func trashCanParam(c *gin.Context) (model.TrashCan, bool) {
+	var trashCan model.TrashCan
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.ParamError(c)
+		return trashCan, false
+	}
+	if err := global.DB.First(&trashCan, id).Error; err != nil || !canViewTrashCan(c, trashCan) {
+		common.FailWithMessage("垃圾桶不存在", c)
+		return trashCan, false
+	}
+	return trashCan, true
+}
+
+// trashCanImageParam 解析路径参数 image_id 并查询属于该垃圾桶的图片，失败时已返回错误响应
+func trashCanImageParam(c *gin.Context, trashCan model.TrashCan) (model.TrashCanImage, bool) {
+	var image model.TrashCanImage
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 32)
+	if err != nil {
+		common.ParamError(c)
+		return image, false
+	}
+	if err := global.DB.Where("id = ? AND trash_can_id = ?", imageID, trashCan.ID).First(&image).Error; err != nil {
+		common.FailWithMessage("图片不存在", c)
+		return image, false
+	}
+	return image, true
+}
+
+// respondTrashCanImages 返回垃圾桶当前的图库
+func respondTrashCanImages(c *gin.Context, trashCanID uint, message string) {
+	images, err := loadTrashCanImages(global.DB, trashCanID)
+	if err != nil {
+		global.SugarLogger.Errorf("查询垃圾桶图片失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+	common.OkWithDetailed(newTrashCanImageItems(images), message, c)
+}
+
+// GetTrashCanImages 获取垃圾桶的图库，按顺序排列，is_cover 为true的是封面
+// GET /api/trashcans/:id/images
+func GetTrashCanImages(c *gin.Context) {
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	respondTrashCanImages(c, trashCan.ID, "查询成功")
+}
+
+// AddTrashCanImage 为垃圾桶添加图片，任何登录用户都可以为别人上传的垃圾桶添加图片
+// POST /api/trashcans/:id/images
+// 表单参数 image 为图片文件（必填），caption 为图片说明（可选）；垃圾桶还没有图片时新图片自动成为封面
+func AddTrashCanImage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	userIDUint := userID.(uint)
+
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	file, err := c.FormFile("image")
+	if err != nil {
+		common.ParamErrorWithMessage("参数 image 不能为空", c)
+		return
+	}
+	caption := c.PostForm("caption")
+	if utf8.RuneCountInString(caption) > maxImageCaptionLen {
+		common.ParamErrorWithMessage(fmt.Sprintf("参数 caption 不能超过 %d 个字符", maxImageCaptionLen), c)
+		return
+	}
+
+	imagePath, err := saveUploadedImage(file)
+	if err != nil {
+		global.SugarLogger.Errorf("保存图片失败: %v", err)
+		common.FailWithMessage("保存图片失败: "+err.Error(), c)
+		return
+	}
+	image := model.TrashCanImage{
+		TrashCanID: trashCan.ID,
+		UserID:     &userIDUint,
+		ImagePath:  imagePath,
+		Caption:    caption,
+	}
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		return addTrashCanImage(tx, &image, false)
+	}); err != nil {
+		if err := os.Remove(imagePath); err != nil {
+			global.SugarLogger.Warnf("删除图片文件失败: %v", err)
+		}
+		if errors.Is(err, errTooManyImages) {
+			common.ParamErrorWithMessage(err.Error(), c)
+			return
+		}
+		global.SugarLogger.Errorf("添加垃圾桶图片失败: %v", err)
+		common.FailWithMessage("上传失败", c)
+		return
+	}
+
+	common.OkWithDetailed(newTrashCanImageItems([]model.TrashCanImage{image})[0], "上传成功", c)
+}
+
+// ReorderTrashCanImages 调整图库中图片的顺序（垃圾桶的上传者或管理员）
+// PUT /api/trashcans/:id/images/order
+// 请求体 {"image_ids": [3, 1, 2]}，需要包含该垃圾桶的全部图片
+func ReorderTrashCanImages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	if !canManageTrashCan(userID.(uint), trashCan) {
+		common.FailWithForbidden(c)
+		return
+	}
+	var req request.ReorderTrashCanImagesRequest
+	if !common.BindJSON(c, &req) {
+		return
+	}
+
+	images, err := loadTrashCanImages(global.DB, trashCan.ID)
+	if err != nil {
+		global.SugarLogger.Errorf("查询垃圾桶图片失败: %v", err)
+		common.FailWithMessage("排序失败", c)
+		return
+	}
+	pending := make(map[uint]bool, len(images))
+	for _, image := range images {
+		pending[image.ID] = true
+	}
+	for _, id := range req.ImageIDs {
+		if !pending[id] {
+			common.ParamErrorWithMessage("参数 image_ids 需要包含该垃圾桶的全部图片且不能重复", c)
+			return
+		}
+		delete(pending, id)
+	}
+	if len(pending) > 0 {
+		common.ParamErrorWithMessage("参数 image_ids 需要包含该垃圾桶的全部图片且不能重复", c)
+		return
+	}
+
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.ImageIDs {
+			if err := tx.Model(&model.TrashCanImage{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		global.SugarLogger.Errorf("调整图片顺序失败: %v", err)
+		common.FailWithMessage("排序失败", c)
+		return
+	}
+
+	respondTrashCanImages(c, trashCan.ID, "排序成功")
+}
+
+// SetTrashCanImageCover 将图片设为垃圾桶的封面（垃圾桶的上传者或管理员）
+// PUT /api/trashcans/:id/images/:image_id/cover
+func SetTrashCanImageCover(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	if !canManageTrashCan(userID.(uint), trashCan) {
+		common.FailWithForbidden(c)
+		return
+	}
+	image, ok := trashCanImageParam(c, trashCan)
+	if !ok {
+		return
+	}
+
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		return setTrashCanCover(tx, trashCan.ID, &image)
+	}); err != nil {
+		global.SugarLogger.Errorf("设置封面失败: %v", err)
+		common.FailWithMessage("设置失败", c)
+		return
+	}
+
+	respondTrashCanImages(c, trashCan.ID, "设置成功")
+}
+
+// DeleteTrashCanImage 删除图库中的一张图片（垃圾桶的上传者、图片的上传者或管理员）
+// DELETE /api/trashcans/:id/images/:image_id
+// 删除的是封面时，图库中的第一张图片成为新的封面
+func DeleteTrashCanImage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	userIDUint := userID.(uint)
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	image, ok := trashCanImageParam(c, trashCan)
+	if !ok {
+		return
+	}
+	if !canManageTrashCan(userIDUint, trashCan) && (image.UserID == nil || *image.UserID != userIDUint) {
+		common.FailWithForbidden(c)
+		return
+	}
+
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		if image.IsCover {
+			return resetTrashCanCover(tx, trashCan.ID)
+		}
+		return nil
+	}); err != nil {
+		global.SugarLogger.Errorf("删除垃圾桶图片失败: %v", err)
+		common.FailWithMessage("删除失败", c)
+		return
+	}
+	if err := os.Remove(image.ImagePath); err != nil {
+		global.SugarLogger.Warnf("删除图片文件失败: %v", err)
+	}
+
+	respondTrashCanImages(c, trashCan.ID, "删除成功")
+}
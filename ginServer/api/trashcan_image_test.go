@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+func TestTrashCanImages(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/:id/images", GetTrashCanImages)
+	r.POST("/trashcans/:id/images", AddTrashCanImage)
+	r.PUT("/trashcans/:id/images/order", ReorderTrashCanImages)
+	r.PUT("/trashcans/:id/images/:image_id/cover", SetTrashCanImageCover)
+	r.DELETE("/trashcans/:id/images/:image_id", DeleteTrashCanImage)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, UserID: uintPtr(1), Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive},
+		{ID: 2, UserID: uintPtr(1), Latitude: 31.2404, Longitude: 121.4837, Status: model.StatusPendingReview},
+	})
+
+	upload := func(id, userID uint, contentType, caption string) testResponse {
+		t.Helper()
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="image"; filename="photo.jpg"`)
+		header.Set("Content-Type", contentType)
+		part, err := w.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte("jpeg"))
+		w.WriteField("caption", caption)
+		w.Close()
+		return doRequest(t, r, "POST", fmt.Sprintf("/trashcans/%d/images", id), &body, w.FormDataContentType(), userID)
+	}
+	gallery := func(resp testResponse) []trashCanImageItem {
+		t.Helper()
+		var images []trashCanImageItem
+		decodeData(t, resp, &images)
+		return images
+	}
+	coverPath := func() string {
+		var trashCan model.TrashCan
+		if err := global.DB.First(&trashCan, 1).Error; err != nil {
+			t.Fatal(err)
+		}
+		return trashCan.ImagePath
+	}
+
+	// 任何登录用户都可以添加图片，第一张图片自动成为封面
+	var first, second trashCanImageItem
+	decodeData(t, upload(1, 2, "image/jpeg", "正面"), &first)
+	decodeData(t, upload(1, 1, "image/jpeg", "侧面"), &second)
+	if !first.IsCover || second.IsCover || first.Caption != "正面" {
+		t.Errorf("上传的图片 = %+v, %+v", first, second)
+	}
+	var images []model.TrashCanImage
+	global.DB.Where("trash_can_id = ?", 1).Order("id").Find(&images)
+	if len(images) != 2 || coverPath() != images[0].ImagePath {
+		t.Fatalf("图库 = %+v, 封面 = %s", images, coverPath())
+	}
+	for _, tt := range []struct {
+		name        string
+		id          uint
+		contentType string
+	}{
+		{"不是图片", 1, "text/plain"},
+		{"待审核的垃圾桶", 2, "image/jpeg"},
+	} {
+		if resp := upload(tt.id, 3, tt.contentType, ""); resp.Code == 2000 {
+			t.Errorf("%s: 上传成功, want 失败", tt.name)
+		}
+	}
+
+	// 只有垃圾桶的上传者和管理员可以调整顺序和封面
+	order := map[string][]uint{"image_ids": {second.ID, first.ID}}
+	if resp := doJSON(t, r, "PUT", "/trashcans/1/images/order", order, 2); resp.Code != 5000 {
+		t.Errorf("其他用户调整顺序: %d %s, want 5000", resp.Code, resp.Msg)
+	}
+	if resp := doJSON(t, r, "PUT", "/trashcans/1/images/order", map[string][]uint{"image_ids": {second.ID}}, 1); resp.Code != 4000 {
+		t.Errorf("缺少图片: %d %s, want 4000", resp.Code, resp.Msg)
+	}
+	if got := gallery(doJSON(t, r, "PUT", "/trashcans/1/images/order", order, 1)); len(got) != 2 || got[0].ID != second.ID {
+		t.Errorf("调整顺序后 = %+v", got)
+	}
+	if resp := doRequest(t, r, "PUT", fmt.Sprintf("/trashcans/1/images/%d/cover", second.ID), nil, "", 2); resp.Code != 5000 {
+		t.Errorf("其他用户设置封面: %d %s, want 5000", resp.Code, resp.Msg)
+	}
+	if got := gallery(doRequest(t, r, "PUT", fmt.Sprintf("/trashcans/1/images/%d/cover", second.ID), nil, "", testAdminID)); !got[0].IsCover || got[1].IsCover {
+		t.Errorf("设置封面后 = %+v", got)
+	}
+	if coverPath() != images[1].ImagePath {
+		t.Errorf("垃圾桶的封面 = %s, want %s", coverPath(), images[1].ImagePath)
+	}
+
+	// 图片的上传者可以删除自己的图片；删除封面后第一张图片成为封面，最后一张删除后没有封面
+	if resp := doRequest(t, r, "DELETE", fmt.Sprintf("/trashcans/1/images/%d", second.ID), nil, "", 2); resp.Code != 5000 {
+		t.Errorf("删除别人的图片: %d %s, want 5000", resp.Code, resp.Msg)
+	}
+	if got := gallery(doRequest(t, r, "DELETE", fmt.Sprintf("/trashcans/1/images/%d", second.ID), nil, "", 1)); len(got) != 1 || !got[0].IsCover {
+		t.Errorf("删除封面后 = %+v", got)
+	}
+	if _, err := os.Stat(images[1].ImagePath); !os.IsNotExist(err) {
+		t.Errorf("删除后图片文件仍然存在: %v", err)
+	}
+	if got := gallery(doRequest(t, r, "DELETE", fmt.Sprintf("/trashcans/1/images/%d", first.ID), nil, "", 2)); len(got) != 0 {
+		t.Errorf("全部删除后 = %+v", got)
+	}
+	if coverPath() != "" {
+		t.Errorf("全部删除后封面 = %s", coverPath())
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// maxInBoundsResults 视野范围查询最多返回的垃圾桶数量
+const maxInBoundsResults = 500
+
+// GetTrashCansInBounds 获取地图视野范围内的垃圾桶
+// GET /api/trashcans/in-bounds?sw_lat=31.1&sw_lng=121.3&ne_lat=31.3&ne_lng=121.6&coord_sys=gcj02
+// sw_lng 大于 ne_lng 时表示视野跨越了180度经线
+// 支持与附近搜索相同的筛选条件，见 request.TrashCanFilter
+func GetTrashCansInBounds(c *gin.Context) {
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	box, ok := parseBoundingBox(c, coordSys)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	var filter request.TrashCanFilter
+	if !common.BindQuery(c, &filter) {
+		return
+	}
+	conditions, ok := trashCanFilterScopes(c, filter)
+	if !ok {
+		return
+	}
+
+	// 多查一条用于判断是否被截断
+	var trashCans []model.TrashCan
+	if err := global.DB.Scopes(model.InBoundingBox(box)).
+		Scopes(conditions.all()...).
+		Order("id").
+		Limit(maxInBoundsResults + 1).
+		Find(&trashCans).Error; err != nil {
+		global.SugarLogger.Errorf("查询视野范围内垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	truncated := len(trashCans) > maxInBoundsResults
+	if truncated {
+		trashCans = trashCans[:maxInBoundsResults]
+	}
+
+	result := map[string]interface{}{
+		"list":      newTrashCanItems(trashCans, coordSys),
+		"truncated": truncated, // 为true时说明视野内垃圾桶过多，客户端应放大地图后再查询
+		"limit":     maxInBoundsResults,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// parseBoundingBox 解析 sw_lat、sw_lng、ne_lat、ne_lng 查询参数，并转换为统一存储的坐标系
+func parseBoundingBox(c *gin.Context, coordSys utils.CoordSys) (utils.BoundingBox, bool) {
+	var values [4]float64
+	for i, key := range []string{"sw_lat", "sw_lng", "ne_lat", "ne_lng"} {
+		v, err := strconv.ParseFloat(c.Query(key), 64)
+		if err != nil {
+			return utils.BoundingBox{}, false
+		}
+		values[i] = v
+	}
+
+	box := utils.BoundingBox{
+		MinLat: values[0],
+		MinLng: values[1],
+		MaxLat: values[2],
+		MaxLng: values[3],
+	}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat > box.MaxLat ||
+		box.MinLng < -180 || box.MinLng > 180 || box.MaxLng < -180 || box.MaxLng > 180 {
+		return utils.BoundingBox{}, false
+	}
+	box.MinLat, box.MinLng = utils.ToCanonicalCoord(box.MinLat, box.MinLng, coordSys)
+	box.MaxLat, box.MaxLng = utils.ToCanonicalCoord(box.MaxLat, box.MaxLng, coordSys)
+	return box, true
+}
+
+const (
+	clusterCellPixels = 64 // 聚合网格大小（像素）
+	clusterMaxZoom    = 18 // 达到该缩放级别后不再聚合，直接返回单个垃圾桶
+	maxClusterZoom    = 22
+	maxClusterTiles   = 64 // 单次请求最多计算的瓦片数量
+)
+
+// tileKey 瓦片坐标
+type tileKey struct {
+	Z int
+	X int
+	Y int
+}
+
+// clusterItem 聚合结果，单个垃圾桶时 Type 为 point
+type clusterItem struct {
+	Type         string                       `json:"type"`         // cluster=聚合点, point=单个垃圾桶
+	ID           uint                         `json:"id,omitempty"` // 单个垃圾桶的ID
+	Latitude     float64                      `json:"latitude"`     // 聚合点为所含垃圾桶坐标的平均值
+	Longitude    float64                      `json:"longitude"`
+	Count        int                          `json:"count"`
+	Bounds       *clusterBounds               `json:"bounds,omitempty"` // 聚合点所含垃圾桶的范围
+	LikeCount    int64                        `json:"like_count"`
+	DislikeCount int64                        `json:"dislike_count"`
+	Categories   model.TrashCanCategoryCounts `json:"categories"` // 各垃圾分类的垃圾桶数量
+}
+
+type clusterBounds struct {
+	SwLat float64 `json:"sw_lat"`
+	SwLng float64 `json:"sw_lng"`
+	NeLat float64 `json:"ne_lat"`
+	NeLng float64 `json:"ne_lng"`
+}
+
+// convert 返回转换为指定坐标系后的副本
+func (item clusterItem) convert(coordSys utils.CoordSys) clusterItem {
+	item.Latitude, item.Longitude = utils.FromCanonicalCoord(item.Latitude, item.Longitude, coordSys)
+	if item.Bounds != nil {
+		b := *item.Bounds
+		b.SwLat, b.SwLng = utils.FromCanonicalCoord(b.SwLat, b.SwLng, coordSys)
+		b.NeLat, b.NeLng = utils.FromCanonicalCoord(b.NeLat, b.NeLng, coordSys)
+		item.Bounds = &b
+	}
+	return item
+}
+
+// clusterCache 按瓦片缓存聚合结果，垃圾桶位置、点赞数、分类等变化时清除对应瓦片
+var clusterCache = utils.NewTTLCache[tileKey, []clusterItem](time.Minute, 20000)
+
+func init() {
+	spatial.TrashCanIndex.OnChange(invalidateClusterTiles)
+}
+
+// invalidateClusterTiles 清除变更前后位置所在的全部瓦片缓存
+func invalidateClusterTiles(old, new *spatial.Point) {
+	if old == nil && new == nil {
+		clusterCache.Clear()
+		return
+	}
+	for _, p := range []*spatial.Point{old, new} {
+		if p == nil {
+			continue
+		}
+		for z := 0; z <= maxClusterZoom; z++ {
+			x, y := utils.LngLatToTile(p.Lat, p.Lng, z)
+			clusterCache.Delete(tileKey{Z: z, X: x, Y: y})
+		}
+	}
+}
+
+// GetTrashCanClusters 按缩放级别聚合视野范围内的垃圾桶
+// GET /api/trashcans/clusters?sw_lat=31.1&sw_lng=121.3&ne_lat=31.3&ne_lng=121.6&zoom=12&coord_sys=gcj02
+func GetTrashCanClusters(c *gin.Context) {
+	coordSys, ok := coordSysParam(c)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+	box, ok := parseBoundingBox(c, coordSys)
+	if !ok {
+		common.ParamError(c)
+		return
+	}
+
+	zoom, err := strconv.Atoi(c.Query("zoom"))
+	if err != nil || zoom < 0 || zoom > maxClusterZoom {
+		common.ParamErrorWithMessage("zoom 必须是 0 到 22 之间的整数", c)
+		return
+	}
+
+	tiles, ok := utils.TilesInBoundingBox(box, zoom, maxClusterTiles)
+	if !ok {
+		common.ParamErrorWithMessage("视野范围相对缩放级别过大", c)
+		return
+	}
+
+	items := make([]clusterItem, 0)
+	for _, t := range tiles {
+		key := tileKey{Z: zoom, X: t[0], Y: t[1]}
+		tileItems, ok := clusterCache.Get(key)
+		if !ok {
+			tileItems, err = clusterTile(key)
+			if err != nil {
+				global.SugarLogger.Errorf("聚合垃圾桶失败: %v", err)
+				common.FailWithMessage("查询失败", c)
+				return
+			}
+			clusterCache.Set(key, tileItems)
+		}
+		// 缓存中为统一坐标系，转换时复制一份避免修改缓存
+		for _, item := range tileItems {
+			items = append(items, item.convert(coordSys))
+		}
+	}
+
+	result := map[string]interface{}{
+		"zoom":      zoom,
+		"clustered": zoom < clusterMaxZoom,
+		"items":     items,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// clusterTile 将瓦片内的垃圾桶按像素网格聚合
+func clusterTile(key tileKey) ([]clusterItem, error) {
+	var points []struct {
+		ID           uint
+		Latitude     float64
+		Longitude    float64
+		LikeCount    int64
+		DislikeCount int64
+		Categories   model.TrashCanCategories
+	}
+	likeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", 1)
+	dislikeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", -1)
+	if err := global.DB.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(utils.TileBoundingBox(key.Z, key.X, key.Y)), model.PublicStatus).
+		Select("id, latitude, longitude, categories, (?) AS like_count, (?) AS dislike_count", likeQuery, dislikeQuery).
+		Order("id").
+		Find(&points).Error; err != nil {
+		return nil, err
+	}
+
+	type cell struct {
+		item   clusterItem
+		latSum float64
+		lngSum float64
+	}
+	cells := make(map[[2]int]*cell)
+	var order [][2]int
+	items := make([]clusterItem, 0)
+
+	for _, p := range points {
+		// 瓦片边界上的点只归属于其中一个瓦片，避免重复
+		if x, y := utils.LngLatToTile(p.Latitude, p.Longitude, key.Z); x != key.X || y != key.Y {
+			continue
+		}
+
+		point := clusterItem{
+			Type:         "point",
+			ID:           p.ID,
+			Latitude:     p.Latitude,
+			Longitude:    p.Longitude,
+			Count:        1,
+			LikeCount:    p.LikeCount,
+			DislikeCount: p.DislikeCount,
+		}
+		point.Categories.Add(p.Categories)
+		if key.Z >= clusterMaxZoom {
+			items = append(items, point)
+			continue
+		}
+
+		px, py := utils.LngLatToPixel(p.Latitude, p.Longitude, key.Z)
+		cellKey := [2]int{int(px) / clusterCellPixels, int(py) / clusterCellPixels}
+		current, ok := cells[cellKey]
+		if !ok {
+			current = &cell{item: point}
+			current.item.Bounds = &clusterBounds{SwLat: p.Latitude, SwLng: p.Longitude, NeLat: p.Latitude, NeLng: p.Longitude}
+			cells[cellKey] = current
+			order = append(order, cellKey)
+		} else {
+			current.item.Count++
+			current.item.LikeCount += p.LikeCount
+			current.item.DislikeCount += p.DislikeCount
+			current.item.Categories.Add(p.Categories)
+			b := current.item.Bounds
+			b.SwLat = math.Min(b.SwLat, p.Latitude)
+			b.SwLng = math.Min(b.SwLng, p.Longitude)
+			b.NeLat = math.Max(b.NeLat, p.Latitude)
+			b.NeLng = math.Max(b.NeLng, p.Longitude)
+		}
+		current.latSum += p.Latitude
+		current.lngSum += p.Longitude
+	}
+
+	for _, cellKey := range order {
+		current := cells[cellKey]
+		if current.item.Count == 1 {
+			current.item.Bounds = nil
+			items = append(items, current.item)
+			continue
+		}
+		current.item.Type = "cluster"
+		current.item.ID = 0
+		current.item.Latitude = current.latSum / float64(current.item.Count)
+		current.item.Longitude = current.lngSum / float64(current.item.Count)
+		items = append(items, current.item)
+	}
+	return items, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+func TestGetTrashCanClustersCache(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/clusters", GetTrashCanClusters)
+
+	createTestTrashCans(t, []model.TrashCan{{ID: 1, Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive}})
+	clusters := func() []clusterItem {
+		t.Helper()
+		var result struct {
+			Items []clusterItem `json:"items"`
+		}
+		decodeData(t, doRequest(t, r, "GET", "/trashcans/clusters?sw_lat=31.2&sw_lng=121.4&ne_lat=31.3&ne_lng=121.5&zoom=14", nil, "", 0), &result)
+		return result.Items
+	}
+	if got := clusters(); len(got) != 1 || got[0].LikeCount != 0 || got[0].Categories.Unknown != 1 {
+		t.Fatalf("聚合结果 = %+v", got)
+	}
+
+	// 点赞点踩、分类和状态的变化不改变位置，同样需要清除缓存
+	if err := global.DB.Create(&model.TrashCanLike{UserID: 1, TrashCanID: 1, Type: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := clusters(); len(got) != 1 || got[0].LikeCount != 1 {
+		t.Errorf("点赞后的聚合结果 = %+v", got)
+	}
+	if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("categories", model.CategoryRecyclable).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := clusters(); len(got) != 1 || got[0].Categories.Recyclable != 1 || got[0].Categories.Unknown != 0 {
+		t.Errorf("修改分类后的聚合结果 = %+v", got)
+	}
+	if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("status", model.StatusPendingReview).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := clusters(); len(got) != 0 {
+		t.Errorf("变为待审核后的聚合结果 = %+v", got)
+	}
+}
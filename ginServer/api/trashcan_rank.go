@@ -0,0 +1,173 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"template/config"
+	"template/ginServer/model"
+	"template/global"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// 附近搜索的排序方式
+const (
+	sortByDistance = "distance" // 按距离由近到远
+	sortByScore    = "score"    // 按综合得分由高到低
+)
+
+// scoreCandidateLimit 按得分排序时只对距离最近的这些垃圾桶计算得分
+const scoreCandidateLimit = 1000
+
+// defaultRankingConfig 未配置 ranking 时使用的默认权重
+var defaultRankingConfig = config.RankingConfig{
+	DistanceWeight:      0.6,
+	QualityWeight:       0.3,
+	RecencyWeight:       0.1,
+	DistanceScaleKm:     1,
+	RecencyHalfLifeDays: 180,
+	WilsonZ:             1.96,
+}
+
+// rankingConfig 读取排序权重配置，未配置或配置无效的参数使用默认值
+func rankingConfig() config.RankingConfig {
+	cfg := defaultRankingConfig
+	if global.CONFIG.RankingConfig == nil {
+		return cfg
+	}
+	cfg = *global.CONFIG.RankingConfig
+	if cfg.DistanceScaleKm <= 0 {
+		cfg.DistanceScaleKm = defaultRankingConfig.DistanceScaleKm
+	}
+	if cfg.RecencyHalfLifeDays <= 0 {
+		cfg.RecencyHalfLifeDays = defaultRankingConfig.RecencyHalfLifeDays
+	}
+	if cfg.WilsonZ <= 0 {
+		cfg.WilsonZ = defaultRankingConfig.WilsonZ
+	}
+	return cfg
+}
+
+// scoreComponents 综合得分及其组成部分，便于排查排序结果
+type scoreComponents struct {
+	Total    float64 `json:"total"`    // 综合得分
+	Distance float64 `json:"distance"` // 距离得分 0~1，越近越高
+	Quality  float64 `json:"quality"`  // 评价得分 -1~1，好评率置信下界减去差评率置信下界
+	Recency  float64 `json:"recency"`  // 新鲜度得分 0~1，按最后更新时间衰减
+}
+
+// newScoreComponents 计算一个垃圾桶的综合得分
+func newScoreComponents(cfg config.RankingConfig, distance float64, likes, dislikes int64, age time.Duration) scoreComponents {
+	votes := likes + dislikes
+	ageDays := math.Max(0, age.Hours()/24)
+	s := scoreComponents{
+		Distance: math.Exp(-distance / cfg.DistanceScaleKm),
+		Quality:  utils.WilsonLowerBound(likes, votes, cfg.WilsonZ) - utils.WilsonLowerBound(dislikes, votes, cfg.WilsonZ),
+		Recency:  math.Exp2(-ageDays / cfg.RecencyHalfLifeDays),
+	}
+	s.Total = cfg.DistanceWeight*s.Distance + cfg.QualityWeight*s.Quality + cfg.RecencyWeight*s.Recency
+	return s
+}
+
+// nearbyResult 附近搜索的一条结果，按得分排序时带有得分
+type nearbyResult struct {
+	spatial.Result
+	Score *scoreComponents
+}
+
+// nearbyByDistance 按距离由近到远查询一页附近的垃圾桶，返回本页结果和是否还有下一页
+func nearbyByDistance(lat, lng, radius float64, limit int, filter trashCanFilter, cursor *nearbyCursor) ([]nearbyResult, bool, error) {
+	var after *spatial.Result
+	if cursor != nil {
+		after = &cursor.Last
+	}
+
+	// 多查询一个用于判断是否还有下一页
+	matched, err := nearestMatching(lat, lng, limit+1, radius, after, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(matched) > limit
+	if hasMore {
+		matched = matched[:limit]
+	}
+
+	results := make([]nearbyResult, 0, len(matched))
+	for _, r := range matched {
+		results = append(results, nearbyResult{Result: r})
+	}
+	return results, hasMore, nil
+}
+
+// nearbyByScore 对半径范围内最近的若干个垃圾桶计算综合得分，按得分由高到低查询一页
+// now 为计算新鲜度的参考时间，翻页时使用第一页的时间以保证得分不变
+// 返回本页结果、是否还有下一页，以及半径范围内是否还有未参与排序的垃圾桶
+func nearbyByScore(lat, lng, radius float64, limit int, filter trashCanFilter, cursor *nearbyCursor, now time.Time) ([]nearbyResult, bool, bool, error) {
+	// 多查询一个用于判断候选是否被截断
+	candidates, err := nearestMatching(lat, lng, scoreCandidateLimit+1, radius, nil, filter)
+	if err != nil || len(candidates) == 0 {
+		return nil, false, false, err
+	}
+	truncated := len(candidates) > scoreCandidateLimit
+	if truncated {
+		candidates = candidates[:scoreCandidateLimit]
+	}
+
+	ids := make([]uint, 0, len(candidates))
+	for _, r := range candidates {
+		ids = append(ids, r.ID)
+	}
+	var rows []struct {
+		ID        uint
+		UpdatedAt time.Time
+	}
+	if err := global.DB.Model(&model.TrashCan{}).
+		Select("id, updated_at").
+		Where("id IN ?", ids).
+		Find(&rows).Error; err != nil {
+		return nil, false, false, err
+	}
+	updatedAt := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		updatedAt[row.ID] = row.UpdatedAt
+	}
+	likeCounts, dislikeCounts := countVotes(ids)
+
+	cfg := rankingConfig()
+	ranked := make([]nearbyResult, 0, len(candidates))
+	for _, r := range candidates {
+		t, ok := updatedAt[r.ID]
+		if !ok {
+			continue
+		}
+		score := newScoreComponents(cfg, r.Distance, likeCounts[r.ID], dislikeCounts[r.ID], now.Sub(t))
+		ranked = append(ranked, nearbyResult{Result: r, Score: &score})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return scoreBefore(ranked[i].Score.Total, ranked[i].ID, ranked[j].Score.Total, ranked[j].ID)
+	})
+
+	// 跳过上一页及之前的结果
+	if cursor != nil {
+		start := sort.Search(len(ranked), func(i int) bool {
+			return scoreBefore(cursor.Score, cursor.Last.ID, ranked[i].Score.Total, ranked[i].ID)
+		})
+		ranked = ranked[start:]
+	}
+
+	hasMore := len(ranked) > limit
+	if hasMore {
+		ranked = ranked[:limit]
+	}
+	return ranked, hasMore, truncated, nil
+}
+
+// scoreBefore 按得分由高到低、得分相同时按ID由小到大排序
+func scoreBefore(scoreA float64, idA uint, scoreB float64, idB uint) bool {
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	return idA < idB
+}
@@ -0,0 +1,489 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/utils"
+)
+
+// SearchTrashCansInArea 查询多边形区域内的垃圾桶（分页）
+// POST /api/trashcans/search
+// 请求体：{"geometry": GeoJSON Polygon/MultiPolygon 或 Feature, "page": 1, "page_size": 10, "coord_sys": "gcj02"}
+func SearchTrashCansInArea(c *gin.Context) {
+	var req struct {
+		Geometry json.RawMessage `json:"geometry"`
+		Page     int             `json:"page"`
+		PageSize int             `json:"page_size"`
+		CoordSys string          `json:"coord_sys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Geometry) == 0 {
+		common.ParamError(c)
+		return
+	}
+
+	coordSys, ok := utils.ParseCoordSys(req.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+		return
+	}
+
+	area, err := utils.ParsePolygonGeoJSON(req.Geometry)
+	if err != nil {
+		common.ParamErrorWithMessage(err.Error(), c)
+		return
+	}
+	area = area.ToCanonicalCoord(coordSys)
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 限制最大每页数量
+	}
+
+	// 先用外接矩形在数据库中粗筛，再逐个判断是否在多边形内
+	ids, err := trashCanIDsInArea(area)
+	if err != nil {
+		global.SugarLogger.Errorf("查询区域内垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	total := len(ids)
+	totalPages := (total + pageSize - 1) / pageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	offset := (page - 1) * pageSize
+	var pageIDs []uint
+	if offset < total {
+		end := offset + pageSize
+		if end > total {
+			end = total
+		}
+		pageIDs = ids[offset:end]
+	}
+
+	trashCans, err := loadTrashCans(pageIDs)
+	if err != nil {
+		global.SugarLogger.Errorf("查询区域内垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	result := map[string]interface{}{
+		"list":        newTrashCanItems(trashCans, coordSys),
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": totalPages,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// trashCanIDsInArea 返回多边形区域内全部垃圾桶的ID，按ID升序
+func trashCanIDsInArea(area utils.MultiPolygon) ([]uint, error) {
+	var candidates []struct {
+		ID        uint
+		Latitude  float64
+		Longitude float64
+	}
+	if err := global.DB.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(area.BoundingBox()), model.PublicStatus).
+		Select("id, latitude, longitude").
+		Order("id").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(candidates))
+	for _, p := range candidates {
+		if area.Contains(p.Latitude, p.Longitude) {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids, nil
+}
+
+const (
+	defaultRouteWidth   = 50    // 默认路线两侧的搜索宽度（米）
+	maxRouteWidth       = 1000  // 最大搜索宽度（米）
+	maxRouteLength      = 100.0 // 最大路线长度（公里）
+	maxAlongRouteResult = 1000  // 最多返回的垃圾桶数量
+)
+
+// alongRouteMatch 路线附近的一个垃圾桶
+type alongRouteMatch struct {
+	ID     uint
+	Along  float64 // 沿路线距起点的距离（公里）
+	Offset float64 // 到路线的距离（公里）
+}
+
+// SearchTrashCansAlongRoute 查询路线沿途一定宽度范围内的垃圾桶，按在路线上的先后顺序返回
+// POST /api/trashcans/along-route
+// 请求体：{"polyline": "编码折线", "precision": 5, "geometry": GeoJSON LineString 或 Feature, "width": 50, "coord_sys": "gcj02"}
+// polyline 与 geometry 二选一，width 单位为米；返回的 along_distance、offset 单位也为米
+func SearchTrashCansAlongRoute(c *gin.Context) {
+	var req struct {
+		Polyline  string          `json:"polyline"`
+		Precision int             `json:"precision"`
+		Geometry  json.RawMessage `json:"geometry"`
+		Width     float64         `json:"width"`
+		CoordSys  string          `json:"coord_sys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ParamError(c)
+		return
+	}
+
+	coordSys, ok := utils.ParseCoordSys(req.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+		return
+	}
+
+	var route utils.LineString
+	var err error
+	switch {
+	case req.Polyline != "":
+		route, err = utils.DecodePolyline(req.Polyline, req.Precision)
+	case len(req.Geometry) > 0:
+		route, err = utils.ParseLineStringGeoJSON(req.Geometry)
+	default:
+		common.ParamErrorWithMessage("polyline 和 geometry 不能同时为空", c)
+		return
+	}
+	if err != nil {
+		common.ParamErrorWithMessage(err.Error(), c)
+		return
+	}
+	route = route.ToCanonicalCoord(coordSys)
+
+	routeLength := route.Length()
+	if routeLength > maxRouteLength {
+		common.ParamErrorWithMessage("路线长度不能超过100公里", c)
+		return
+	}
+
+	width := req.Width
+	if width == 0 {
+		width = defaultRouteWidth
+	}
+	if width < 0 || width > maxRouteWidth {
+		common.ParamErrorWithMessage("width 必须在0到1000米之间", c)
+		return
+	}
+
+	matches, err := trashCansAlongRoute(route, width/1000)
+	if err != nil {
+		global.SugarLogger.Errorf("查询路线沿途垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	truncated := len(matches) > maxAlongRouteResult
+	if truncated {
+		matches = matches[:maxAlongRouteResult]
+	}
+
+	ids := make([]uint, 0, len(matches))
+	matchMap := make(map[uint]alongRouteMatch, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+		matchMap[m.ID] = m
+	}
+	trashCans, err := loadTrashCans(ids)
+	if err != nil {
+		global.SugarLogger.Errorf("查询路线沿途垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	type TrashCanAlongRoute struct {
+		trashCanItem
+		AlongDistance float64 `json:"along_distance"` // 沿路线距起点的距离（米）
+		Offset        float64 `json:"offset"`         // 到路线的距离（米）
+	}
+
+	list := make([]TrashCanAlongRoute, 0, len(trashCans))
+	for _, item := range newTrashCanItems(trashCans, coordSys) {
+		m := matchMap[item.ID]
+		list = append(list, TrashCanAlongRoute{
+			trashCanItem:  item,
+			AlongDistance: math.Round(m.Along * 1000),
+			Offset:        math.Round(m.Offset*10000) / 10,
+		})
+	}
+
+	result := map[string]interface{}{
+		"list":         list,
+		"route_length": math.Round(routeLength * 1000),
+		"width":        width,
+		"truncated":    truncated,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// trashCansAlongRoute 返回距路线 width（公里）以内的全部垃圾桶，按沿路线的距离排序
+func trashCansAlongRoute(route utils.LineString, width float64) ([]alongRouteMatch, error) {
+	var candidates []struct {
+		ID        uint
+		Latitude  float64
+		Longitude float64
+	}
+	if err := global.DB.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(route.BoundingBox().Expand(width)), model.PublicStatus).
+		Select("id, latitude, longitude").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	matches := make([]alongRouteMatch, 0, len(candidates))
+	for _, p := range candidates {
+		along, offset := route.Project(p.Latitude, p.Longitude)
+		if offset <= width {
+			matches = append(matches, alongRouteMatch{ID: p.ID, Along: along, Offset: offset})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Along != matches[j].Along {
+			return matches[i].Along < matches[j].Along
+		}
+		if matches[i].Offset != matches[j].Offset {
+			return matches[i].Offset < matches[j].Offset
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches, nil
+}
+
+const (
+	maxTextSearchCandidates = 1000 // 全文搜索最多参与排序的结果数量
+	minTrigramTermLength    = 3    // trigram分词器能够使用索引的最短关键字长度
+	descriptionSnippetRunes = 60   // 描述摘要的最大长度
+)
+
+// textSearchMatch 全文搜索命中的垃圾桶
+type textSearchMatch struct {
+	ID        uint
+	Relevance float64 // bm25得分，越小越相关
+}
+
+// SearchTrashCansByText 按地址和描述全文搜索垃圾桶
+// GET /api/trashcans/search?q=人民广场&lat=31.23&lng=121.47&page=1&page_size=10&coord_sys=gcj02
+// 多个关键字用空格分隔，需全部命中；提供 lat、lng 时综合相关度和距离排序，否则按相关度排序
+// 返回的 address_highlight、description_highlight 已做HTML转义，命中的关键字用 <mark> 标记
+func SearchTrashCansByText(c *gin.Context) {
+	var query request.TrashCanTextSearchQuery
+	if !common.BindQuery(c, &query) {
+		return
+	}
+
+	coordSys, ok := utils.ParseCoordSys(query.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("参数 coord_sys 只能是 wgs84、gcj02、bd09 之一", c)
+		return
+	}
+	if (query.Lat == nil) != (query.Lng == nil) {
+		common.ParamErrorWithMessage("参数 lat 和 lng 需要同时提供", c)
+		return
+	}
+	terms := strings.Fields(query.Q)
+	if len(terms) == 0 {
+		common.ParamErrorWithMessage("参数 q 不能为空", c)
+		return
+	}
+
+	matches, total, truncated, err := searchTrashCanText(terms)
+	if err != nil {
+		global.SugarLogger.Errorf("全文搜索垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	// 相关度归一化到 (0, 1]，最相关的为1
+	best := 0.0
+	for _, m := range matches {
+		best = math.Min(best, m.Relevance)
+	}
+	relevance := make(map[uint]float64, len(matches))
+	for _, m := range matches {
+		relevance[m.ID] = 1
+		if best < 0 {
+			relevance[m.ID] = m.Relevance / best
+		}
+	}
+
+	// 提供了位置时，相关度和距离各占一半
+	var distances map[uint]float64
+	scores := relevance
+	if query.Lat != nil {
+		lat, lng := utils.ToCanonicalCoord(*query.Lat, *query.Lng, coordSys)
+		distances, err = trashCanDistances(lat, lng, matches)
+		if err != nil {
+			global.SugarLogger.Errorf("全文搜索垃圾桶失败: %v", err)
+			common.FailWithMessage("查询失败", c)
+			return
+		}
+		scores = make(map[uint]float64, len(matches))
+		for id, r := range relevance {
+			scores[id] = 0.5*r + 0.5/(1+distances[id])
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return scoreBefore(scores[matches[i].ID], matches[i].ID, scores[matches[j].ID], matches[j].ID)
+	})
+
+	// 只有相关度最高的 maxTextSearchCandidates 个结果参与排序和分页
+	totalPages := (len(matches) + query.PageSize - 1) / query.PageSize
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	offset := (query.Page - 1) * query.PageSize
+	var pageIDs []uint
+	for i := offset; i < len(matches) && i < offset+query.PageSize; i++ {
+		pageIDs = append(pageIDs, matches[i].ID)
+	}
+
+	trashCans, err := loadTrashCans(pageIDs)
+	if err != nil {
+		global.SugarLogger.Errorf("全文搜索垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	type TrashCanSearchResult struct {
+		trashCanItem
+		AddressHighlight     string   `json:"address_highlight"`     // 高亮后的地址
+		DescriptionHighlight string   `json:"description_highlight"` // 高亮后的描述摘要
+		Relevance            float64  `json:"relevance"`             // 相关度 0~1
+		Distance             *float64 `json:"distance,omitempty"`    // 距离（公里），提供位置时返回
+	}
+
+	list := make([]TrashCanSearchResult, 0, len(trashCans))
+	for _, item := range newTrashCanItems(trashCans, coordSys) {
+		result := TrashCanSearchResult{
+			trashCanItem:         item,
+			AddressHighlight:     utils.Highlight(item.Address, terms, 0),
+			DescriptionHighlight: utils.Highlight(item.Description, terms, descriptionSnippetRunes),
+			Relevance:            relevance[item.ID],
+		}
+		if distance, ok := distances[item.ID]; ok {
+			result.Distance = &distance
+		}
+		list = append(list, result)
+	}
+
+	result := map[string]interface{}{
+		"list":        list,
+		"total":       total,
+		"page":        query.Page,
+		"page_size":   query.PageSize,
+		"total_pages": totalPages,
+		"truncated":   truncated,
+	}
+
+	common.OkWithData(result, c)
+}
+
+// searchTrashCanText 在全文索引中查找地址或描述包含全部关键字的垃圾桶，按相关度排序
+// 最多返回 maxTextSearchCandidates 个结果，超出时 truncated 为 true；
+// total 为命中的总数，但只有短关键字时统计总数需要扫描全表，此时不再统计，total 为返回的结果数量
+func searchTrashCanText(terms []string) (matches []textSearchMatch, total int64, truncated bool, err error) {
+	query, ranked := textSearchQuery(terms)
+	if ranked {
+		query = query.Select(fmt.Sprintf("rowid AS id, bm25(%s) AS relevance", model.TrashCanSearchTable)).Order("relevance")
+	} else {
+		// 没有可以使用索引的关键字时无法计算相关度
+		query = query.Select("rowid AS id, 0 AS relevance").Order("rowid")
+	}
+
+	// 多查一条用于判断是否被截断
+	if err := query.Limit(maxTextSearchCandidates + 1).Scan(&matches).Error; err != nil {
+		return nil, 0, false, err
+	}
+	truncated = len(matches) > maxTextSearchCandidates
+	if truncated {
+		matches = matches[:maxTextSearchCandidates]
+	}
+	total = int64(len(matches))
+	if truncated && ranked {
+		// 结果被截断时另外查询总数
+		q, _ := textSearchQuery(terms)
+		if err := q.Count(&total).Error; err != nil {
+			return nil, 0, false, err
+		}
+	}
+	return matches, total, truncated, nil
+}
+
+// textSearchQuery 构造全文搜索的查询条件
+// 不少于3个字符的关键字使用FTS5索引匹配，更短的关键字（如“地铁”）trigram无法索引，改用 LIKE 过滤；
+// 有关键字使用了索引匹配时 ranked 为 true，可以用 bm25 计算相关度，否则只能逐行扫描全表
+func textSearchQuery(terms []string) (query *gorm.DB, ranked bool) {
+	table := model.TrashCanSearchTable
+	// 只搜索公开的垃圾桶
+	query = global.DB.Table(table).
+		Where("rowid IN (?)", global.DB.Model(&model.TrashCan{}).Scopes(model.PublicStatus).Select("id"))
+
+	var phrases []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= minTrigramTermLength {
+			phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+			continue
+		}
+		pattern := "%" + model.EscapeLike(term) + "%"
+		query = query.Where(`(address LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	if len(phrases) > 0 {
+		query = query.Where(table+" MATCH ?", strings.Join(phrases, " "))
+	}
+	return query, len(phrases) > 0
+}
+
+// trashCanDistances 计算指定位置到各个搜索结果的距离（公里）
+func trashCanDistances(lat, lng float64, matches []textSearchMatch) (map[uint]float64, error) {
+	ids := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+	var points []struct {
+		ID        uint
+		Latitude  float64
+		Longitude float64
+	}
+	if len(ids) > 0 {
+		if err := global.DB.Model(&model.TrashCan{}).
+			Select("id, latitude, longitude").
+			Where("id IN ?", ids).
+			Find(&points).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	distances := make(map[uint]float64, len(points))
+	for _, p := range points {
+		distances[p.ID] = utils.CalculateDistance(lat, lng, p.Latitude, p.Longitude)
+	}
+	return distances, nil
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"template/ginServer/api/common"
+	"template/ginServer/middle"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+)
+
+// defaultMissingReportThreshold 未配置时自动变为 reported_missing 所需的报告人数
+const defaultMissingReportThreshold = 3
+
+// missingReportThreshold 自动变为 reported_missing 所需的报告人数
+func missingReportThreshold() int64 {
+	if cfg := global.CONFIG.LifecycleConfig; cfg != nil && cfg.MissingReportThreshold > 0 {
+		return int64(cfg.MissingReportThreshold)
+	}
+	return defaultMissingReportThreshold
+}
+
+// errStatusChanged 变更期间垃圾桶的状态已被其他请求修改
+var errStatusChanged = errors.New("垃圾桶状态已变化，请刷新后重试")
+
+// transitionTrashCanStatus 按状态机变更垃圾桶状态并记录变更，actorID 为nil表示系统自动变更
+// 变为 active 时清空不存在报告，之后重新计数
+func transitionTrashCanStatus(tx *gorm.DB, trashCan *model.TrashCan, to model.TrashCanStatus, actor model.TrashCanStatusActor, actorID *uint, reason string) error {
+	from := trashCan.Status
+	if err := model.CheckTransition(from, to, actor); err != nil {
+		return err
+	}
+	// 以当前状态为条件更新，避免并发的变更互相覆盖
+	// 状态决定垃圾桶是否公开，空间索引在事务提交后随之加入或删除该垃圾桶，并使相关缓存失效
+	res := tx.Model(&model.TrashCan{ID: trashCan.ID}).Where("status = ?", from).Update("status", to)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errStatusChanged
+	}
+	if err := tx.Create(&model.TrashCanStatusLog{
+		TrashCanID: trashCan.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      actor,
+		ActorID:    actorID,
+		Reason:     reason,
+	}).Error; err != nil {
+		return err
+	}
+	if to == model.StatusActive {
+		if err := tx.Where("trash_can_id = ?", trashCan.ID).Delete(&model.TrashCanMissingReport{}).Error; err != nil {
+			return err
+		}
+	}
+	trashCan.Status = to
+	return nil
+}
+
+// UpdateTrashCanStatus 变更垃圾桶的生命周期状态
+// PUT /api/trashcans/:id/status
+// 请求体 {"status": "active", "reason": "已核实"}；管理员可以执行全部允许的变更，
+// 垃圾桶的上传者只能撤回待审核的垃圾桶或将垃圾桶标记为已撤除
+func UpdateTrashCanStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	userIDUint := userID.(uint)
+
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	var req request.UpdateTrashCanStatusRequest
+	if !common.BindJSON(c, &req) {
+		return
+	}
+	to, err := model.ParseTrashCanStatus(req.Status)
+	if err != nil {
+		common.ParamErrorWithMessage("参数 status 有误: "+err.Error(), c)
+		return
+	}
+
+	var actor model.TrashCanStatusActor
+	switch {
+	case middle.IsAdmin(userIDUint):
+		actor = model.ActorModerator
+	case trashCan.UserID != nil && *trashCan.UserID == userIDUint:
+		actor = model.ActorOwner
+	default:
+		common.FailWithForbidden(c)
+		return
+	}
+
+	if err := model.CheckTransition(trashCan.Status, to, actor); err != nil {
+		if errors.Is(err, model.ErrTransitionForbidden) {
+			common.FailWithForbidden(c)
+			return
+		}
+		common.ParamErrorWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		return transitionTrashCanStatus(tx, &trashCan, to, actor, &userIDUint, strings.TrimSpace(req.Reason))
+	}); err != nil {
+		if errors.Is(err, errStatusChanged) {
+			common.FailWithMessage(err.Error(), c)
+			return
+		}
+		global.SugarLogger.Errorf("变更垃圾桶状态失败: %v", err)
+		common.FailWithMessage("变更失败", c)
+		return
+	}
+
+	common.OkWithDetailed(map[string]interface{}{
+		"id":     trashCan.ID,
+		"status": trashCan.Status,
+	}, "变更成功", c)
+}
+
+// ReportTrashCanMissing 报告垃圾桶已不存在，每个用户对每个垃圾桶只能报告一次
+// POST /api/trashcans/:id/missing-reports
+// 正常状态的垃圾桶收到的报告达到 config.yml 中 lifecycle.missing_report_threshold 时自动变为 reported_missing
+func ReportTrashCanMissing(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.FailWithAuthority(c)
+		return
+	}
+	userIDUint := userID.(uint)
+
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+	// 请求体可以省略
+	var req request.ReportTrashCanMissingRequest
+	if c.Request.ContentLength != 0 && !common.BindJSON(c, &req) {
+		return
+	}
+	if trashCan.Status != model.StatusActive && trashCan.Status != model.StatusReportedMissing {
+		common.ParamErrorWithMessage(fmt.Sprintf("%s 状态的垃圾桶不能报告不存在", trashCan.Status), c)
+		return
+	}
+
+	threshold := missingReportThreshold()
+	var count int64
+	var reported bool
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 依靠 (trash_can_id, user_id) 唯一索引判断是否报告过，并发的重复报告也只会插入一条
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.TrashCanMissingReport{
+			TrashCanID: trashCan.ID,
+			UserID:     userIDUint,
+			Reason:     strings.TrimSpace(req.Reason),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			reported = true
+			return nil
+		}
+		if err := tx.Model(&model.TrashCanMissingReport{}).Where("trash_can_id = ?", trashCan.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if trashCan.Status != model.StatusActive || count < threshold {
+			return nil
+		}
+		reason := fmt.Sprintf("%d 位用户报告该垃圾桶已不存在", count)
+		return transitionTrashCanStatus(tx, &trashCan, model.StatusReportedMissing, model.ActorSystem, nil, reason)
+	})
+	if err != nil {
+		global.SugarLogger.Errorf("报告垃圾桶不存在失败: %v", err)
+		common.FailWithMessage("报告失败", c)
+		return
+	}
+	if reported {
+		common.ParamErrorWithMessage("已经报告过该垃圾桶", c)
+		return
+	}
+
+	common.OkWithDetailed(map[string]interface{}{
+		"id":           trashCan.ID,
+		"status":       trashCan.Status,
+		"report_count": count,
+		"threshold":    threshold,
+	}, "报告成功", c)
+}
+
+// GetTrashCanStatusLogs 获取垃圾桶的状态变更记录，按时间先后排列
+// GET /api/trashcans/:id/status-logs
+func GetTrashCanStatusLogs(c *gin.Context) {
+	trashCan, ok := trashCanParam(c)
+	if !ok {
+		return
+	}
+
+	var logs []model.TrashCanStatusLog
+	if err := global.DB.Where("trash_can_id = ?", trashCan.ID).Order("id").Find(&logs).Error; err != nil {
+		global.SugarLogger.Errorf("查询状态变更记录失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+	var reportCount int64
+	if err := global.DB.Model(&model.TrashCanMissingReport{}).Where("trash_can_id = ?", trashCan.ID).Count(&reportCount).Error; err != nil {
+		global.SugarLogger.Errorf("统计不存在报告失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	common.OkWithData(map[string]interface{}{
+		"status":               trashCan.Status,
+		"missing_report_count": reportCount, // 当前的不存在报告人数，垃圾桶重新变为 active 时清零
+		"logs":                 logs,
+	}, c)
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+func TestUpdateTrashCanStatus(t *testing.T) {
+	r := setupTestServer(t)
+	r.PUT("/trashcans/:id/status", UpdateTrashCanStatus)
+	r.GET("/trashcans/:id/status-logs", GetTrashCanStatusLogs)
+	r.DELETE("/trashcans/:id", DeleteTrashCan)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, UserID: uintPtr(1), Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusPendingReview},
+		{ID: 2, UserID: uintPtr(1), Latitude: 31.2404, Longitude: 121.4837, Status: model.StatusActive},
+	})
+
+	steps := []struct {
+		name     string
+		id       uint
+		status   string
+		userID   uint
+		wantCode int
+	}{
+		{"上传者不能审核自己的垃圾桶", 1, "active", 1, 5000},
+		{"其他用户看不到待审核的垃圾桶", 1, "removed", 2, 5000},
+		{"其他用户不能撤除", 2, "removed", 2, 5000},
+		{"状态有误", 1, "deleted", testAdminID, 4000},
+		{"不允许的变更", 1, "reported_missing", testAdminID, 4000},
+		{"管理员审核通过", 1, "active", testAdminID, 2000},
+		{"重复变更", 1, "active", testAdminID, 4000},
+		{"上传者撤除", 2, "removed", 1, 2000},
+		{"上传者不能恢复", 2, "active", 1, 5000},
+		{"管理员恢复", 2, "active", testAdminID, 2000},
+	}
+	for _, step := range steps {
+		resp := doJSON(t, r, "PUT", fmt.Sprintf("/trashcans/%d/status", step.id),
+			map[string]string{"status": step.status, "reason": step.name}, step.userID)
+		if resp.Code != step.wantCode {
+			t.Errorf("%s: 响应 = %d %s, want %d", step.name, resp.Code, resp.Msg, step.wantCode)
+		}
+	}
+
+	var history struct {
+		Status model.TrashCanStatus      `json:"status"`
+		Logs   []model.TrashCanStatusLog `json:"logs"`
+	}
+	decodeData(t, doRequest(t, r, "GET", "/trashcans/2/status-logs", nil, "", 0), &history)
+	if history.Status != model.StatusActive || len(history.Logs) != 2 ||
+		history.Logs[0].Actor != model.ActorOwner || history.Logs[1].Actor != model.ActorModerator ||
+		history.Logs[1].Reason != "管理员恢复" {
+		t.Errorf("状态变更记录 = %+v", history)
+	}
+
+	// 删除垃圾桶后状态变更记录保留备查
+	if resp := doRequest(t, r, "DELETE", "/trashcans/1", nil, "", 1); resp.Code != 2000 {
+		t.Fatalf("删除垃圾桶: %d %s", resp.Code, resp.Msg)
+	}
+	var count int64
+	global.DB.Model(&model.TrashCanStatusLog{}).Where("trash_can_id = ?", 1).Count(&count)
+	if count != 1 {
+		t.Errorf("删除后 #1 的状态变更记录有 %d 条, want 1", count)
+	}
+}
+
+func TestReportTrashCanMissing(t *testing.T) {
+	r := setupTestServer(t)
+	r.POST("/trashcans/:id/missing-reports", ReportTrashCanMissing)
+	r.PUT("/trashcans/:id/status", UpdateTrashCanStatus)
+	r.GET("/trashcans/:id/status-logs", GetTrashCanStatusLogs)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, UserID: uintPtr(1), Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive},
+		{ID: 2, UserID: uintPtr(1), Latitude: 31.2404, Longitude: 121.4837, Status: model.StatusPendingReview},
+	})
+
+	type reportResult struct {
+		Status      model.TrashCanStatus `json:"status"`
+		ReportCount int64                `json:"report_count"`
+	}
+	report := func(id, userID uint) testResponse {
+		return doJSON(t, r, "POST", fmt.Sprintf("/trashcans/%d/missing-reports", id), map[string]string{"reason": "已被撤走"}, userID)
+	}
+
+	for i, want := range []reportResult{{model.StatusActive, 1}, {model.StatusActive, 2}} {
+		var got reportResult
+		decodeData(t, report(1, uint(i+1)), &got)
+		if got != want {
+			t.Errorf("第 %d 次报告 = %+v, want %+v", i+1, got, want)
+		}
+	}
+	if resp := report(1, 1); resp.Code != 4000 || resp.Msg != "已经报告过该垃圾桶" {
+		t.Errorf("重复报告: %d %s", resp.Code, resp.Msg)
+	}
+	if resp := report(2, 1); resp.Code != 4000 {
+		t.Errorf("报告待审核的垃圾桶: %d %s, want 4000", resp.Code, resp.Msg)
+	}
+
+	// 达到阈值后自动变为 reported_missing
+	var got reportResult
+	decodeData(t, report(1, 3), &got)
+	if got.Status != model.StatusReportedMissing || got.ReportCount != 3 {
+		t.Errorf("达到阈值后 = %+v", got)
+	}
+	decodeData(t, report(1, 4), &got)
+	if got.Status != model.StatusReportedMissing || got.ReportCount != 4 {
+		t.Errorf("达到阈值之后的报告 = %+v", got)
+	}
+
+	// 管理员确认仍然存在后清空报告
+	if resp := doJSON(t, r, "PUT", "/trashcans/1/status", map[string]string{"status": "active"}, testAdminID); resp.Code != 2000 {
+		t.Fatalf("恢复为 active: %d %s", resp.Code, resp.Msg)
+	}
+	var history struct {
+		MissingReportCount int64                     `json:"missing_report_count"`
+		Logs               []model.TrashCanStatusLog `json:"logs"`
+	}
+	decodeData(t, doRequest(t, r, "GET", "/trashcans/1/status-logs", nil, "", 0), &history)
+	if history.MissingReportCount != 0 || len(history.Logs) != 2 ||
+		history.Logs[0].Actor != model.ActorSystem || history.Logs[0].ActorID != nil {
+		t.Errorf("状态变更记录 = %+v", history)
+	}
+	decodeData(t, report(1, 1), &got)
+	if got.ReportCount != 1 {
+		t.Errorf("恢复后重新计数 = %+v", got)
+	}
+}
+
+func TestTrashCanVisibility(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/nearby", GetNearbyTrashCans)
+	r.GET("/trashcans/:id", GetTrashCanDetail)
+	r.PUT("/trashcans/:id/status", UpdateTrashCanStatus)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, UserID: uintPtr(1), Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive},
+		{ID: 2, UserID: uintPtr(1), Latitude: 31.2305, Longitude: 121.4737, Status: model.StatusPendingReview},
+		{ID: 3, UserID: uintPtr(2), Latitude: 31.2306, Longitude: 121.4737, Status: model.StatusRemoved},
+		{ID: 4, UserID: uintPtr(2), Latitude: 31.2307, Longitude: 121.4737, Status: model.StatusReportedMissing},
+	})
+
+	nearby := func(query string, userID uint) []uint {
+		t.Helper()
+		var result struct {
+			List []trashCanItem `json:"list"`
+		}
+		decodeData(t, doRequest(t, r, "GET", "/trashcans/nearby?lat=31.2304&lng=121.4737&radius=1"+query, nil, "", userID), &result)
+		ids := make([]uint, 0, len(result.List))
+		for _, item := range result.List {
+			ids = append(ids, item.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+	tests := []struct {
+		name   string
+		query  string
+		userID uint
+		want   []uint
+	}{
+		{"未登录默认", "", 0, []uint{1, 4}},
+		{"未登录查询全部状态", "&status=all", 0, []uint{1, 4}},
+		{"登录用户默认", "", 1, []uint{1, 4}},
+		{"上传者查询待审核", "&status=pending_review", 1, []uint{2}},
+		{"上传者查询全部状态", "&status=all", 1, []uint{1, 2, 4}},
+		{"其他用户查询待审核", "&status=pending_review", 2, []uint{}},
+		{"其他用户查询全部状态", "&status=all", 2, []uint{1, 3, 4}},
+		{"管理员查询全部状态", "&status=all", testAdminID, []uint{1, 2, 3, 4}},
+		{"管理员查询并带其他筛选条件", "&status=all&uploader_id=1", testAdminID, []uint{1, 2}},
+		{"管理员按得分排序", "&status=pending_review,removed&sort=score", testAdminID, []uint{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nearby(tt.query, tt.userID); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("附近的垃圾桶 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		id       uint
+		userID   uint
+		wantCode int
+	}{
+		{2, 0, 5000},
+		{2, 2, 5000},
+		{2, 1, 2000},
+		{2, testAdminID, 2000},
+		{3, 0, 2000},
+	} {
+		if resp := doRequest(t, r, "GET", fmt.Sprintf("/trashcans/%d", tt.id), nil, "", tt.userID); resp.Code != tt.wantCode {
+			t.Errorf("用户 %d 查询 #%d 的详情: %d %s, want %d", tt.userID, tt.id, resp.Code, resp.Msg, tt.wantCode)
+		}
+	}
+
+	// 状态变更后空间索引随之更新
+	for id, status := range map[uint]string{2: "active", 1: "removed"} {
+		if resp := doJSON(t, r, "PUT", fmt.Sprintf("/trashcans/%d/status", id), map[string]string{"status": status}, testAdminID); resp.Code != 2000 {
+			t.Fatalf("将 #%d 变为 %s: %d %s", id, status, resp.Code, resp.Msg)
+		}
+	}
+	if got := nearby("", 0); fmt.Sprint(got) != fmt.Sprint([]uint{2, 4}) {
+		t.Errorf("状态变更后附近的垃圾桶 = %v, want [2 4]", got)
+	}
+}
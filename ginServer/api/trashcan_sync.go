@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/binary"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/ginServer/model/request"
+	"template/global"
+	"template/utils"
+)
+
+// changesTokenScope 同步令牌的签名作用域
+const changesTokenScope = "trashcans/changes:v1"
+
+// changesToken 增量同步令牌
+// Change 为客户端已同步到的变更日志ID；全量同步分页进行时 Snapshot 为true，Last 为上一页最后一个垃圾桶的ID
+type changesToken struct {
+	Change   uint64
+	Snapshot bool
+	Last     uint
+}
+
+// encodeChangesToken 将同步令牌编码为带签名的字符串
+func encodeChangesToken(token changesToken) string {
+	payload := binary.BigEndian.AppendUint64(nil, token.Change)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(token.Last))
+	flag := byte(0)
+	if token.Snapshot {
+		flag = 1
+	}
+	payload = append(payload, flag)
+	return utils.SignCursor(cursorSecret(), changesTokenScope, payload)
+}
+
+// decodeChangesToken 校验签名并解析同步令牌
+func decodeChangesToken(s string) (changesToken, error) {
+	var token changesToken
+	payload, err := utils.VerifyCursor(cursorSecret(), changesTokenScope, s)
+	if err != nil {
+		return token, err
+	}
+	if len(payload) != 17 {
+		return token, utils.ErrInvalidCursor
+	}
+	token.Change = binary.BigEndian.Uint64(payload)
+	token.Last = uint(binary.BigEndian.Uint64(payload[8:]))
+	token.Snapshot = payload[16] == 1
+	return token, nil
+}
+
+// GetTrashCanChanges 离线增量同步，返回令牌之后新增、修改和删除的垃圾桶以及新的令牌
+// GET /api/trashcans/changes?since=<token>&limit=500&coord_sys=gcj02
+// 不传 since 时进行全量同步（full_sync=true），分页返回全部公开的垃圾桶；has_more 为true时用返回的 token 继续请求，
+// 全部取完后保存最后的 token，之后定期用它获取增量变更，deleted 中的ID需要从本地缓存中删除
+// 令牌之后的变更日志已超过保留期限被清理时，同样返回从头开始的全量同步
+func GetTrashCanChanges(c *gin.Context) {
+	var query request.TrashCanChangesQuery
+	if !common.BindQuery(c, &query) {
+		return
+	}
+	coordSys, ok := utils.ParseCoordSys(query.CoordSys)
+	if !ok {
+		common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+		return
+	}
+
+	var token changesToken
+	restart := query.Since == ""
+	if !restart {
+		var err error
+		if token, err = decodeChangesToken(query.Since); err != nil {
+			common.ParamErrorWithMessage("同步令牌无效，请不带 since 重新全量同步", c)
+			return
+		}
+		if !token.Snapshot {
+			// 客户端太久没有同步，令牌之后的变更日志已被清理，无法再计算增量
+			if restart, err = changesCompacted(token.Change); err != nil {
+				global.SugarLogger.Errorf("查询变更日志失败: %v", err)
+				common.FailWithMessage("查询失败", c)
+				return
+			}
+		}
+	}
+	if restart {
+		// 全量同步从当前最新的变更开始记录位置，分页期间发生的变更会在之后的增量同步中返回
+		token = changesToken{Snapshot: true}
+		if err := global.DB.Model(&model.TrashCanChange{}).
+			Select("COALESCE(MAX(id), 0)").
+			Scan(&token.Change).Error; err != nil {
+			global.SugarLogger.Errorf("查询变更日志失败: %v", err)
+			common.FailWithMessage("查询失败", c)
+			return
+		}
+	}
+
+	if token.Snapshot {
+		var trashCans []model.TrashCan
+		if err := global.DB.Scopes(model.PublicStatus).
+			Where("id > ?", token.Last).
+			Order("id").
+			Limit(query.Limit + 1).
+			Find(&trashCans).Error; err != nil {
+			global.SugarLogger.Errorf("全量同步查询垃圾桶失败: %v", err)
+			common.FailWithMessage("查询失败", c)
+			return
+		}
+		hasMore := len(trashCans) > query.Limit
+		if hasMore {
+			trashCans = trashCans[:query.Limit]
+			token.Last = trashCans[len(trashCans)-1].ID
+		} else {
+			token.Snapshot, token.Last = false, 0
+		}
+
+		result := map[string]interface{}{
+			"full_sync": true, // 客户端收到全量同步的第一页时应清空本地缓存
+			"created":   newTrashCanItems(trashCans, coordSys),
+			"updated":   []trashCanItem{},
+			"deleted":   []uint{},
+			"token":     encodeChangesToken(token),
+			"has_more":  hasMore,
+		}
+		common.OkWithData(result, c)
+		return
+	}
+
+	var changes []model.TrashCanChange
+	if err := global.DB.Where("id > ?", token.Change).
+		Order("id").
+		Limit(query.Limit + 1).
+		Find(&changes).Error; err != nil {
+		global.SugarLogger.Errorf("查询变更日志失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+	hasMore := len(changes) > query.Limit
+	if hasMore {
+		changes = changes[:query.Limit]
+	}
+	if len(changes) > 0 {
+		token.Change = changes[len(changes)-1].ID
+	}
+
+	// 同一个垃圾桶的多次变更合并为一条：按当前是否存在（且公开）区分删除，存在时按本次范围内的第一次变更区分新增和修改
+	var ids []uint
+	created := make(map[uint]bool)
+	for _, change := range changes {
+		if _, seen := created[change.TrashCanID]; seen {
+			continue
+		}
+		ids = append(ids, change.TrashCanID)
+		created[change.TrashCanID] = change.Op == model.TrashCanChangeCreated
+	}
+	trashCans, err := loadTrashCans(ids)
+	if err != nil {
+		global.SugarLogger.Errorf("增量同步查询垃圾桶失败: %v", err)
+		common.FailWithMessage("查询失败", c)
+		return
+	}
+
+	// 只同步公开的垃圾桶，变为待审核或已撤除的垃圾桶与已删除的一样作为墓碑返回；
+	// 重新公开的垃圾桶（如审核通过）可能不在客户端的本地缓存中，客户端处理 updated 时应按新增处理不存在的垃圾桶
+	public := trashCans[:0]
+	for _, tc := range trashCans {
+		if tc.Status.Public() {
+			public = append(public, tc)
+		}
+	}
+	existing := make(map[uint]bool, len(public))
+	createdItems, updatedItems := make([]trashCanItem, 0), make([]trashCanItem, 0)
+	for _, item := range newTrashCanItems(public, coordSys) {
+		existing[item.ID] = true
+		if created[item.ID] {
+			createdItems = append(createdItems, item)
+		} else {
+			updatedItems = append(updatedItems, item)
+		}
+	}
+	deleted := make([]uint, 0)
+	for _, id := range ids {
+		if !existing[id] {
+			deleted = append(deleted, id)
+		}
+	}
+
+	result := map[string]interface{}{
+		"full_sync": false,
+		"created":   createdItems,
+		"updated":   updatedItems,
+		"deleted":   deleted,
+		"token":     encodeChangesToken(token),
+		"has_more":  hasMore,
+	}
+	common.OkWithData(result, c)
+}
+
+// changesCompacted 变更日志中 change 之后的记录是否已被清理
+// 变更日志ID连续递增，清理时保留最新的一条，最早的一条不紧接在 change 之后说明中间的记录已被删除
+func changesCompacted(change uint64) (bool, error) {
+	var oldest *uint64
+	if err := global.DB.Model(&model.TrashCanChange{}).
+		Select("MIN(id)").
+		Scan(&oldest).Error; err != nil {
+		return false, err
+	}
+	return oldest != nil && *oldest > change+1, nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/model"
+	"template/global"
+	Orm "template/initialize/orm"
+)
+
+// changesResult 增量同步的响应数据
+type changesResult struct {
+	FullSync bool           `json:"full_sync"`
+	Created  []trashCanItem `json:"created"`
+	Updated  []trashCanItem `json:"updated"`
+	Deleted  []uint         `json:"deleted"`
+	Token    string         `json:"token"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// getChanges 请求增量同步，since 为空时全量同步
+func getChanges(t *testing.T, r *gin.Engine, since string, limit int) changesResult {
+	t.Helper()
+	var result changesResult
+	target := fmt.Sprintf("/trashcans/changes?limit=%d&since=%s", limit, url.QueryEscape(since))
+	decodeData(t, doRequest(t, r, "GET", target, nil, "", 0), &result)
+	return result
+}
+
+// itemIDs 按顺序取出垃圾桶ID，用于比较
+func itemIDs(items []trashCanItem) string {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestGetTrashCanChanges(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/changes", GetTrashCanChanges)
+
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 1, Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive},
+		{ID: 2, Latitude: 31.2404, Longitude: 121.4837, Status: model.StatusActive},
+		{ID: 3, Latitude: 31.2504, Longitude: 121.4937, Status: model.StatusActive},
+		{ID: 4, Latitude: 31.2604, Longitude: 121.5037, Status: model.StatusPendingReview},
+	})
+
+	// 全量同步分页返回公开的垃圾桶
+	first := getChanges(t, r, "", 2)
+	if !first.FullSync || !first.HasMore || itemIDs(first.Created) != "[1 2]" {
+		t.Fatalf("全量同步第一页 = %+v", first)
+	}
+	// 分页期间的变更在之后的增量同步中返回
+	if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("address", "人民广场").Error; err != nil {
+		t.Fatal(err)
+	}
+	second := getChanges(t, r, first.Token, 2)
+	if !second.FullSync || second.HasMore || itemIDs(second.Created) != "[3]" {
+		t.Fatalf("全量同步第二页 = %+v", second)
+	}
+
+	// 同一个垃圾桶的多次变更合并为一条；变为非公开的垃圾桶和已删除的一样作为墓碑返回，
+	// 审核通过的垃圾桶在 updated 中返回
+	if err := global.DB.Delete(&model.TrashCan{}, 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	createTestTrashCans(t, []model.TrashCan{
+		{ID: 5, Latitude: 31.2704, Longitude: 121.5137, Status: model.StatusActive},
+		{ID: 6, Latitude: 31.2804, Longitude: 121.5237, Status: model.StatusPendingReview},
+	})
+	for id, status := range map[uint]model.TrashCanStatus{3: model.StatusRemoved, 4: model.StatusActive} {
+		if err := global.DB.Model(&model.TrashCan{ID: id}).Update("status", status).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := global.DB.Model(&model.TrashCan{ID: 5}).Update("description", "东门").Error; err != nil {
+		t.Fatal(err)
+	}
+	delta := getChanges(t, r, second.Token, 100)
+	if delta.FullSync || delta.HasMore || itemIDs(delta.Created) != "[5]" || itemIDs(delta.Updated) != "[1 4]" ||
+		fmt.Sprint(delta.Deleted) != "[2 6 3]" {
+		t.Errorf("增量同步 = created %s, updated %s, deleted %v", itemIDs(delta.Created), itemIDs(delta.Updated), delta.Deleted)
+	}
+	if delta.Created[0].Description != "东门" {
+		t.Errorf("新增的垃圾桶 = %+v, 应为最新的数据", delta.Created[0])
+	}
+
+	// 没有新的变更时令牌不变
+	idle := getChanges(t, r, delta.Token, 100)
+	if idle.FullSync || idle.Token != delta.Token || len(idle.Created)+len(idle.Updated)+len(idle.Deleted) != 0 {
+		t.Errorf("没有变更时 = %+v", idle)
+	}
+
+	if resp := doRequest(t, r, "GET", "/trashcans/changes?since=invalid", nil, "", 0); resp.Code != 4000 {
+		t.Errorf("无效的令牌: %d %s, want 4000", resp.Code, resp.Msg)
+	}
+
+	// 令牌之后的变更日志被清理后返回全量同步
+	for _, address := range []string{"一", "二"} {
+		if err := global.DB.Model(&model.TrashCan{ID: 1}).Update("address", address).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := global.DB.Model(&model.TrashCanChange{}).Where("1 = 1").
+		Update("created_at", "2000-01-01 00:00:00").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Orm.CompactTrashCanChanges(global.DB, 30); err != nil {
+		t.Fatal(err)
+	}
+	stale := getChanges(t, r, delta.Token, 100)
+	if !stale.FullSync || itemIDs(stale.Created) != "[1 4 5]" {
+		t.Errorf("变更日志清理后 = %+v, want 全量同步", stale)
+	}
+	// 清理后的令牌仍可继续增量同步
+	if err := global.DB.Model(&model.TrashCan{ID: 5}).Update("address", "三").Error; err != nil {
+		t.Fatal(err)
+	}
+	next := getChanges(t, r, stale.Token, 100)
+	if next.FullSync || itemIDs(next.Updated) != "[5]" {
+		t.Errorf("全量同步后的增量同步 = %+v", next)
+	}
+}
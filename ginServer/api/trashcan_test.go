@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"template/config"
+	"template/ginServer/model"
+	"template/global"
+	Orm "template/initialize/orm"
+	"template/internal/modules/spatial"
+)
+
+// testAdminID 测试中拥有管理员权限的用户ID
+const testAdminID = 9
+
+// testUserHeader 测试请求中代替登录的请求头，值为用户ID
+const testUserHeader = "X-Test-User"
+
+// setupTestServer 使用内存数据库初始化全局配置、数据库和空间索引，返回不做鉴权的路由
+// 请求头 testUserHeader 指定的用户视为已登录，测试结束后恢复原有的全局变量
+func setupTestServer(tb testing.TB) *gin.Engine {
+	tb.Helper()
+	previousDB, previousConfig, previousLogger := global.DB, global.CONFIG, global.SugarLogger
+	tb.Cleanup(func() {
+		global.DB, global.CONFIG, global.SugarLogger = previousDB, previousConfig, previousLogger
+	})
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	// 内存数据库的每个连接都是独立的数据库
+	sqlDB, err := db.DB()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	global.DB = db
+	global.SugarLogger = zap.NewNop().Sugar()
+	global.CONFIG = config.System{
+		JWTConfig:    &config.JWTConfig{Secret: "test"},
+		AdminConfig:  &config.AdminConfig{UserIDs: []uint{testAdminID}},
+		UploadConfig: &config.UploadConfig{ImageDir: tb.TempDir()},
+	}
+	Orm.RegisterTables()
+	Orm.MigrateData()
+	if err := spatial.Init(); err != nil {
+		tb.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if header := c.GetHeader(testUserHeader); header != "" {
+			userID, err := strconv.ParseUint(header, 10, 64)
+			if err != nil {
+				c.AbortWithStatus(400)
+				return
+			}
+			c.Set("userID", uint(userID))
+		}
+		c.Next()
+	})
+	return r
+}
+
+// testResponse 统一格式的响应
+type testResponse struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+	Msg  string          `json:"msg"`
+}
+
+// doRequest 发送请求并解析统一格式的响应，userID 为0时不登录
+func doRequest(tb testing.TB, r *gin.Engine, method, target string, body io.Reader, contentType string, userID uint) testResponse {
+	tb.Helper()
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if userID != 0 {
+		req.Header.Set(testUserHeader, strconv.FormatUint(uint64(userID), 10))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		tb.Fatalf("%s %s 的响应不是JSON: %s", method, target, w.Body.String())
+	}
+	return resp
+}
+
+// doJSON 以JSON请求体发送请求
+func doJSON(tb testing.TB, r *gin.Engine, method, target string, body interface{}, userID uint) testResponse {
+	tb.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return doRequest(tb, r, method, target, bytes.NewReader(data), "application/json", userID)
+}
+
+// decodeData 解析响应中的数据，失败时终止测试
+func decodeData(tb testing.TB, resp testResponse, v interface{}) {
+	tb.Helper()
+	if resp.Code != 2000 {
+		tb.Fatalf("响应 = %d %s, want 2000", resp.Code, resp.Msg)
+	}
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		tb.Fatalf("解析响应数据失败: %v: %s", err, resp.Data)
+	}
+}
+
+// createTestTrashCans 批量创建垃圾桶，未指定状态的使用数据库默认值
+func createTestTrashCans(tb testing.TB, trashCans []model.TrashCan) {
+	tb.Helper()
+	if err := global.DB.CreateInBatches(&trashCans, 500).Error; err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// uintPtr 返回指向 v 的指针，用于设置垃圾桶的上传者
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestGetNearbyTrashCansCursor(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/trashcans/nearby", GetNearbyTrashCans)
+
+	// 25个垃圾桶，其中每5个与前一个距离相同，按（距离, ID）分页时不能重复或遗漏
+	trashCans := make([]model.TrashCan, 0, 25)
+	for i := 0; i < 25; i++ {
+		trashCans = append(trashCans, model.TrashCan{Latitude: 31.2304 + float64(i/5)*0.001, Longitude: 121.4737 + float64(i%5)*1e-9})
+	}
+	createTestTrashCans(t, trashCans)
+
+	type page struct {
+		List []struct {
+			ID uint `json:"id"`
+		} `json:"list"`
+		NextCursor string `json:"next_cursor"`
+		HasMore    bool   `json:"has_more"`
+	}
+	const base = "/trashcans/nearby?lat=31.2304&lng=121.4737&radius=1&coord_sys=gcj02"
+	seen := make(map[uint]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("分页没有结束")
+		}
+		var p page
+		decodeData(t, doRequest(t, r, "GET", base+"&limit=10&cursor="+url.QueryEscape(cursor), nil, "", 0), &p)
+		for _, item := range p.List {
+			if seen[item.ID] {
+				t.Errorf("垃圾桶 #%d 重复出现", item.ID)
+			}
+			seen[item.ID] = true
+		}
+		if !p.HasMore {
+			break
+		}
+		cursor = p.NextCursor
+		// 游标与其他查询参数不一致时拒绝，limit 可以改变
+		for _, changed := range []string{
+			"/trashcans/nearby?lat=31.2305&lng=121.4737&radius=1&coord_sys=gcj02",
+			"/trashcans/nearby?lat=31.2304&lng=121.4737&radius=1&coord_sys=wgs84",
+			base + "&q=abc",
+		} {
+			if resp := doRequest(t, r, "GET", changed+"&cursor="+url.QueryEscape(cursor), nil, "", 0); resp.Code != 4000 {
+				t.Errorf("修改参数后使用游标 %s: %d %s, want 4000", changed, resp.Code, resp.Msg)
+			}
+		}
+		if resp := doRequest(t, r, "GET", base+"&limit=5&cursor="+url.QueryEscape(cursor), nil, "", 0); resp.Code != 2000 {
+			t.Errorf("修改 limit 后使用游标: %d %s", resp.Code, resp.Msg)
+		}
+	}
+	if len(seen) != 25 {
+		t.Errorf("分页共返回 %d 个垃圾桶, want 25", len(seen))
+	}
+	if resp := doRequest(t, r, "GET", base+"&cursor=invalid", nil, "", 0); resp.Code != 4000 {
+		t.Errorf("无效的游标: %d %s, want 4000", resp.Code, resp.Msg)
+	}
+}
+
+func TestCreateTrashCanDuplicate(t *testing.T) {
+	r := setupTestServer(t)
+	r.POST("/trashcans", CreateTrashCan)
+
+	createTestTrashCans(t, []model.TrashCan{{ID: 1, Latitude: 31.2304, Longitude: 121.4737}})
+	create := func(lat, lng float64, force bool) testResponse {
+		t.Helper()
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="image"; filename="photo.jpg"`)
+		header.Set("Content-Type", "image/jpeg")
+		part, err := w.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte("jpeg"))
+		w.WriteField("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+		w.WriteField("longitude", strconv.FormatFloat(lng, 'f', -1, 64))
+		w.WriteField("force", strconv.FormatBool(force))
+		w.Close()
+		return doRequest(t, r, "POST", "/trashcans", &body, w.FormDataContentType(), 1)
+	}
+	uploaded := func() int {
+		entries, err := os.ReadDir(global.CONFIG.UploadConfig.ImageDir)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	// 可能重复时返回附近已有的垃圾桶，不保存图片
+	var duplicates struct {
+		Candidates []struct {
+			ID uint `json:"id"`
+		} `json:"candidates"`
+	}
+	resp := create(31.23041, 121.47371, false)
+	if resp.Code != 4002 {
+		t.Fatalf("可能重复时: %d %s, want 4002", resp.Code, resp.Msg)
+	}
+	if err := json.Unmarshal(resp.Data, &duplicates); err != nil || len(duplicates.Candidates) != 1 || duplicates.Candidates[0].ID != 1 {
+		t.Errorf("重复的垃圾桶 = %s", resp.Data)
+	}
+	if n := uploaded(); n != 0 {
+		t.Errorf("提示重复后保存了 %d 张图片", n)
+	}
+
+	var created struct {
+		ID          uint   `json:"id"`
+		DuplicateOf []uint `json:"duplicate_of"`
+	}
+	decodeData(t, create(31.23041, 121.47371, true), &created)
+	if created.ID == 0 || fmt.Sprint(created.DuplicateOf) != "[1]" {
+		t.Errorf("强制创建 = %+v", created)
+	}
+	if n := uploaded(); n != 1 {
+		t.Errorf("创建后有 %d 张图片, want 1", n)
+	}
+}
+
+// BenchmarkGetNearbyTrashCans 附近搜索接口的性能测试，在上海市中心约50公里范围内随机生成垃圾桶
+func BenchmarkGetNearbyTrashCans(b *testing.B) {
+	const total = 20000
+	centerLat, centerLng := 31.2304, 121.4737
+	rng := rand.New(rand.NewSource(1))
+	r := setupTestServer(b)
+	r.GET("/trashcans/nearby", GetNearbyTrashCans)
+
+	trashCans := make([]model.TrashCan, 0, total)
+	for i := 0; i < total; i++ {
+		trashCans = append(trashCans, model.TrashCan{
+			Latitude:  centerLat + (rng.Float64()-0.5)*0.9,
+			Longitude: centerLng + (rng.Float64()-0.5)*1.0,
+			Address:   fmt.Sprintf("测试地址 %d", i),
+		})
+	}
+	createTestTrashCans(b, trashCans)
+
+	for _, radius := range []float64{0.5, 1, 5} {
+		b.Run(fmt.Sprintf("radius=%gkm", radius), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lat := centerLat + (rng.Float64()-0.5)*0.4
+				lng := centerLng + (rng.Float64()-0.5)*0.4
+				target := fmt.Sprintf("/trashcans/nearby?lat=%f&lng=%f&radius=%g", lat, lng, radius)
+				if resp := doRequest(b, r, "GET", target, nil, "", 0); resp.Code != 2000 {
+					b.Fatalf("响应 = %d %s", resp.Code, resp.Msg)
+				}
+			}
+		})
+	}
+}
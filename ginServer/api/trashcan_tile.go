@@ -0,0 +1,247 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/ginServer/model"
+	"template/global"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+const (
+	mvtLayerName   = "trashcans"
+	mvtContentType = "application/vnd.mapbox-vector-tile"
+	mvtMaxZoom     = 22
+	mvtBuffer      = 64 // 瓦片边缘的缓冲区（瓦片坐标单位），避免图标在瓦片边界被截断
+	mvtCellSize    = 4  // 同一瓦片内落在同一网格（瓦片坐标单位）中的垃圾桶合并为一个要素
+	mvtMaxAge      = 60 // 客户端缓存时间（秒），过期后用 ETag 重新验证
+)
+
+// mvtCoordSystems 矢量瓦片支持的坐标系，垃圾桶位置变化时需要清除每种坐标系下的瓦片缓存
+var mvtCoordSystems = []utils.CoordSys{utils.CoordWGS84, utils.CoordGCJ02, utils.CoordBD09}
+
+// mvtKey 矢量瓦片缓存键
+type mvtKey struct {
+	tileKey
+	CoordSys utils.CoordSys
+}
+
+// mvtTile 已编码的矢量瓦片
+type mvtTile struct {
+	Data []byte
+	ETag string
+}
+
+// mvtCache 矢量瓦片缓存，垃圾桶位置、点赞数、封面图片等变化时清除受影响的瓦片
+var mvtCache = utils.NewTTLCache[mvtKey, mvtTile](5*time.Minute, 20000)
+
+func init() {
+	spatial.TrashCanIndex.OnChange(invalidateMVTTiles)
+}
+
+// invalidateMVTTiles 清除变更前后位置所在（含缓冲区）的全部矢量瓦片缓存
+func invalidateMVTTiles(old, new *spatial.Point) {
+	if old == nil && new == nil {
+		mvtCache.Clear()
+		return
+	}
+	buffer := float64(mvtBuffer) * utils.TileSize / utils.MVTExtent
+	for _, p := range []*spatial.Point{old, new} {
+		if p == nil {
+			continue
+		}
+		for _, coordSys := range mvtCoordSystems {
+			lat, lng := utils.FromCanonicalCoord(p.Lat, p.Lng, coordSys)
+			for z := 0; z <= mvtMaxZoom; z++ {
+				n := 1 << z
+				px, py := utils.LngLatToPixel(lat, lng, z)
+				y0 := max(int(math.Floor((py-buffer)/utils.TileSize)), 0)
+				y1 := min(int(math.Floor((py+buffer)/utils.TileSize)), n-1)
+				x0 := int(math.Floor((px - buffer) / utils.TileSize))
+				x1 := int(math.Floor((px + buffer) / utils.TileSize))
+				for x := x0; x <= x1; x++ {
+					for y := y0; y <= y1; y++ {
+						mvtCache.Delete(mvtKey{tileKey{Z: z, X: (x%n + n) % n, Y: y}, coordSys})
+					}
+				}
+			}
+		}
+	}
+}
+
+// GetTrashCanTile 以 Mapbox Vector Tile 格式返回瓦片内的垃圾桶，可直接作为地图库的矢量瓦片数据源
+// GET /tiles/{z}/{x}/{y}.mvt?coord_sys=wgs84
+// 图层名为 trashcans，要素属性：id、like_count、dislike_count、has_image、count（合并的垃圾桶数量）
+// coord_sys 为底图使用的坐标系，默认wgs84；使用高德等GCJ-02底图时传 gcj02
+func GetTrashCanTile(c *gin.Context) {
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	yStr, ok := strings.CutSuffix(c.Param("y"), ".mvt")
+	y, errY := strconv.Atoi(yStr)
+	if !ok || errZ != nil || errX != nil || errY != nil ||
+		z < 0 || z > mvtMaxZoom || x < 0 || x >= 1<<z || y < 0 || y >= 1<<z {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	coordSys := utils.CoordWGS84
+	if value := c.Query("coord_sys"); value != "" {
+		if coordSys, ok = utils.ParseCoordSys(value); !ok {
+			common.ParamErrorWithMessage("coord_sys 只支持 wgs84、gcj02、bd09", c)
+			return
+		}
+	}
+
+	key := mvtKey{tileKey{Z: z, X: x, Y: y}, coordSys}
+	tile, ok := mvtCache.Get(key)
+	if !ok {
+		var err error
+		if tile, err = buildMVTTile(key); err != nil {
+			global.SugarLogger.Errorf("生成矢量瓦片失败: %v", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		mvtCache.Set(key, tile)
+	}
+
+	c.Header("ETag", tile.ETag)
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(mvtMaxAge))
+	if etagMatches(c.GetHeader("If-None-Match"), tile.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, mvtContentType, tile.Data)
+}
+
+// etagMatches 判断 If-None-Match 是否包含指定的 ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildMVTTile 查询瓦片范围（含缓冲区）内的垃圾桶并编码为矢量瓦片
+func buildMVTTile(key mvtKey) (mvtTile, error) {
+	// 瓦片范围向外扩展缓冲区，再转换为统一存储的坐标系；坐标系之间的偏移不是均匀的，多扩展1公里以免遗漏
+	n := float64(int(1) << key.Z)
+	margin := float64(mvtBuffer) / utils.MVTExtent
+	tileLat := func(y float64) float64 {
+		y = math.Max(0, math.Min(n, y))
+		return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180.0 / math.Pi
+	}
+	tileLng := func(x float64) float64 {
+		return utils.NormalizeLng(x/n*360.0 - 180.0)
+	}
+	box := utils.BoundingBox{
+		MinLat: tileLat(float64(key.Y) + 1 + margin),
+		MaxLat: tileLat(float64(key.Y) - margin),
+		MinLng: tileLng(float64(key.X) - margin),
+		MaxLng: tileLng(float64(key.X) + 1 + margin),
+	}
+	if key.Z == 0 {
+		box.MinLng, box.MaxLng = -180, 180
+	}
+	box.MinLat, box.MinLng = utils.ToCanonicalCoord(box.MinLat, box.MinLng, key.CoordSys)
+	box.MaxLat, box.MaxLng = utils.ToCanonicalCoord(box.MaxLat, box.MaxLng, key.CoordSys)
+	box = box.Expand(1)
+
+	var points []struct {
+		ID           uint
+		Latitude     float64
+		Longitude    float64
+		HasImage     bool
+		LikeCount    int64
+		DislikeCount int64
+	}
+	likeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", 1)
+	dislikeQuery := global.DB.Model(&model.TrashCanLike{}).
+		Select("COUNT(*)").Where("trash_can_id = trash_cans.id AND type = ?", -1)
+	if err := global.DB.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(box), model.PublicStatus).
+		Select("id, latitude, longitude, COALESCE(image_path, '') <> '' AS has_image, (?) AS like_count, (?) AS dislike_count",
+			likeQuery, dislikeQuery).
+		Order("id").
+		Find(&points).Error; err != nil {
+		return mvtTile{}, err
+	}
+
+	// 相同网格中的垃圾桶在地图上无法区分，合并为一个要素以限制低缩放级别下瓦片的大小
+	type feature struct {
+		id           uint
+		x, y         int
+		count        int
+		hasImage     bool
+		likeCount    int64
+		dislikeCount int64
+	}
+	cells := make(map[[2]int]*feature)
+	var features []*feature
+	scale := float64(utils.MVTExtent) / utils.TileSize
+	worldSize := n * utils.TileSize
+	for _, p := range points {
+		lat, lng := utils.FromCanonicalCoord(p.Latitude, p.Longitude, key.CoordSys)
+		px, py := utils.LngLatToPixel(lat, lng, key.Z)
+		dx := px - float64(key.X)*utils.TileSize
+		// 跨越180度经线的缓冲区
+		if dx > worldSize/2 {
+			dx -= worldSize
+		} else if dx < -worldSize/2 {
+			dx += worldSize
+		}
+		tx := int(math.Floor(dx * scale))
+		ty := int(math.Floor((py - float64(key.Y)*utils.TileSize) * scale))
+		if tx < -mvtBuffer || tx > utils.MVTExtent+mvtBuffer || ty < -mvtBuffer || ty > utils.MVTExtent+mvtBuffer {
+			continue
+		}
+
+		cell := [2]int{floorDiv(tx, mvtCellSize), floorDiv(ty, mvtCellSize)}
+		if f, ok := cells[cell]; ok {
+			f.count++
+			f.hasImage = f.hasImage || p.HasImage
+			f.likeCount += p.LikeCount
+			f.dislikeCount += p.DislikeCount
+			continue
+		}
+		f := &feature{id: p.ID, x: tx, y: ty, count: 1, hasImage: p.HasImage, likeCount: p.LikeCount, dislikeCount: p.DislikeCount}
+		cells[cell] = f
+		features = append(features, f)
+	}
+
+	layer := utils.NewMVTLayer(mvtLayerName)
+	for _, f := range features {
+		layer.AddPoint(uint64(f.id), f.x, f.y,
+			utils.MVTProperty{Key: "id", Value: f.id},
+			utils.MVTProperty{Key: "count", Value: f.count},
+			utils.MVTProperty{Key: "has_image", Value: f.hasImage},
+			utils.MVTProperty{Key: "like_count", Value: f.likeCount},
+			utils.MVTProperty{Key: "dislike_count", Value: f.dislikeCount},
+		)
+	}
+	data := utils.EncodeMVT(layer)
+	sum := sha256.Sum256(data)
+	return mvtTile{Data: data, ETag: `"` + hex.EncodeToString(sum[:8]) + `"`}, nil
+}
+
+// floorDiv 向下取整的整数除法
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"template/ginServer/model"
+	"template/global"
+	"template/utils"
+)
+
+func TestGetTrashCanTileCache(t *testing.T) {
+	r := setupTestServer(t)
+	r.GET("/tiles/:z/:x/:y", GetTrashCanTile)
+
+	createTestTrashCans(t, []model.TrashCan{{ID: 1, Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive}})
+	const z = 14
+	px, py := utils.LngLatToPixel(31.2304, 121.4737, z)
+	target := fmt.Sprintf("/tiles/%d/%d/%d.mvt?coord_sys=gcj02", z, int(px/utils.TileSize), int(py/utils.TileSize))
+	etag := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != 200 {
+			t.Fatalf("GET %s = %d", target, w.Code)
+		}
+		return w.Header().Get("ETag")
+	}
+
+	// 点赞点踩、封面和状态的变化会改变瓦片内容，需要清除缓存
+	seen := map[string]string{etag(): "初始"}
+	for _, change := range []struct {
+		name  string
+		apply func() error
+	}{
+		{"点赞", func() error { return global.DB.Create(&model.TrashCanLike{UserID: 1, TrashCanID: 1, Type: 1}).Error }},
+		{"设置封面", func() error { return global.DB.Model(&model.TrashCan{ID: 1}).Update("image_path", "a.jpg").Error }},
+		{"撤除", func() error {
+			return global.DB.Model(&model.TrashCan{ID: 1}).Update("status", model.StatusRemoved).Error
+		}},
+	} {
+		if err := change.apply(); err != nil {
+			t.Fatal(err)
+		}
+		tag := etag()
+		if previous, ok := seen[tag]; ok {
+			t.Errorf("%s后瓦片的 ETag 与%s后相同", change.name, previous)
+		}
+		seen[tag] = change.name
+	}
+}
@@ -0,0 +1,39 @@
+package middle
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"template/ginServer/api/common"
+	"template/global"
+)
+
+// AdminAuth 管理员权限中间件，需要在 JWTAuth 之后使用
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			common.FailWithAuthority(c)
+			c.Abort()
+			return
+		}
+		if !IsAdmin(userID.(uint)) {
+			common.FailWithForbidden(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin 判断用户是否为管理员（在 config.yml 的 admin.user_ids 中配置）
+func IsAdmin(userID uint) bool {
+	if global.CONFIG.AdminConfig == nil {
+		return false
+	}
+	for _, id := range global.CONFIG.AdminConfig.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
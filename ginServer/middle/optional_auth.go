@@ -0,0 +1,19 @@
+package middle
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// OptionalJWTAuth 可选的登录认证中间件，用于公开接口：
+// 请求带有 Authorization 头时与 JWTAuth 相同（令牌无效时拒绝请求），否则作为未登录用户继续处理
+// 处理函数可以通过 c.Get("userID") 是否存在区分登录用户，例如上传者可以查询到自己待审核的垃圾桶
+func OptionalJWTAuth() gin.HandlerFunc {
+	auth := JWTAuth()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
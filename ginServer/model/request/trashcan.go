@@ -0,0 +1,95 @@
+package request
+
+import "time"
+
+// TrashCanFilter 垃圾桶列表的通用筛选条件
+type TrashCanFilter struct {
+	HasImage     *bool      `form:"has_image"`                                      // 是否有图片
+	MinLikeRatio *float64   `form:"min_like_ratio" binding:"omitempty,gte=0,lte=1"` // 最低点赞率 likes/(likes+dislikes)
+	CreatedFrom  *time.Time `form:"created_from" time_format:"2006-01-02"`          // 创建日期起（含）
+	CreatedTo    *time.Time `form:"created_to" time_format:"2006-01-02"`            // 创建日期止（含）
+	UploaderID   *uint      `form:"uploader_id" binding:"omitempty,gt=0"`           // 上传者用户ID
+	Q            string     `form:"q" binding:"max=100"`                            // 地址或描述包含的关键字
+	Category     string     `form:"category" binding:"max=100"`                     // 垃圾分类，多个用逗号分隔时需同时支持全部分类
+	Status       string     `form:"status" binding:"max=100"`                       // 生命周期状态，多个用逗号分隔，all 表示全部，默认只含公开的（active、reported_missing）
+}
+
+// NearbyTrashCanQuery 附近垃圾桶查询参数
+type NearbyTrashCanQuery struct {
+	Lat      *float64 `form:"lat" binding:"required,gte=-90,lte=90"`
+	Lng      *float64 `form:"lng" binding:"required,gte=-180,lte=180"`
+	Radius   float64  `form:"radius,default=5" binding:"gt=0"`          // 搜索半径（公里）
+	Limit    int      `form:"limit,default=10" binding:"gte=1,lte=100"` // 每页数量
+	CoordSys string   `form:"coord_sys"`
+	Cursor   string   `form:"cursor"`
+	Sort     string   `form:"sort,default=distance" binding:"oneof=distance score"` // 排序方式
+	TrashCanFilter
+}
+
+// TrashCanTextSearchQuery 垃圾桶全文搜索参数
+type TrashCanTextSearchQuery struct {
+	Q        string   `form:"q" binding:"required,max=100"`           // 搜索关键字，多个关键字用空格分隔
+	Lat      *float64 `form:"lat" binding:"omitempty,gte=-90,lte=90"` // 可选，与 lng 同时提供时离该位置越近排名越靠前
+	Lng      *float64 `form:"lng" binding:"omitempty,gte=-180,lte=180"`
+	Page     int      `form:"page,default=1" binding:"gte=1"`
+	PageSize int      `form:"page_size,default=10" binding:"gte=1,lte=100"`
+	CoordSys string   `form:"coord_sys"`
+}
+
+// TrashCanExportQuery 导出垃圾桶数据的查询参数
+// 矩形范围使用与视野查询相同的 sw_lat、sw_lng、ne_lat、ne_lng 参数，由 parseBoundingBox 解析
+type TrashCanExportQuery struct {
+	Polygon  string `form:"polygon"`                 // 区域范围，GeoJSON Polygon/MultiPolygon 或 Feature
+	CoordSys string `form:"coord_sys,default=wgs84"` // 导出文件使用的坐标系，GIS软件一般使用WGS84
+	TrashCanFilter
+}
+
+// MergeTrashCansRequest 合并垃圾桶请求
+type MergeTrashCansRequest struct {
+	SurvivorID   uint   `json:"survivor_id" binding:"required"`                          // 合并后保留的垃圾桶
+	MergeIDs     []uint `json:"merge_ids" binding:"required,min=1,max=50,dive,required"` // 合并到保留垃圾桶后删除的垃圾桶
+	Coordinate   string `json:"coordinate" binding:"omitempty,oneof=survivor average"`   // 坐标取值：survivor 使用保留垃圾桶的坐标，average 取平均值
+	CoordinateID uint   `json:"coordinate_id"`                                           // 使用指定垃圾桶的坐标，不能与 coordinate=average 同时使用
+	ImageID      uint   `json:"image_id"`                                                // 使用指定垃圾桶的图片，默认选择评价最好的
+}
+
+// TrashCanHeatmapQuery 垃圾桶密度热力图参数
+// 范围使用与视野查询相同的 sw_lat、sw_lng、ne_lat、ne_lng 参数，由 parseBoundingBox 解析
+type TrashCanHeatmapQuery struct {
+	Resolution int    `form:"resolution" binding:"required,gte=1,lte=24"`   // 分辨率，网格边长为 resolution 级瓦片的大小
+	Layout     string `form:"layout,default=grid" binding:"oneof=grid hex"` // 网格形状：grid 正方形，hex 六边形
+	Votes      bool   `form:"votes"`                                        // 是否返回每个网格的点赞、点踩合计
+	Categories bool   `form:"categories"`                                   // 是否返回每个网格中各垃圾分类的垃圾桶数量
+}
+
+// TrashCanCoverageQuery 覆盖缺口分析参数
+// 范围使用与视野查询相同的 sw_lat、sw_lng、ne_lat、ne_lng 参数，由 parseBoundingBox 解析
+type TrashCanCoverageQuery struct {
+	Threshold float64 `form:"threshold,default=300" binding:"gt=0,lte=10000"` // 阈值距离（米）
+	Step      float64 `form:"step" binding:"omitempty,gte=10"`                // 采样间距（米），默认为阈值的一半
+	Limit     int     `form:"limit,default=20" binding:"gte=1,lte=100"`       // 最多返回的缺口区域数量
+	Cells     bool    `form:"cells"`                                          // 是否返回每个区域包含的采样网格
+}
+
+// TrashCanChangesQuery 离线增量同步参数
+type TrashCanChangesQuery struct {
+	Since    string `form:"since"`                                      // 上次同步返回的 token，为空时全量同步
+	Limit    int    `form:"limit,default=500" binding:"gte=1,lte=1000"` // 每页处理的变更（全量同步时为垃圾桶）数量
+	CoordSys string `form:"coord_sys"`
+}
+
+// UpdateTrashCanStatusRequest 变更垃圾桶状态的请求
+type UpdateTrashCanStatusRequest struct {
+	Status string `json:"status" binding:"required"` // 新状态
+	Reason string `json:"reason" binding:"max=200"`  // 变更原因
+}
+
+// ReportTrashCanMissingRequest 报告垃圾桶已不存在的请求
+type ReportTrashCanMissingRequest struct {
+	Reason string `json:"reason" binding:"max=200"` // 说明，如"已被物业撤走"
+}
+
+// ReorderTrashCanImagesRequest 调整垃圾桶图库顺序的请求
+type ReorderTrashCanImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1,dive,required"` // 按新顺序排列的全部图片ID
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template/utils"
+)
+
+// geohashMaxCells 使用geohash前缀查询时最多覆盖的单元格数量
+const geohashMaxCells = 16
+
+// InBoundingBox 按矩形范围筛选垃圾桶
+// 先用geohash前缀区间命中索引选出候选单元格，再用经纬度精确过滤
+func InBoundingBox(box utils.BoundingBox) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		ranges := utils.GeohashRanges(utils.GeohashCover(box, geohashMaxCells))
+		if len(ranges) > 0 {
+			conditions := make([]string, 0, len(ranges))
+			args := make([]interface{}, 0, len(ranges)*2)
+			for _, r := range ranges {
+				conditions = append(conditions, "(geohash >= ? AND geohash < ?)")
+				args = append(args, r.Start, r.End)
+			}
+			db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+		}
+
+		db = db.Where("latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+		if box.CrossesAntimeridian() {
+			return db.Where("(longitude >= ? OR longitude <= ?)", box.MinLng, box.MaxLng)
+		}
+		return db.Where("longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+}
+
+// HasImage 按是否有图片筛选垃圾桶
+func HasImage(hasImage bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if hasImage {
+			return db.Where("image_path <> ''")
+		}
+		return db.Where("(image_path = '' OR image_path IS NULL)")
+	}
+}
+
+// MinLikeRatio 筛选点赞率 likes/(likes+dislikes) 不低于 ratio 的垃圾桶，没有投票的垃圾桶不满足条件
+func MinLikeRatio(ratio float64) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		votes := db.Session(&gorm.Session{NewDB: true}).
+			Model(&TrashCanLike{}).
+			Select("trash_can_id").
+			Group("trash_can_id").
+			Having("SUM(CASE WHEN type = 1 THEN 1 ELSE 0 END) >= ? * COUNT(*)", ratio)
+		return db.Where("id IN (?)", votes)
+	}
+}
+
+// CreatedBetween 按创建时间筛选垃圾桶，from 为零值时不限制起始时间，to 为零值时不限制结束时间（不含 to）
+func CreatedBetween(from, to time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			db = db.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			db = db.Where("created_at < ?", to)
+		}
+		return db
+	}
+}
+
+// UploadedBy 按上传者筛选垃圾桶
+func UploadedBy(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// MatchText 筛选地址或描述中包含关键字的垃圾桶
+func MatchText(q string) func(db *gorm.DB) *gorm.DB {
+	pattern := "%" + EscapeLike(q) + "%"
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`(address LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+}
+
+// likeEscaper 转义 LIKE 中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike 转义 LIKE 模式中的通配符，需配合 ESCAPE '\' 使用
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -1,21 +1,81 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"template/utils"
+)
 
 // TrashCan 垃圾桶模型
 type TrashCan struct {
-	ID          uint      `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
-	UserID      *uint     `json:"user_id" gorm:"index"` // 可为NULL以兼容现有数据
-	Latitude    float64   `json:"latitude" gorm:"type:REAL;not null"`
-	Longitude   float64   `json:"longitude" gorm:"type:REAL;not null"`
-	Address     string    `json:"address" gorm:"type:TEXT"`
-	Description string    `json:"description" gorm:"type:TEXT"`
-	ImagePath   string    `json:"image_path" gorm:"type:TEXT"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID             uint               `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
+	UserID         *uint              `json:"user_id" gorm:"index"` // 可为NULL以兼容现有数据
+	Latitude       float64            `json:"latitude" gorm:"type:REAL;not null;index:idx_trash_cans_lat_lng,priority:1"`
+	Longitude      float64            `json:"longitude" gorm:"type:REAL;not null;index:idx_trash_cans_lat_lng,priority:2"`
+	Geohash        string             `json:"geohash" gorm:"type:VARCHAR(12);index"`                 // 由经纬度自动计算，可用于空间查询、缓存键和分片
+	SourceCoordSys string             `json:"source_coord_sys" gorm:"type:VARCHAR(8);default:gcj02"` // 上传时客户端使用的坐标系，经纬度统一转换为GCJ-02存储
+	Address        string             `json:"address" gorm:"type:TEXT"`
+	Description    string             `json:"description" gorm:"type:TEXT"`
+	ImagePath      string             `json:"image_path" gorm:"type:TEXT"`
+	Categories     TrashCanCategories `json:"categories" gorm:"not null;default:0"`                         // 支持投放的垃圾分类，已有数据迁移后为0（未知）
+	Status         TrashCanStatus     `json:"status" gorm:"type:VARCHAR(20);not null;default:active;index"` // 生命周期状态，已有数据迁移后为 active
+	CreatedAt      time.Time          `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time          `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // TableName 指定表名
 func (TrashCan) TableName() string {
 	return "trash_cans"
 }
+
+// TrashCanSearchTable 垃圾桶地址和描述的FTS5全文索引表，由触发器与 trash_cans 表保持同步
+const TrashCanSearchTable = "trash_cans_fts"
+
+// BeforeCreate 创建前根据经纬度计算geohash，批量创建时会对每条记录分别调用
+func (t *TrashCan) BeforeCreate(tx *gorm.DB) error {
+	t.Geohash = utils.GeohashEncode(t.Latitude, t.Longitude, utils.GeohashMaxPrecision)
+	return nil
+}
+
+// BeforeUpdate 更新了经纬度时同步更新geohash
+func (t *TrashCan) BeforeUpdate(tx *gorm.DB) error {
+	lat, lng := t.Latitude, t.Longitude
+
+	dest := tx.Statement.Dest
+	if v, ok := dest.(TrashCan); ok {
+		dest = &v
+	}
+	switch dest := dest.(type) {
+	case map[string]interface{}:
+		// Updates(map) 只有在更新经纬度时才需要重新计算
+		latValue, hasLat := dest["latitude"]
+		lngValue, hasLng := dest["longitude"]
+		if !hasLat && !hasLng {
+			return nil
+		}
+		if v, ok := latValue.(float64); ok {
+			lat = v
+		}
+		if v, ok := lngValue.(float64); ok {
+			lng = v
+		}
+	case *TrashCan:
+		// Save 或 Updates(struct)，只采用非零值
+		if dest != t && dest.Latitude == 0 && dest.Longitude == 0 {
+			return nil
+		}
+		if dest.Latitude != 0 {
+			lat = dest.Latitude
+		}
+		if dest.Longitude != 0 {
+			lng = dest.Longitude
+		}
+	default:
+		return nil
+	}
+
+	tx.Statement.SetColumn("geohash", utils.GeohashEncode(lat, lng, utils.GeohashMaxPrecision))
+	return nil
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TrashCanCategories 垃圾桶支持投放的垃圾分类，按上海的四分类标准，一个垃圾桶可以有多个分类，按位存储
+// 为0时表示分类未知，添加该字段之前的垃圾桶均为未知
+type TrashCanCategories uint8
+
+const (
+	CategoryRecyclable TrashCanCategories = 1 << iota // 可回收物
+	CategoryHazardous                                 // 有害垃圾
+	CategoryWet                                       // 湿垃圾（厨余垃圾）
+	CategoryResidual                                  // 干垃圾（其他垃圾）
+
+	CategoryUnknown TrashCanCategories = 0 // 分类未知
+)
+
+// CategoryUnknownName 分类未知时使用的名称
+const CategoryUnknownName = "unknown"
+
+// TrashCanCategoryNames 全部分类按顺序对应的名称
+var TrashCanCategoryNames = []struct {
+	Category TrashCanCategories
+	Name     string
+}{
+	{CategoryRecyclable, "recyclable"},
+	{CategoryHazardous, "hazardous"},
+	{CategoryWet, "wet"},
+	{CategoryResidual, "residual"},
+}
+
+// categoryAliases 解析时接受的其他名称
+var categoryAliases = map[string]TrashCanCategories{
+	"kitchen": CategoryWet,
+	"food":    CategoryWet,
+	"dry":     CategoryResidual,
+	"other":   CategoryResidual,
+	"可回收物":    CategoryRecyclable,
+	"有害垃圾":    CategoryHazardous,
+	"湿垃圾":     CategoryWet,
+	"厨余垃圾":    CategoryWet,
+	"干垃圾":     CategoryResidual,
+	"其他垃圾":    CategoryResidual,
+}
+
+// ParseTrashCanCategories 解析逗号分隔的分类名称，如 "recyclable,wet"；空字符串或 unknown 表示分类未知
+func ParseTrashCanCategories(s string) (TrashCanCategories, error) {
+	var categories TrashCanCategories
+	unknown := false
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == CategoryUnknownName {
+			unknown = true
+			continue
+		}
+		category, ok := categoryAliases[name]
+		for _, c := range TrashCanCategoryNames {
+			if c.Name == name {
+				category, ok = c.Category, true
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("未知的垃圾分类: %s，只支持 recyclable、hazardous、wet、residual、unknown", name)
+		}
+		categories |= category
+	}
+	if unknown && categories != CategoryUnknown {
+		return 0, fmt.Errorf("unknown 不能与其他垃圾分类同时使用")
+	}
+	return categories, nil
+}
+
+// Names 返回分类名称列表，分类未知时为 ["unknown"]
+func (c TrashCanCategories) Names() []string {
+	names := make([]string, 0, len(TrashCanCategoryNames))
+	for _, item := range TrashCanCategoryNames {
+		if c&item.Category != 0 {
+			names = append(names, item.Name)
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, CategoryUnknownName)
+	}
+	return names
+}
+
+// String 返回逗号分隔的分类名称
+func (c TrashCanCategories) String() string {
+	return strings.Join(c.Names(), ",")
+}
+
+// MarshalJSON 序列化为分类名称数组
+func (c TrashCanCategories) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Names())
+}
+
+// UnmarshalJSON 从分类名称数组解析
+func (c *TrashCanCategories) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	categories, err := ParseTrashCanCategories(strings.Join(names, ","))
+	if err != nil {
+		return err
+	}
+	*c = categories
+	return nil
+}
+
+// HasCategories 筛选支持全部指定分类的垃圾桶，categories 为 CategoryUnknown 时筛选分类未知的垃圾桶
+func HasCategories(categories TrashCanCategories) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if categories == CategoryUnknown {
+			return db.Where("categories = 0")
+		}
+		return db.Where("(categories & ?) = ?", uint8(categories), uint8(categories))
+	}
+}
+
+// TrashCanCategoryCounts 各分类的垃圾桶数量，一个垃圾桶有多个分类时在每个分类中都会计数
+type TrashCanCategoryCounts struct {
+	Recyclable int64 `json:"recyclable"`
+	Hazardous  int64 `json:"hazardous"`
+	Wet        int64 `json:"wet"`
+	Residual   int64 `json:"residual"`
+	Unknown    int64 `json:"unknown"`
+}
+
+// CategoryCountsSelect 在聚合查询中统计各分类数量的 SELECT 表达式，列名与 TrashCanCategoryCounts 的字段对应
+func CategoryCountsSelect(column string) string {
+	return fmt.Sprintf("SUM((%[1]s & %[2]d) <> 0) AS recyclable, SUM((%[1]s & %[3]d) <> 0) AS hazardous, "+
+		"SUM((%[1]s & %[4]d) <> 0) AS wet, SUM((%[1]s & %[5]d) <> 0) AS residual, SUM(%[1]s = 0) AS unknown",
+		column, CategoryRecyclable, CategoryHazardous, CategoryWet, CategoryResidual)
+}
+
+// Add 计入一个垃圾桶的分类
+func (c *TrashCanCategoryCounts) Add(categories TrashCanCategories) {
+	if categories == CategoryUnknown {
+		c.Unknown++
+		return
+	}
+	if categories&CategoryRecyclable != 0 {
+		c.Recyclable++
+	}
+	if categories&CategoryHazardous != 0 {
+		c.Hazardous++
+	}
+	if categories&CategoryWet != 0 {
+		c.Wet++
+	}
+	if categories&CategoryResidual != 0 {
+		c.Residual++
+	}
+}
+
+// Merge 合并另一组统计
+func (c *TrashCanCategoryCounts) Merge(other TrashCanCategoryCounts) {
+	c.Recyclable += other.Recyclable
+	c.Hazardous += other.Hazardous
+	c.Wet += other.Wet
+	c.Residual += other.Residual
+	c.Unknown += other.Unknown
+}
+
+// Values 按 recyclable、hazardous、wet、residual、unknown 的顺序返回各分类数量
+func (c TrashCanCategoryCounts) Values() []int64 {
+	return []int64{c.Recyclable, c.Hazardous, c.Wet, c.Residual, c.Unknown}
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// 垃圾桶变更类型
+const (
+	TrashCanChangeCreated = "created"
+	TrashCanChangeUpdated = "updated"
+	TrashCanChangeDeleted = "deleted"
+)
+
+// TrashCanChange 垃圾桶变更日志，由数据库触发器在垃圾桶及其点赞点踩增删改时写入，与变更本身在同一事务中提交
+// 删除的垃圾桶只在这里留下记录（墓碑），离线客户端据此删除本地缓存
+type TrashCanChange struct {
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement"` // 单调递增，作为增量同步的位置
+	TrashCanID uint      `json:"trash_can_id" gorm:"not null;index"`
+	Op         string    `json:"op" gorm:"type:VARCHAR(8);not null"` // created、updated、deleted
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (TrashCanChange) TableName() string {
+	return "trash_can_changes"
+}
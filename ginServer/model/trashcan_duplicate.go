@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// TrashCanDuplicate 用户确认后强制创建的疑似重复垃圾桶，供管理员审核
+// 每个创建时附近已存在的垃圾桶各记录一条
+type TrashCanDuplicate struct {
+	ID            uint      `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
+	TrashCanID    uint      `json:"trash_can_id" gorm:"not null;index"`    // 强制创建的垃圾桶
+	DuplicateOfID uint      `json:"duplicate_of_id" gorm:"not null;index"` // 创建时附近已存在的垃圾桶
+	Distance      float64   `json:"distance"`                              // 两者之间的距离（米）
+	UserID        uint      `json:"user_id" gorm:"index"`                  // 强制创建的用户
+	Reviewed      bool      `json:"reviewed" gorm:"default:false;index"`   // 是否已审核
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TrashCanDuplicate) TableName() string {
+	return "trash_can_duplicates"
+}
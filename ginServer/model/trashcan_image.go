@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// TrashCanImage 垃圾桶图库中的一张图片，任何用户都可以为垃圾桶添加图片
+// 每个有图片的垃圾桶恰好有一张封面，封面的路径同时保存在 TrashCan.ImagePath 中，供列表、筛选和矢量瓦片直接使用
+type TrashCanImage struct {
+	ID         uint      `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
+	TrashCanID uint      `json:"trash_can_id" gorm:"not null;index:idx_trash_can_images_order,priority:1"`
+	UserID     *uint     `json:"user_id" gorm:"index"` // 上传者，迁移的旧图片为垃圾桶的上传者
+	ImagePath  string    `json:"-" gorm:"type:TEXT;not null"`
+	Caption    string    `json:"caption" gorm:"type:VARCHAR(200)"`
+	SortOrder  int       `json:"sort_order" gorm:"not null;default:0;index:idx_trash_can_images_order,priority:2"` // 图库中的顺序，从小到大排列
+	IsCover    bool      `json:"is_cover" gorm:"not null;default:false"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TrashCanImage) TableName() string {
+	return "trash_can_images"
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// TrashCanRedirect 合并后被删除的垃圾桶ID指向保留的垃圾桶，使旧ID仍能查询到详情
+type TrashCanRedirect struct {
+	FromID    uint      `json:"from_id" gorm:"primaryKey;autoIncrement:false"` // 被合并删除的垃圾桶ID
+	ToID      uint      `json:"to_id" gorm:"not null;index"`                   // 合并后保留的垃圾桶ID
+	MergedBy  uint      `json:"merged_by"`                                     // 执行合并的管理员
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TrashCanRedirect) TableName() string {
+	return "trash_can_redirects"
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashCanStatus 垃圾桶的生命周期状态
+// 正常流转为 pending_review → active → reported_missing → removed，允许的变更及操作者见 trashCanStatusTransitions
+type TrashCanStatus string
+
+const (
+	StatusPendingReview   TrashCanStatus = "pending_review"   // 待审核，用户新上传的垃圾桶
+	StatusActive          TrashCanStatus = "active"           // 正常，添加状态之前的垃圾桶均为正常
+	StatusReportedMissing TrashCanStatus = "reported_missing" // 多位用户报告已不存在，等待管理员确认
+	StatusRemoved         TrashCanStatus = "removed"          // 已撤除，附近搜索默认不再返回
+)
+
+// TrashCanStatuses 全部状态，按生命周期的顺序排列
+var TrashCanStatuses = []TrashCanStatus{StatusPendingReview, StatusActive, StatusReportedMissing, StatusRemoved}
+
+// PublicStatuses 公开的状态，附近搜索、地图、导出、同步等公开接口默认只返回这些状态的垃圾桶
+// 待审核的垃圾桶只有上传者和管理员可以看到
+var PublicStatuses = []TrashCanStatus{StatusActive, StatusReportedMissing}
+
+// Public 该状态的垃圾桶是否公开
+func (s TrashCanStatus) Public() bool {
+	for _, status := range PublicStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TrashCanStatusActor 状态变更的操作者类型
+type TrashCanStatusActor string
+
+const (
+	ActorOwner     TrashCanStatusActor = "owner"     // 垃圾桶的上传者
+	ActorModerator TrashCanStatusActor = "moderator" // 管理员
+	ActorSystem    TrashCanStatusActor = "system"    // 系统自动变更，如报告不存在的人数达到阈值
+)
+
+// trashCanStatusTransitions 允许的状态变更及可以执行该变更的操作者
+var trashCanStatusTransitions = map[TrashCanStatus]map[TrashCanStatus][]TrashCanStatusActor{
+	StatusPendingReview: {
+		StatusActive:  {ActorModerator},             // 审核通过
+		StatusRemoved: {ActorModerator, ActorOwner}, // 审核不通过，或上传者撤回
+	},
+	StatusActive: {
+		StatusReportedMissing: {ActorModerator, ActorSystem},
+		StatusRemoved:         {ActorModerator, ActorOwner},
+	},
+	StatusReportedMissing: {
+		StatusActive:  {ActorModerator}, // 确认垃圾桶仍然存在
+		StatusRemoved: {ActorModerator}, // 确认已撤除
+	},
+	StatusRemoved: {
+		StatusActive: {ActorModerator}, // 恢复误操作
+	},
+}
+
+// ParseTrashCanStatus 解析状态名称
+func ParseTrashCanStatus(s string) (TrashCanStatus, error) {
+	status := TrashCanStatus(strings.ToLower(strings.TrimSpace(s)))
+	for _, item := range TrashCanStatuses {
+		if item == status {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("未知的状态: %s，只支持 pending_review、active、reported_missing、removed", s)
+}
+
+// ParseTrashCanStatuses 解析逗号分隔的状态名称，如 "active,reported_missing"
+func ParseTrashCanStatuses(s string) ([]TrashCanStatus, error) {
+	var statuses []TrashCanStatus
+	for _, name := range strings.Split(s, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		status, err := ParseTrashCanStatus(name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ErrTransitionForbidden 允许该状态变更，但当前操作者没有权限执行
+var ErrTransitionForbidden = errors.New("没有权限执行该状态变更")
+
+// CheckTransition 检查操作者能否将垃圾桶从 from 状态变更为 to 状态
+func CheckTransition(from, to TrashCanStatus, actor TrashCanStatusActor) error {
+	if from == to {
+		return fmt.Errorf("垃圾桶已经是 %s 状态", to)
+	}
+	actors, ok := trashCanStatusTransitions[from][to]
+	if !ok {
+		return fmt.Errorf("不能从 %s 变更为 %s", from, to)
+	}
+	for _, a := range actors {
+		if a == actor {
+			return nil
+		}
+	}
+	return ErrTransitionForbidden
+}
+
+// HasStatus 筛选处于指定状态之一的垃圾桶
+func HasStatus(statuses ...TrashCanStatus) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status IN ?", statuses)
+	}
+}
+
+// NotRemoved 排除已撤除的垃圾桶
+func NotRemoved(db *gorm.DB) *gorm.DB {
+	return db.Where("status <> ?", StatusRemoved)
+}
+
+// PublicStatus 只保留公开的垃圾桶，公开接口未指定 status 时的默认条件
+func PublicStatus(db *gorm.DB) *gorm.DB {
+	return db.Where("status IN ?", PublicStatuses)
+}
+
+// VisibleTo 筛选普通用户可以看到的垃圾桶：公开的垃圾桶及其自己上传的垃圾桶
+func VisibleTo(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status IN ? OR user_id = ?", PublicStatuses, userID)
+	}
+}
+
+// TrashCanStatusLog 垃圾桶状态变更记录
+type TrashCanStatusLog struct {
+	ID         uint                `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
+	TrashCanID uint                `json:"trash_can_id" gorm:"not null;index"`
+	FromStatus TrashCanStatus      `json:"from_status" gorm:"type:VARCHAR(20);not null"`
+	ToStatus   TrashCanStatus      `json:"to_status" gorm:"type:VARCHAR(20);not null"`
+	Actor      TrashCanStatusActor `json:"actor" gorm:"type:VARCHAR(20);not null"` // 操作者类型
+	ActorID    *uint               `json:"actor_id" gorm:"index"`                  // 操作的用户，系统自动变更时为NULL
+	Reason     string              `json:"reason" gorm:"type:VARCHAR(200)"`
+	CreatedAt  time.Time           `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TrashCanStatusLog) TableName() string {
+	return "trash_can_status_logs"
+}
+
+// TrashCanMissingReport 用户报告垃圾桶已不存在，每个用户对每个垃圾桶只记录一次
+// 垃圾桶被确认仍然存在（重新变为 active）时清空，重新开始计数
+type TrashCanMissingReport struct {
+	ID         uint      `json:"id" gorm:"primaryKey;AUTO_INCREMENT"`
+	TrashCanID uint      `json:"trash_can_id" gorm:"not null;uniqueIndex:idx_trash_can_missing_reports_user,priority:1"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_trash_can_missing_reports_user,priority:2"`
+	Reason     string    `json:"reason" gorm:"type:VARCHAR(200)"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TrashCanMissingReport) TableName() string {
+	return "trash_can_missing_reports"
+}
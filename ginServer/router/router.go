@@ -39,11 +39,33 @@ func InitRouter() {
 		{
 			authGroup.GET("/users/me", api.GetCurrentUser)
 			authGroup.GET("/users/me/trashcans", api.GetUserTrashCans)
+			authGroup.GET("/users/me/trashcans/export", api.ExportUserTrashCans)
+			authGroup.GET("/users/me/trashcans/export.geojson", api.ExportUserTrashCans)
+			authGroup.GET("/users/me/trashcans/export.kml", api.ExportUserTrashCans)
+			authGroup.GET("/users/me/trashcans/export.gpx", api.ExportUserTrashCans)
 		}
 
-		// 垃圾桶相关接口（公开）
-		v1.GET("/trashcans/nearby", api.GetNearbyTrashCans)
-		v1.GET("/trashcans/:id", api.GetTrashCanDetail)
+		// 垃圾桶相关接口（公开，登录用户可以额外看到自己待审核的垃圾桶）
+		trashCanPublicGroup := v1.Group("")
+		trashCanPublicGroup.Use(middle.OptionalJWTAuth())
+		{
+			trashCanPublicGroup.GET("/trashcans/nearby", api.GetNearbyTrashCans)
+			trashCanPublicGroup.GET("/trashcans/in-bounds", api.GetTrashCansInBounds)
+			trashCanPublicGroup.GET("/trashcans/clusters", api.GetTrashCanClusters)
+			trashCanPublicGroup.GET("/trashcans/heatmap", api.GetTrashCanHeatmap)
+			trashCanPublicGroup.GET("/trashcans/coverage-gaps", api.GetCoverageGaps)
+			trashCanPublicGroup.GET("/trashcans/changes", api.GetTrashCanChanges)
+			trashCanPublicGroup.GET("/trashcans/search", api.SearchTrashCansByText)
+			trashCanPublicGroup.POST("/trashcans/search", api.SearchTrashCansInArea)
+			trashCanPublicGroup.POST("/trashcans/along-route", api.SearchTrashCansAlongRoute)
+			trashCanPublicGroup.GET("/trashcans/export", api.ExportTrashCans)
+			trashCanPublicGroup.GET("/trashcans/export.geojson", api.ExportTrashCans)
+			trashCanPublicGroup.GET("/trashcans/export.kml", api.ExportTrashCans)
+			trashCanPublicGroup.GET("/trashcans/export.gpx", api.ExportTrashCans)
+			trashCanPublicGroup.GET("/trashcans/:id", api.GetTrashCanDetail)
+			trashCanPublicGroup.GET("/trashcans/:id/images", api.GetTrashCanImages)
+			trashCanPublicGroup.GET("/trashcans/:id/status-logs", api.GetTrashCanStatusLogs)
+		}
 
 		// 垃圾桶相关接口（需要认证）
 		trashCanAuthGroup := v1.Group("")
@@ -54,9 +76,26 @@ func InitRouter() {
 			trashCanAuthGroup.DELETE("/trashcans/:id", api.DeleteTrashCan)
 			trashCanAuthGroup.POST("/trashcans/:id/like", api.ToggleLike)
 			trashCanAuthGroup.POST("/trashcans/:id/dislike", api.ToggleDislike)
+			trashCanAuthGroup.POST("/trashcans/:id/images", api.AddTrashCanImage)
+			trashCanAuthGroup.PUT("/trashcans/:id/images/order", api.ReorderTrashCanImages)
+			trashCanAuthGroup.PUT("/trashcans/:id/images/:image_id/cover", api.SetTrashCanImageCover)
+			trashCanAuthGroup.DELETE("/trashcans/:id/images/:image_id", api.DeleteTrashCanImage)
+			trashCanAuthGroup.PUT("/trashcans/:id/status", api.UpdateTrashCanStatus)
+			trashCanAuthGroup.POST("/trashcans/:id/missing-reports", api.ReportTrashCanMissing)
+		}
+
+		// 管理员接口
+		adminGroup := v1.Group("admin")
+		adminGroup.Use(middle.JWTAuth(), middle.AdminAuth())
+		{
+			adminGroup.POST("/trashcans/merge", api.MergeTrashCans)
+			adminGroup.POST("/trashcans/import", api.ImportTrashCans)
 		}
 	}
 
+	// 垃圾桶矢量瓦片
+	router.GET("/tiles/:z/:x/:y", api.GetTrashCanTile)
+
 	// 静态文件服务 - 图片访问
 	router.Static("/uploads", "./uploads")
 
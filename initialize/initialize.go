@@ -3,6 +3,8 @@ package initialize
 import (
 	"template/global"
 	Orm "template/initialize/orm"
+	"template/internal/modules/geofence"
+	"template/internal/modules/spatial"
 )
 
 func Initialize() {
@@ -11,6 +13,14 @@ func Initialize() {
 	CreateMkdirall()
 	InitLogger()
 	Viper()
+	if err := geofence.Init(); err != nil {
+		global.SugarLogger.Panicf("服务范围配置错误: %v", err)
+	}
 	global.DB = Orm.InitDB()
 	Orm.RegisterTables()
+	Orm.MigrateData()
+	Orm.StartTrashCanChangeCompaction()
+	if err := spatial.Init(); err != nil {
+		global.SugarLogger.Errorf("空间索引加载失败，附近搜索将直接查询数据库: %v", err)
+	}
 }
@@ -0,0 +1,101 @@
+package Orm
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+const (
+	defaultChangeRetentionDays = 30        // 未配置时变更日志的保留天数
+	changeCompactionInterval   = time.Hour // 清理过期变更日志的间隔
+)
+
+// changeRetentionDays 变更日志的保留天数
+func changeRetentionDays() int {
+	if cfg := global.CONFIG.SyncConfig; cfg != nil && cfg.ChangeRetentionDays > 0 {
+		return cfg.ChangeRetentionDays
+	}
+	return defaultChangeRetentionDays
+}
+
+// createTrashCanChangeTriggers 创建写入垃圾桶变更日志的触发器
+// 触发器与引起变更的语句在同一事务中执行，任何途径（接口、合并、批量导入、命令行工具）的修改都会被记录
+// 点赞点踩和图库的变化会改变同步给客户端的数量，同样记为垃圾桶的更新
+func createTrashCanChangeTriggers(db *gorm.DB) error {
+	changes := model.TrashCanChange{}.TableName()
+	source := model.TrashCan{}.TableName()
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&model.TrashCanLike{}); err != nil {
+		return err
+	}
+	likes := stmt.Schema.Table
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON %[2]s BEGIN
+			INSERT INTO %[1]s(trash_can_id, op, created_at) VALUES (new.id, '%[3]s', CURRENT_TIMESTAMP);
+		END`, changes, source, model.TrashCanChangeCreated),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE ON %[2]s BEGIN
+			INSERT INTO %[1]s(trash_can_id, op, created_at) VALUES (new.id, '%[3]s', CURRENT_TIMESTAMP);
+		END`, changes, source, model.TrashCanChangeUpdated),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON %[2]s BEGIN
+			INSERT INTO %[1]s(trash_can_id, op, created_at) VALUES (old.id, '%[3]s', CURRENT_TIMESTAMP);
+		END`, changes, source, model.TrashCanChangeDeleted),
+	}
+	// 垃圾桶已被删除时（例如合并时先删除投票）不再记录更新
+	for _, event := range []struct{ suffix, when, table, row string }{
+		{"likes_ai", "INSERT", likes, "new"},
+		{"likes_au", "UPDATE", likes, "new"},
+		{"likes_ad", "DELETE", likes, "old"},
+		{"images_ai", "INSERT", model.TrashCanImage{}.TableName(), "new"},
+		{"images_au", "UPDATE", model.TrashCanImage{}.TableName(), "new"},
+		{"images_ad", "DELETE", model.TrashCanImage{}.TableName(), "old"},
+	} {
+		statements = append(statements, fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_%[2]s AFTER %[3]s ON %[4]s BEGIN
+			INSERT INTO %[1]s(trash_can_id, op, created_at)
+			SELECT %[5]s.trash_can_id, '%[6]s', CURRENT_TIMESTAMP WHERE EXISTS (SELECT 1 FROM %[7]s WHERE id = %[5]s.trash_can_id);
+		END`, changes, event.suffix, event.when, event.table, event.row, model.TrashCanChangeUpdated, source))
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompactTrashCanChanges 删除超过保留天数的变更日志，返回删除的条数
+// 最新的一条始终保留，增量同步以最早保留的变更判断令牌之后的变更是否已被清理
+func CompactTrashCanChanges(db *gorm.DB, retentionDays int) (int64, error) {
+	changes := model.TrashCanChange{}.TableName()
+	result := db.Exec(fmt.Sprintf(`DELETE FROM %[1]s WHERE created_at < datetime('now', ?) AND id < (SELECT MAX(id) FROM %[1]s)`, changes),
+		fmt.Sprintf("-%d days", retentionDays))
+	return result.RowsAffected, result.Error
+}
+
+// StartTrashCanChangeCompaction 在后台定期清理过期的变更日志，启动时先清理一次
+func StartTrashCanChangeCompaction() {
+	compact := func() {
+		deleted, err := CompactTrashCanChanges(global.DB, changeRetentionDays())
+		if err != nil {
+			global.SugarLogger.Errorf("清理变更日志失败: %v", err)
+			return
+		}
+		if deleted > 0 {
+			global.SugarLogger.Infof("已清理 %d 条过期的变更日志", deleted)
+		}
+	}
+	go func() {
+		compact()
+		ticker := time.NewTicker(changeCompactionInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			compact()
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package Orm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"template/ginServer/model"
+	"template/global"
+)
+
+// createTrashCanSearchIndex 创建垃圾桶地址和描述的FTS5全文索引
+// 使用trigram分词器，按连续3个字符建立索引，适合没有空格分词的中文
+// 索引表为外部内容表，不重复存储文本，由触发器在 trash_cans 增删改时同步
+func createTrashCanSearchIndex(db *gorm.DB) error {
+	table := model.TrashCanSearchTable
+	source := model.TrashCan{}.TableName()
+
+	exists := db.Migrator().HasTable(table)
+	statements := []string{
+		fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(
+			address, description, content='%s', content_rowid='id', tokenize='trigram')`, table, source),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON %[2]s BEGIN
+			INSERT INTO %[1]s(rowid, address, description) VALUES (new.id, new.address, new.description);
+		END`, table, source),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON %[2]s BEGIN
+			INSERT INTO %[1]s(%[1]s, rowid, address, description) VALUES ('delete', old.id, old.address, old.description);
+		END`, table, source),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE OF address, description ON %[2]s BEGIN
+			INSERT INTO %[1]s(%[1]s, rowid, address, description) VALUES ('delete', old.id, old.address, old.description);
+			INSERT INTO %[1]s(rowid, address, description) VALUES (new.id, new.address, new.description);
+		END`, table, source),
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	// 新建索引时为已有数据建立索引
+	if !exists {
+		if err := db.Exec(fmt.Sprintf(`INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')`, table)).Error; err != nil {
+			return err
+		}
+		global.SugarLogger.Info("全文索引创建完成")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package Orm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"template/ginServer/model"
+	"template/global"
+	"template/utils"
+)
+
+// MigrateData 表结构迁移之后的数据迁移
+func MigrateData() {
+	db := global.DB
+	if err := backfillGeohash(db); err != nil {
+		global.SugarLogger.Errorf("回填geohash失败: %v", err)
+	}
+	if err := backfillTrashCanImages(db); err != nil {
+		global.SugarLogger.Errorf("迁移垃圾桶图片失败: %v", err)
+	}
+	if err := createTrashCanSearchIndex(db); err != nil {
+		global.SugarLogger.Errorf("创建全文索引失败: %v", err)
+	}
+	if err := createTrashCanChangeTriggers(db); err != nil {
+		global.SugarLogger.Errorf("创建变更日志触发器失败: %v", err)
+	}
+}
+
+// backfillGeohash 为新增geohash字段之前创建的垃圾桶回填geohash
+func backfillGeohash(db *gorm.DB) error {
+	var trashCans []model.TrashCan
+	total := 0
+	result := db.Select("id, latitude, longitude").
+		Where("geohash IS NULL OR geohash = ''").
+		FindInBatches(&trashCans, 500, func(tx *gorm.DB, batch int) error {
+			for _, tc := range trashCans {
+				hash := utils.GeohashEncode(tc.Latitude, tc.Longitude, utils.GeohashMaxPrecision)
+				// UpdateColumn 不会触发钩子，也不会修改 updated_at
+				if err := db.Model(&model.TrashCan{}).Where("id = ?", tc.ID).
+					UpdateColumn("geohash", hash).Error; err != nil {
+					return err
+				}
+			}
+			total += len(trashCans)
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if total > 0 {
+		global.SugarLogger.Infof("已为 %d 个垃圾桶回填geohash", total)
+	}
+	return nil
+}
+
+// backfillTrashCanImages 将添加图库之前垃圾桶的图片迁移为图库中的封面
+// 需要在创建变更日志触发器之前执行，避免迁移产生大量变更记录
+func backfillTrashCanImages(db *gorm.DB) error {
+	images := model.TrashCanImage{}.TableName()
+	trashCans := model.TrashCan{}.TableName()
+	result := db.Exec(fmt.Sprintf(`INSERT INTO %[1]s (trash_can_id, user_id, image_path, caption, sort_order, is_cover, created_at)
+		SELECT id, user_id, image_path, '', 0, true, created_at FROM %[2]s
+		WHERE image_path <> '' AND NOT EXISTS (SELECT 1 FROM %[1]s WHERE trash_can_id = %[2]s.id)`, images, trashCans))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		global.SugarLogger.Infof("已将 %d 个垃圾桶的图片迁移到图库", result.RowsAffected)
+	}
+	return nil
+}
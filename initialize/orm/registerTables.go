@@ -13,6 +13,12 @@ func RegisterTables() {
 		model.User{},
 		model.TrashCan{},
 		model.TrashCanLike{},
+		model.TrashCanDuplicate{},
+		model.TrashCanRedirect{},
+		model.TrashCanImage{},
+		model.TrashCanChange{},
+		model.TrashCanStatusLog{},
+		model.TrashCanMissingReport{},
 	)
 	if err != nil {
 		global.SugarLogger.Error("register table failed")
@@ -0,0 +1,438 @@
+// Package coverage 覆盖缺口分析：在范围内按网格采样，找出离最近的垃圾桶超过阈值距离的区域，供分析接口和命令行工具共用
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// 默认参数
+const (
+	DefaultMaxSamples = 40000 // 最多的采样点数量
+)
+
+// ErrNoTrashCans 没有任何垃圾桶时无法计算距离
+var ErrNoTrashCans = errors.New("还没有任何垃圾桶，无法分析覆盖情况")
+
+// InputError 分析参数有误（范围过大、采样间距过小等），错误信息可以直接返回给用户
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Options 分析选项
+type Options struct {
+	Box        utils.BoundingBox // 分析范围（统一存储的坐标系）
+	Threshold  float64           // 阈值距离（米），离最近的垃圾桶超过该距离的采样点视为缺口
+	Step       float64           // 采样间距（米），小于等于0时取阈值的一半，采样点过多时自动加大
+	MaxSamples int               // 最多的采样点数量，默认40000
+	MaxRegions int               // 最多返回的缺口区域数量，0表示不限制
+	WithCells  bool              // 是否在结果中返回每个区域包含的采样网格
+	Index      *spatial.Index    // 垃圾桶的空间索引，为nil时使用服务的全局索引（只包含公开的垃圾桶）
+}
+
+// LatLng 经纬度
+type LatLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Cell 一个采样网格，坐标为网格中心
+type Cell struct {
+	LatLng
+	Distance float64 `json:"distance"` // 到最近垃圾桶的距离（米）
+}
+
+// Bounds 区域的外接矩形
+type Bounds struct {
+	SwLat float64 `json:"sw_lat"`
+	SwLng float64 `json:"sw_lng"`
+	NeLat float64 `json:"ne_lat"`
+	NeLng float64 `json:"ne_lng"`
+}
+
+// Region 由相邻（上下左右）的缺口网格组成的区域
+type Region struct {
+	Rank        int                `json:"rank"`         // 按面积由大到小的排名，从1开始
+	CellCount   int                `json:"cell_count"`   // 包含的采样网格数量
+	Area        float64            `json:"area"`         // 面积（平方米）
+	MaxDistance float64            `json:"max_distance"` // 区域内离最近垃圾桶的最远距离（米）
+	Farthest    LatLng             `json:"farthest"`     // 离最近垃圾桶最远的采样点，即最需要增设垃圾桶的位置
+	Centroid    LatLng             `json:"centroid"`     // 区域的中心
+	Bounds      Bounds             `json:"bounds"`
+	Polygon     utils.MultiPolygon `json:"polygon"`         // 区域轮廓，GeoJSON MultiPolygon 的 coordinates
+	Cells       []Cell             `json:"cells,omitempty"` // 包含的采样网格（WithCells 时返回）
+}
+
+// Result 分析结果
+type Result struct {
+	Threshold   float64  `json:"threshold"`    // 阈值距离（米）
+	Step        float64  `json:"step"`         // 实际使用的采样间距（米）
+	Rows        int      `json:"rows"`         // 采样网格行数
+	Columns     int      `json:"columns"`      // 采样网格列数
+	Samples     int      `json:"samples"`      // 采样点数量
+	GapCells    int      `json:"gap_cells"`    // 缺口网格数量
+	GapArea     float64  `json:"gap_area"`     // 缺口总面积（平方米）
+	Coverage    float64  `json:"coverage"`     // 阈值距离内有垃圾桶的采样点比例
+	RegionCount int      `json:"region_count"` // 缺口区域总数，可能多于返回的区域数量
+	Regions     []Region `json:"regions"`
+}
+
+// Convert 返回转换为指定坐标系后的副本
+func (r Region) Convert(to utils.CoordSys) Region {
+	convert := func(p LatLng) LatLng {
+		p.Latitude, p.Longitude = utils.FromCanonicalCoord(p.Latitude, p.Longitude, to)
+		return p
+	}
+	r.Farthest = convert(r.Farthest)
+	r.Centroid = convert(r.Centroid)
+	r.Bounds.SwLat, r.Bounds.SwLng = utils.FromCanonicalCoord(r.Bounds.SwLat, r.Bounds.SwLng, to)
+	r.Bounds.NeLat, r.Bounds.NeLng = utils.FromCanonicalCoord(r.Bounds.NeLat, r.Bounds.NeLng, to)
+	r.Polygon = r.Polygon.FromCanonicalCoord(to)
+	if r.Cells != nil {
+		cells := make([]Cell, len(r.Cells))
+		for i, cell := range r.Cells {
+			cells[i] = cell
+			cells[i].LatLng = convert(cell.LatLng)
+		}
+		r.Cells = cells
+	}
+	return r
+}
+
+// grid 采样网格，第0行在范围的南边，第0列在西边
+type grid struct {
+	box     utils.BoundingBox
+	rows    int
+	columns int
+	dLat    float64 // 每行的纬度跨度
+	dLng    float64 // 每列的经度跨度
+}
+
+// vertex 返回网格顶点的经纬度，跨越180度经线时经度保持连续（可能大于180），使轮廓不被截断
+func (g grid) vertex(row, column int) (float64, float64) {
+	return g.box.MinLat + float64(row)*g.dLat, g.box.MinLng + float64(column)*g.dLng
+}
+
+// center 返回网格中心的经纬度
+func (g grid) center(row, column int) (float64, float64) {
+	lat := g.box.MinLat + (float64(row)+0.5)*g.dLat
+	lng := g.box.MinLng + (float64(column)+0.5)*g.dLng
+	return lat, utils.NormalizeLng(lng)
+}
+
+// area 返回第 row 行一个网格的面积（平方米）
+func (g grid) area(row int) float64 {
+	lat0, lng0 := g.vertex(row, 0)
+	lat1, lng1 := g.vertex(row+1, 1)
+	lat := (lat0 + lat1) / 2
+	height := utils.CalculateDistance(lat0, lng0, lat1, lng0) * 1000
+	width := utils.CalculateDistance(lat, lng0, lat, lng1) * 1000
+	return height * width
+}
+
+// newGrid 按采样间距划分网格，采样点数量超过上限时：未指定间距则加大间距，指定了间距则返回错误
+func newGrid(box utils.BoundingBox, step, threshold float64, maxSamples int) (grid, float64, error) {
+	lngSpan := box.MaxLng - box.MinLng
+	if box.CrossesAntimeridian() {
+		lngSpan += 360
+	}
+	centerLat := (box.MinLat + box.MaxLat) / 2
+	height := utils.CalculateDistance(box.MinLat, 0, box.MaxLat, 0) * 1000
+	width := utils.CalculateDistance(centerLat, 0, centerLat, lngSpan/2) * 2000
+	if height <= 0 || width <= 0 {
+		return grid{}, 0, &InputError{Err: errors.New("分析范围不能为空")}
+	}
+
+	auto := step <= 0
+	if auto {
+		step = threshold / 2
+		// 采样点过多时按面积加大间距
+		if minStep := math.Sqrt(height * width / float64(maxSamples)); step < minStep {
+			step = math.Ceil(minStep)
+		}
+	}
+	rows := max(1, int(math.Ceil(height/step)))
+	columns := max(1, int(math.Ceil(width/step)))
+	// 行列数向上取整后仍可能略微超出上限
+	for auto && rows*columns > maxSamples {
+		step = math.Ceil(step * 1.05)
+		rows = max(1, int(math.Ceil(height/step)))
+		columns = max(1, int(math.Ceil(width/step)))
+	}
+	if rows*columns > maxSamples {
+		return grid{}, 0, &InputError{Err: fmt.Errorf("采样点数量 %d 超过上限 %d，请缩小范围或加大采样间距", rows*columns, maxSamples)}
+	}
+
+	g := grid{
+		box:     box,
+		rows:    rows,
+		columns: columns,
+		dLat:    (box.MaxLat - box.MinLat) / float64(rows),
+		dLng:    lngSpan / float64(columns),
+	}
+	return g, step, nil
+}
+
+// Analyze 在范围内按网格采样，计算每个采样点到最近垃圾桶的距离，
+// 把超过阈值距离的相邻采样网格合并为缺口区域，按面积由大到小排序
+func Analyze(opts Options) (*Result, error) {
+	if opts.Threshold <= 0 {
+		return nil, &InputError{Err: errors.New("阈值距离必须大于0")}
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = DefaultMaxSamples
+	}
+	g, step, err := newGrid(opts.Box, opts.Step, opts.Threshold, opts.MaxSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	nearest := func(lat, lng float64) ([]spatial.Result, error) {
+		if opts.Index != nil {
+			return opts.Index.Nearest(lat, lng, 1, 0), nil
+		}
+		return spatial.Nearest(lat, lng, 1, 0)
+	}
+	distances := make([]float64, g.rows*g.columns)
+	gap := make([]bool, len(distances))
+	gapCells := 0
+	for row := 0; row < g.rows; row++ {
+		for column := 0; column < g.columns; column++ {
+			lat, lng := g.center(row, column)
+			results, err := nearest(lat, lng)
+			if err != nil {
+				return nil, err
+			}
+			if len(results) == 0 {
+				return nil, ErrNoTrashCans
+			}
+			i := row*g.columns + column
+			distances[i] = results[0].Distance * 1000
+			if distances[i] > opts.Threshold {
+				gap[i] = true
+				gapCells++
+			}
+		}
+	}
+
+	result := &Result{
+		Threshold: opts.Threshold,
+		Step:      math.Round(step*10) / 10,
+		Rows:      g.rows,
+		Columns:   g.columns,
+		Samples:   len(distances),
+		GapCells:  gapCells,
+		Coverage:  1 - float64(gapCells)/float64(len(distances)),
+		Regions:   make([]Region, 0),
+	}
+
+	// 广度优先合并上下左右相邻的缺口网格
+	visited := make([]bool, len(gap))
+	for start := range gap {
+		if !gap[start] || visited[start] {
+			continue
+		}
+		visited[start] = true
+		members := []int{start}
+		for k := 0; k < len(members); k++ {
+			row, column := members[k]/g.columns, members[k]%g.columns
+			for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				r, c := row+d[0], column+d[1]
+				if r < 0 || r >= g.rows || c < 0 || c >= g.columns {
+					continue
+				}
+				if i := r*g.columns + c; gap[i] && !visited[i] {
+					visited[i] = true
+					members = append(members, i)
+				}
+			}
+		}
+		region := newRegion(g, members, distances, gap, opts.WithCells)
+		result.GapArea += region.Area
+		result.Regions = append(result.Regions, region)
+	}
+
+	sort.SliceStable(result.Regions, func(i, j int) bool {
+		a, b := result.Regions[i], result.Regions[j]
+		if a.Area != b.Area {
+			return a.Area > b.Area
+		}
+		return a.MaxDistance > b.MaxDistance
+	})
+	result.RegionCount = len(result.Regions)
+	if opts.MaxRegions > 0 && len(result.Regions) > opts.MaxRegions {
+		result.Regions = result.Regions[:opts.MaxRegions]
+	}
+	for i := range result.Regions {
+		result.Regions[i].Rank = i + 1
+	}
+	result.GapArea = math.Round(result.GapArea)
+	return result, nil
+}
+
+// newRegion 汇总一个缺口区域的面积、最远点、中心、外接矩形和轮廓
+func newRegion(g grid, members []int, distances []float64, gap []bool, withCells bool) Region {
+	region := Region{CellCount: len(members), MaxDistance: -1}
+	var latSum, lngSum float64
+	minRow, maxRow, minColumn, maxColumn := g.rows, -1, g.columns, -1
+	for _, i := range members {
+		row, column := i/g.columns, i%g.columns
+		lat, lng := g.center(row, column)
+		area := g.area(row)
+		region.Area += area
+		// 中心按面积加权，经度使用连续值以免跨越180度经线时取平均出错
+		_, continuousLng := g.vertex(row, column)
+		latSum += lat * area
+		lngSum += (continuousLng + g.dLng/2) * area
+		if distances[i] > region.MaxDistance {
+			region.MaxDistance = distances[i]
+			region.Farthest = LatLng{Latitude: lat, Longitude: lng}
+		}
+		minRow, maxRow = min(minRow, row), max(maxRow, row)
+		minColumn, maxColumn = min(minColumn, column), max(maxColumn, column)
+		if withCells {
+			region.Cells = append(region.Cells, Cell{LatLng: LatLng{Latitude: lat, Longitude: lng}, Distance: math.Round(distances[i])})
+		}
+	}
+	region.Centroid = LatLng{Latitude: latSum / region.Area, Longitude: utils.NormalizeLng(lngSum / region.Area)}
+	region.Bounds.SwLat, region.Bounds.SwLng = g.vertex(minRow, minColumn)
+	region.Bounds.NeLat, region.Bounds.NeLng = g.vertex(maxRow+1, maxColumn+1)
+	region.Bounds.SwLng, region.Bounds.NeLng = utils.NormalizeLng(region.Bounds.SwLng), utils.NormalizeLng(region.Bounds.NeLng)
+	region.Polygon = traceOutline(g, members, gap)
+	region.Area = math.Round(region.Area)
+	region.MaxDistance = math.Round(region.MaxDistance)
+	return region
+}
+
+// traceOutline 追踪区域的轮廓：收集不与其他缺口网格相邻的网格边，按方向首尾相连成环
+// 外环为逆时针、洞为顺时针，符合 GeoJSON 的约定
+func traceOutline(g grid, members []int, gap []bool) utils.MultiPolygon {
+	isGap := func(row, column int) bool {
+		return row >= 0 && row < g.rows && column >= 0 && column < g.columns && gap[row*g.columns+column]
+	}
+
+	// 顶点为 (行, 列)，边按逆时针方向记录，以起点为键
+	type vertex [2]int
+	edges := make(map[vertex][]vertex)
+	for _, i := range members {
+		row, column := i/g.columns, i%g.columns
+		if !isGap(row-1, column) {
+			edges[vertex{row, column}] = append(edges[vertex{row, column}], vertex{row, column + 1})
+		}
+		if !isGap(row, column+1) {
+			edges[vertex{row, column + 1}] = append(edges[vertex{row, column + 1}], vertex{row + 1, column + 1})
+		}
+		if !isGap(row+1, column) {
+			edges[vertex{row + 1, column + 1}] = append(edges[vertex{row + 1, column + 1}], vertex{row + 1, column})
+		}
+		if !isGap(row, column-1) {
+			edges[vertex{row + 1, column}] = append(edges[vertex{row + 1, column}], vertex{row, column})
+		}
+	}
+
+	var outers, holes []utils.Ring
+	for len(edges) > 0 {
+		// 从最下方、最左侧的顶点开始，保证起点在外环或洞的角上
+		start := vertex{g.rows + 1, g.columns + 1}
+		for v := range edges {
+			if v[0] < start[0] || v[0] == start[0] && v[1] < start[1] {
+				start = v
+			}
+		}
+		var path []vertex
+		from, at := start, start
+		for {
+			next := edges[at]
+			if len(next) == 0 {
+				break
+			}
+			// 两个网格只在顶点处相接时该顶点有两条出边，优先左转，使环紧贴本侧的网格
+			k := 0
+			if len(next) > 1 {
+				in := [2]int{at[0] - from[0], at[1] - from[1]}
+				for j, to := range next {
+					out := [2]int{to[0] - at[0], to[1] - at[1]}
+					// 行对应纬度（y），列对应经度（x），叉积为正表示左转
+					if in[1]*out[0]-in[0]*out[1] > 0 {
+						k = j
+					}
+				}
+			}
+			to := next[k]
+			edges[at] = append(next[:k], next[k+1:]...)
+			if len(edges[at]) == 0 {
+				delete(edges, at)
+			}
+			path = append(path, at)
+			from, at = at, to
+			if at == start {
+				break
+			}
+		}
+
+		// 去掉共线的中间顶点
+		var ring utils.Ring
+		for i, v := range path {
+			prev, next := path[(i+len(path)-1)%len(path)], path[(i+1)%len(path)]
+			if (v[0]-prev[0])*(next[1]-v[1]) == (v[1]-prev[1])*(next[0]-v[0]) {
+				continue
+			}
+			lat, lng := g.vertex(v[0], v[1])
+			ring = append(ring, [2]float64{lng, lat})
+		}
+		if len(ring) < 3 {
+			continue
+		}
+		ring = append(ring, ring[0])
+		if ringArea(ring) > 0 {
+			outers = append(outers, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	polygons := make(utils.MultiPolygon, len(outers))
+	for i, outer := range outers {
+		polygons[i] = utils.Polygon{outer}
+	}
+	for _, hole := range holes {
+		// 洞的顶点都在所属外环的内部或边上，取洞的中心判断
+		var lat, lng float64
+		for _, p := range hole[:len(hole)-1] {
+			lng += p[0]
+			lat += p[1]
+		}
+		lat, lng = lat/float64(len(hole)-1), lng/float64(len(hole)-1)
+		k := 0
+		for i, outer := range outers {
+			if outer.Contains(lat, lng) {
+				k = i
+				break
+			}
+		}
+		polygons[k] = append(polygons[k], hole)
+	}
+	return polygons
+}
+
+// ringArea 环的有向面积（鞋带公式），逆时针为正
+func ringArea(ring utils.Ring) float64 {
+	var sum float64
+	for i := 0; i+1 < len(ring); i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum / 2
+}
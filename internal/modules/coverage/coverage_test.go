@@ -0,0 +1,276 @@
+package coverage
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// unitGrid 返回每个网格为1度见方、原点在 (0, 0) 的网格，顶点 (行, 列) 对应坐标 [列, 行]
+func unitGrid(rows, columns int) grid {
+	return grid{
+		box:     utils.BoundingBox{MinLat: 0, MaxLat: float64(rows), MinLng: 0, MaxLng: float64(columns)},
+		rows:    rows,
+		columns: columns,
+		dLat:    1,
+		dLng:    1,
+	}
+}
+
+// parseMask 将字符画转换为缺口标记，第一行字符为最北边（行号最大）的一行，# 表示缺口
+func parseMask(lines ...string) (grid, []bool, []int) {
+	g := unitGrid(len(lines), len(lines[0]))
+	gap := make([]bool, g.rows*g.columns)
+	var members []int
+	for i, line := range lines {
+		row := g.rows - 1 - i
+		for column, ch := range line {
+			if ch == '#' {
+				gap[row*g.columns+column] = true
+				members = append(members, row*g.columns+column)
+			}
+		}
+	}
+	return g, gap, members
+}
+
+func TestTraceOutline(t *testing.T) {
+	tests := []struct {
+		name     string
+		mask     []string
+		polygons []int // 每个多边形中各环（外环在前）的顶点数，不含闭合点
+	}{
+		{"单个网格", []string{"#"}, []int{4}},
+		{"一行", []string{"###"}, []int{4}},
+		{"L形", []string{
+			"#.",
+			"##",
+		}, []int{6}},
+		{"凹形", []string{
+			"#.#",
+			"###",
+		}, []int{8}},
+		{"中间有洞", []string{
+			"###",
+			"#.#",
+			"###",
+		}, []int{4, 4}},
+		{"两个洞", []string{
+			"#####",
+			"#.#.#",
+			"#####",
+		}, []int{4, 4, 4}},
+		// 中间的空白网格与外部只在顶点处相接，不形成洞，外环在该顶点处与自身相接
+		{"空白网格在顶点处与外部相接", []string{
+			"###.",
+			"##.#",
+			"####",
+		}, []int{10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, gap, members := parseMask(tt.mask...)
+			polygons := traceOutline(g, members, gap)
+			if len(polygons) != 1 {
+				t.Fatalf("多边形数量 = %d, want 1: %v", len(polygons), polygons)
+			}
+			polygon := polygons[0]
+			if len(polygon) != len(tt.polygons) {
+				t.Fatalf("环的数量 = %d, want %d: %v", len(polygon), len(tt.polygons), polygon)
+			}
+			outer := ringArea(polygon[0])
+			area := outer
+			for i, ring := range polygon {
+				checkRing(t, ring)
+				if len(ring)-1 != tt.polygons[i] {
+					t.Errorf("第 %d 个环的顶点数 = %d, want %d: %v", i, len(ring)-1, tt.polygons[i], ring)
+				}
+				if i > 0 {
+					if ringArea(ring) >= 0 {
+						t.Errorf("洞应为顺时针: %v", ring)
+					}
+					area += ringArea(ring)
+				}
+			}
+			if outer <= 0 {
+				t.Errorf("外环应为逆时针: %v", polygon[0])
+			}
+			// 每个网格的面积为1
+			if area != float64(len(members)) {
+				t.Errorf("轮廓面积 = %v, want %d", area, len(members))
+			}
+			// 缺口网格的中心在多边形内，其余网格的中心不在
+			for row := 0; row < g.rows; row++ {
+				for column := 0; column < g.columns; column++ {
+					inside := polygons.Contains(float64(row)+0.5, float64(column)+0.5)
+					if inside != gap[row*g.columns+column] {
+						t.Errorf("网格 (%d, %d) 的中心在多边形内 = %v, want %v", row, column, inside, gap[row*g.columns+column])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTraceOutlineDiagonal(t *testing.T) {
+	// 只在顶点处相接的网格各自成为一个多边形，两个环在公共顶点处不交叉
+	g, gap, members := parseMask(
+		"#.",
+		".#",
+	)
+	polygons := traceOutline(g, members, gap)
+	if len(polygons) != 2 {
+		t.Fatalf("多边形数量 = %d, want 2: %v", len(polygons), polygons)
+	}
+	for _, polygon := range polygons {
+		if len(polygon) != 1 || len(polygon[0]) != 5 || ringArea(polygon[0]) != 1 {
+			t.Errorf("多边形应为一个网格的正方形: %v", polygon)
+		}
+	}
+
+	// 绕回自身、在顶点处相接的环：左转优先使外环紧贴网格，中间形成洞
+	g, gap, members = parseMask(
+		"##.",
+		"#.#",
+		".##",
+	)
+	polygons = traceOutline(g, members, gap)
+	var area float64
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			checkRing(t, ring)
+			area += ringArea(ring)
+		}
+	}
+	if area != float64(len(members)) {
+		t.Errorf("轮廓面积 = %v, want %d: %v", area, len(members), polygons)
+	}
+}
+
+// checkRing 检查环首尾相连，且相邻的边不共线
+func checkRing(t *testing.T, ring utils.Ring) {
+	t.Helper()
+	if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+		t.Fatalf("环没有闭合: %v", ring)
+	}
+	n := len(ring) - 1
+	for i := 0; i < n; i++ {
+		prev, v, next := ring[(i+n-1)%n], ring[i], ring[(i+1)%n]
+		if (v[0]-prev[0])*(next[1]-v[1]) == (v[1]-prev[1])*(next[0]-v[0]) {
+			t.Errorf("顶点 %v 在共线的边上: %v", v, ring)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// 范围南北约2.2公里、东西约1.9公里，西边和东边的中间各有一个垃圾桶，
+	// 阈值为1200米时中间被覆盖，北边和南边各有一个缺口
+	box := utils.BoundingBox{MinLat: 31.2, MaxLat: 31.22, MinLng: 121.4, MaxLng: 121.42}
+	idx := spatial.NewIndex()
+	idx.Load([]spatial.Point{
+		{ID: 1, Lat: 31.21, Lng: 121.4005},
+		{ID: 2, Lat: 31.21, Lng: 121.4195},
+	})
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantErr     error
+		wantInput   bool
+		wantRegions int
+		check       func(t *testing.T, r *Result)
+	}{
+		{
+			name:        "南北两个缺口",
+			opts:        Options{Box: box, Threshold: 1200, Step: 100, Index: idx},
+			wantRegions: 2,
+			check: func(t *testing.T, r *Result) {
+				if r.Rows*r.Columns != r.Samples || r.GapCells == 0 || r.GapCells == r.Samples {
+					t.Errorf("采样结果 = %+v", r)
+				}
+				for _, region := range r.Regions {
+					if region.MaxDistance <= 1200 {
+						t.Errorf("缺口区域的最远距离 %v 不超过阈值", region.MaxDistance)
+					}
+				}
+			},
+		},
+		{
+			name:        "阈值足够大时没有缺口",
+			opts:        Options{Box: box, Threshold: 5000, Index: idx},
+			wantRegions: 0,
+			check: func(t *testing.T, r *Result) {
+				if r.Coverage != 1 || r.GapArea != 0 {
+					t.Errorf("覆盖率 = %v, 缺口面积 = %v, want 1, 0", r.Coverage, r.GapArea)
+				}
+			},
+		},
+		{
+			name:        "限制返回的区域数量",
+			opts:        Options{Box: box, Threshold: 1200, Step: 100, Index: idx, MaxRegions: 1},
+			wantRegions: 1,
+			check: func(t *testing.T, r *Result) {
+				if r.RegionCount != 2 || r.Regions[0].Rank != 1 {
+					t.Errorf("RegionCount = %d, Rank = %d, want 2, 1", r.RegionCount, r.Regions[0].Rank)
+				}
+			},
+		},
+		{
+			name:    "没有垃圾桶",
+			opts:    Options{Box: box, Threshold: 800, Index: spatial.NewIndex()},
+			wantErr: ErrNoTrashCans,
+		},
+		{
+			name:      "阈值不大于0",
+			opts:      Options{Box: box, Index: idx},
+			wantInput: true,
+		},
+		{
+			name:      "指定的间距使采样点过多",
+			opts:      Options{Box: box, Threshold: 800, Step: 10, MaxSamples: 1000, Index: idx},
+			wantInput: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Analyze(tt.opts)
+			var inputErr *InputError
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Analyze error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantInput:
+				if !errors.As(err, &inputErr) {
+					t.Fatalf("Analyze error = %v, want InputError", err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Analyze error = %v", err)
+			}
+			if len(result.Regions) != tt.wantRegions {
+				t.Fatalf("区域数量 = %d, want %d", len(result.Regions), tt.wantRegions)
+			}
+			var area float64
+			for i, region := range result.Regions {
+				if i > 0 && region.Area > result.Regions[i-1].Area {
+					t.Errorf("区域没有按面积由大到小排序")
+				}
+				if !region.Polygon.Contains(region.Farthest.Latitude, region.Farthest.Longitude) {
+					t.Errorf("最远点 %+v 不在区域轮廓内", region.Farthest)
+				}
+				area += region.Area
+			}
+			if tt.opts.MaxRegions == 0 && math.Abs(area-result.GapArea) > float64(len(result.Regions)) {
+				t.Errorf("区域面积之和 %v 与缺口总面积 %v 不一致", area, result.GapArea)
+			}
+			if tt.check != nil {
+				tt.check(t, result)
+			}
+		})
+	}
+}
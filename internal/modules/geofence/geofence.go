@@ -0,0 +1,125 @@
+// Package geofence 服务范围（地理围栏），在 config.yml 的 service_area 中配置
+// 范围外不能上传或移动垃圾桶，附近搜索也不会以范围外的点为中心；未配置时不限制范围
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"template/config"
+	"template/global"
+	"template/utils"
+)
+
+// ErrOutsideServiceArea 坐标不在服务范围内
+var ErrOutsideServiceArea = errors.New("该位置不在服务范围内")
+
+// Area 服务范围，坐标为统一存储的坐标系
+type Area struct {
+	polygons utils.MultiPolygon
+	box      utils.BoundingBox
+}
+
+// serviceArea 当前生效的服务范围，为nil时不限制
+var serviceArea *Area
+
+// Init 按 global.CONFIG 中的 service_area 加载服务范围
+func Init() error {
+	area, err := Load(global.CONFIG.ServiceAreaConfig)
+	if err != nil {
+		return err
+	}
+	serviceArea = area
+	if area != nil {
+		global.SugarLogger.Infof("服务范围加载完成，共 %d 个多边形", len(area.polygons))
+	}
+	return nil
+}
+
+// Load 解析服务范围配置，未配置任何多边形时返回nil
+func Load(cfg *config.ServiceAreaConfig) (*Area, error) {
+	if cfg == nil || (len(cfg.Polygons) == 0 && cfg.GeoJSONFile == "") {
+		return nil, nil
+	}
+	coordSys := utils.CoordWGS84
+	if cfg.CoordSys != "" {
+		var ok bool
+		if coordSys, ok = utils.ParseCoordSys(cfg.CoordSys); !ok {
+			return nil, fmt.Errorf("service_area.coord_sys 只支持 wgs84、gcj02、bd09: %s", cfg.CoordSys)
+		}
+	}
+
+	var polygons utils.MultiPolygon
+	for i, points := range cfg.Polygons {
+		ring := make(utils.Ring, 0, len(points))
+		for _, p := range points {
+			if len(p) != 2 {
+				return nil, fmt.Errorf("service_area.polygons 第 %d 个多边形的坐标应为 [经度, 纬度]", i+1)
+			}
+			ring = append(ring, [2]float64{p[0], p[1]})
+		}
+		polygons = append(polygons, utils.Polygon{ring})
+	}
+	if len(polygons) > 0 {
+		if err := polygons.Normalize(); err != nil {
+			return nil, fmt.Errorf("service_area.polygons 有误: %v", err)
+		}
+	}
+	if cfg.GeoJSONFile != "" {
+		data, err := os.ReadFile(cfg.GeoJSONFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取服务范围文件失败: %v", err)
+		}
+		fromFile, err := parseGeoJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("服务范围文件 %s 有误: %v", cfg.GeoJSONFile, err)
+		}
+		polygons = append(polygons, fromFile...)
+	}
+
+	polygons = polygons.ToCanonicalCoord(coordSys)
+	return &Area{polygons: polygons, box: polygons.BoundingBox()}, nil
+}
+
+// parseGeoJSON 解析 Polygon、MultiPolygon、Feature，或由它们组成的 FeatureCollection
+func parseGeoJSON(data []byte) (utils.MultiPolygon, error) {
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("GeoJSON格式错误: %v", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return utils.ParsePolygonGeoJSON(data)
+	}
+	if len(collection.Features) == 0 {
+		return nil, errors.New("FeatureCollection 中没有要素")
+	}
+	var polygons utils.MultiPolygon
+	for i, feature := range collection.Features {
+		mp, err := utils.ParsePolygonGeoJSON(feature)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个要素: %v", i+1, err)
+		}
+		polygons = append(polygons, mp...)
+	}
+	return polygons, nil
+}
+
+// Contains 判断点（统一存储的坐标系）是否在服务范围内
+func (a *Area) Contains(lat, lng float64) bool {
+	return a.box.Contains(lat, lng) && a.polygons.Contains(lat, lng)
+}
+
+// Enabled 是否配置了服务范围
+func Enabled() bool {
+	return serviceArea != nil
+}
+
+// Contains 判断点（统一存储的坐标系）是否在服务范围内，未配置服务范围时总是返回true
+func Contains(lat, lng float64) bool {
+	return serviceArea == nil || serviceArea.Contains(lat, lng)
+}
@@ -0,0 +1,99 @@
+package geofence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"template/config"
+	"template/utils"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	collection := filepath.Join(dir, "area.geojson")
+	if err := os.WriteFile(collection, []byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[116,39],[117,39],[117,40],[116,40]]]}}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.geojson")
+	if err := os.WriteFile(empty, []byte(`{"type":"FeatureCollection","features":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 上海附近的正方形，坐标为统一存储的坐标系
+	square := [][][]float64{{{121, 31}, {122, 31}, {122, 32}, {121, 32}}}
+
+	tests := []struct {
+		name    string
+		cfg     *config.ServiceAreaConfig
+		wantNil bool
+		wantErr bool
+		inside  [][2]float64 // [纬度, 经度]
+		outside [][2]float64
+	}{
+		{name: "未配置", cfg: nil, wantNil: true},
+		{name: "没有多边形", cfg: &config.ServiceAreaConfig{CoordSys: "gcj02"}, wantNil: true},
+		{
+			name:    "配置文件中的多边形",
+			cfg:     &config.ServiceAreaConfig{Polygons: square, CoordSys: string(utils.CanonicalCoordSys)},
+			inside:  [][2]float64{{31.5, 121.5}},
+			outside: [][2]float64{{32.5, 121.5}, {39.5, 116.5}},
+		},
+		{
+			name:    "多边形与GeoJSON文件合并",
+			cfg:     &config.ServiceAreaConfig{Polygons: square, GeoJSONFile: collection, CoordSys: string(utils.CanonicalCoordSys)},
+			inside:  [][2]float64{{31.5, 121.5}, {39.5, 116.5}},
+			outside: [][2]float64{{35, 119}},
+		},
+		{
+			name: "按配置的坐标系转换",
+			// WGS84 的边界转换为 GCJ02 后向东偏移约400米，东边界外侧附近的点落入范围内
+			cfg:     &config.ServiceAreaConfig{Polygons: square, CoordSys: "wgs84"},
+			inside:  [][2]float64{{31.5, 122.002}},
+			outside: [][2]float64{{31.5, 121.002}},
+		},
+		{name: "不支持的坐标系", cfg: &config.ServiceAreaConfig{Polygons: square, CoordSys: "epsg3857"}, wantErr: true},
+		{name: "坐标不是两个数", cfg: &config.ServiceAreaConfig{Polygons: [][][]float64{{{121, 31, 0}, {122, 31}, {122, 32}}}}, wantErr: true},
+		{name: "点太少", cfg: &config.ServiceAreaConfig{Polygons: [][][]float64{{{121, 31}, {122, 31}}}}, wantErr: true},
+		{name: "文件不存在", cfg: &config.ServiceAreaConfig{GeoJSONFile: filepath.Join(dir, "missing.geojson")}, wantErr: true},
+		{name: "FeatureCollection为空", cfg: &config.ServiceAreaConfig{GeoJSONFile: empty}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area, err := Load(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (area == nil) != tt.wantNil {
+				t.Fatalf("Load() = %v, wantNil %v", area, tt.wantNil)
+			}
+			for _, p := range tt.inside {
+				if !area.Contains(p[0], p[1]) {
+					t.Errorf("%v 应在服务范围内", p)
+				}
+			}
+			for _, p := range tt.outside {
+				if area.Contains(p[0], p[1]) {
+					t.Errorf("%v 应在服务范围外", p)
+				}
+			}
+		})
+	}
+}
+
+func TestContainsWithoutServiceArea(t *testing.T) {
+	previous := serviceArea
+	defer func() { serviceArea = previous }()
+
+	serviceArea = nil
+	if Enabled() || !Contains(0, 0) {
+		t.Error("未配置服务范围时不应限制")
+	}
+	serviceArea, _ = Load(&config.ServiceAreaConfig{Polygons: [][][]float64{{{121, 31}, {122, 31}, {122, 32}}}, CoordSys: string(utils.CanonicalCoordSys)})
+	if !Enabled() || Contains(0, 0) || !Contains(31.2, 121.5) {
+		t.Error("配置服务范围后应按范围判断")
+	}
+}
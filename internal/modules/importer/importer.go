@@ -0,0 +1,222 @@
+// Package importer 从CSV或GeoJSON文件批量导入垃圾桶，供管理员导入接口和命令行工具共用
+package importer
+
+import (
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+
+	"template/ginServer/model"
+	"template/internal/modules/geofence"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// 默认参数
+const (
+	DefaultBatchSize       = 500
+	DefaultDuplicateRadius = 10.0 // 米
+)
+
+// 每行的导入结果
+const (
+	StatusAccepted = "accepted" // 已导入（试运行时为可以导入）
+	StatusSkipped  = "skipped"  // 与已有垃圾桶或文件中前面的记录重复，已跳过
+	StatusFailed   = "failed"   // 数据有误，无法导入
+)
+
+// InputError 导入文件本身有误（格式错误、缺少必需的列、记录过多等），错误信息可以直接返回给用户
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Options 导入选项
+type Options struct {
+	Format          string         // csv 或 geojson
+	Mapping         ColumnMapping  // 列映射
+	CoordSys        utils.CoordSys // 文件中经纬度使用的坐标系
+	DryRun          bool           // 试运行，只校验和检测重复，不写入数据库
+	BatchSize       int            // 每批插入的记录数，默认500
+	DuplicateRadius float64        // 该距离（米）以内已有垃圾桶时视为重复，默认10
+	MaxRows         int            // 最多导入的记录数，0表示不限制
+	UserID          *uint          // 记录为该用户上传
+	Index           *spatial.Index // 用于检测与已有垃圾桶重复的空间索引，为nil时使用服务的全局索引（只包含公开的垃圾桶）
+}
+
+// RowResult 一行的导入结果
+type RowResult struct {
+	Row         int    `json:"row"` // CSV中为行号（表头为第1行），GeoJSON中为要素序号（从1开始）
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	ID          uint   `json:"id,omitempty"`           // 导入后的垃圾桶ID
+	DuplicateOf uint   `json:"duplicate_of,omitempty"` // 重复的已有垃圾桶ID
+}
+
+// Report 导入报告
+type Report struct {
+	DryRun   bool        `json:"dry_run"`
+	Total    int         `json:"total"`
+	Accepted int         `json:"accepted"`
+	Skipped  int         `json:"skipped"`
+	Failed   int         `json:"failed"`
+	Rows     []RowResult `json:"rows"`
+}
+
+// Import 读取文件并导入垃圾桶
+// 逐行校验坐标，检测与已有垃圾桶及文件中前面记录的重复，然后在一个事务中分批插入；
+// 插入失败时整个事务回滚，返回错误。文件本身有误时返回 *InputError，单行数据的问题记录在报告中
+func Import(db *gorm.DB, r io.Reader, opts Options) (*Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.DuplicateRadius <= 0 {
+		opts.DuplicateRadius = DefaultDuplicateRadius
+	}
+
+	var records []record
+	var err error
+	switch opts.Format {
+	case FormatCSV:
+		records, err = readCSV(r, opts.Mapping)
+	case FormatGeoJSON:
+		records, err = readGeoJSON(r, opts.Mapping)
+	default:
+		err = fmt.Errorf("不支持的文件格式: %s，只支持 csv、geojson", opts.Format)
+	}
+	if err == nil && opts.MaxRows > 0 && len(records) > opts.MaxRows {
+		err = fmt.Errorf("文件中有 %d 条记录，单次最多导入 %d 条", len(records), opts.MaxRows)
+	}
+	if err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	report := &Report{DryRun: opts.DryRun, Rows: make([]RowResult, len(records))}
+	// 文件中已接受的记录，用于检测文件内部的重复，ID为记录在 records 中的下标+1
+	accepted := spatial.NewIndex()
+	accepted.Load(nil)
+	var trashCans []model.TrashCan
+	var acceptedRows []int
+
+	for i, rec := range records {
+		result := &report.Rows[i]
+		result.Row = rec.Row
+		if rec.Err == nil {
+			rec.Latitude, rec.Longitude, rec.Err = normalizeCoordinate(rec.Latitude, rec.Longitude, opts.CoordSys)
+		}
+		if rec.Err != nil {
+			result.Status, result.Reason = StatusFailed, rec.Err.Error()
+			continue
+		}
+
+		radius := opts.DuplicateRadius / 1000
+		existing, err := existingWithin(db, opts.Index, rec.Latitude, rec.Longitude, radius)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			result.Status, result.DuplicateOf = StatusSkipped, existing[0].ID
+			result.Reason = fmt.Sprintf("与已有垃圾桶 #%d 相距 %.1f 米", existing[0].ID, existing[0].Distance*1000)
+			continue
+		}
+		if previous := accepted.Within(rec.Latitude, rec.Longitude, radius); len(previous) > 0 {
+			result.Status = StatusSkipped
+			result.Reason = fmt.Sprintf("与文件中第 %d 行相距 %.1f 米", records[previous[0].ID-1].Row, previous[0].Distance*1000)
+			continue
+		}
+
+		accepted.Upsert(spatial.Point{ID: uint(i + 1), Lat: rec.Latitude, Lng: rec.Longitude})
+		result.Status = StatusAccepted
+		trashCans = append(trashCans, model.TrashCan{
+			UserID:         opts.UserID,
+			Latitude:       rec.Latitude,
+			Longitude:      rec.Longitude,
+			SourceCoordSys: string(opts.CoordSys),
+			Address:        rec.Address,
+			Description:    rec.Description,
+			Status:         model.StatusActive, // 管理员导入的数据无需审核
+		})
+		acceptedRows = append(acceptedRows, i)
+	}
+
+	if !opts.DryRun && len(trashCans) > 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.CreateInBatches(&trashCans, opts.BatchSize).Error
+		}); err != nil {
+			return nil, fmt.Errorf("写入数据库失败，已全部回滚: %v", err)
+		}
+		for k, i := range acceptedRows {
+			report.Rows[i].ID = trashCans[k].ID
+		}
+	}
+
+	report.Total = len(report.Rows)
+	for _, row := range report.Rows {
+		switch row.Status {
+		case StatusAccepted:
+			report.Accepted++
+		case StatusSkipped:
+			report.Skipped++
+		case StatusFailed:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// normalizeCoordinate 校验经纬度并转换为统一存储的坐标系
+func normalizeCoordinate(lat, lng float64, coordSys utils.CoordSys) (float64, float64, error) {
+	// 表格中缺失的坐标常被填为0，ValidateCoordinate 会拒绝 (0, 0)
+	if err := utils.ValidateCoordinate(lat, lng); err != nil {
+		return 0, 0, err
+	}
+	lat, lng = utils.ToCanonicalCoord(lat, lng, coordSys)
+	if !geofence.Contains(lat, lng) {
+		return 0, 0, geofence.ErrOutsideServiceArea
+	}
+	return lat, lng, nil
+}
+
+// existingWithin 查询半径（公里）内已有的垃圾桶，并到数据库中确认仍然存在（空间索引中可能残留已回滚的记录）
+func existingWithin(db *gorm.DB, idx *spatial.Index, lat, lng, radius float64) ([]spatial.Result, error) {
+	var matched []spatial.Result
+	if idx != nil {
+		matched = idx.Within(lat, lng, radius)
+	} else {
+		var err error
+		if matched, err = spatial.Within(lat, lng, radius); err != nil {
+			return nil, err
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(matched))
+	for _, r := range matched {
+		ids = append(ids, r.ID)
+	}
+	var existing []uint
+	if err := db.Model(&model.TrashCan{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+		return nil, err
+	}
+	existingSet := make(map[uint]struct{}, len(existing))
+	for _, id := range existing {
+		existingSet[id] = struct{}{}
+	}
+	results := matched[:0]
+	for _, r := range matched {
+		if _, ok := existingSet[r.ID]; ok {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"template/ginServer/model"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// newTestDB 创建内存数据库，已有一个位于人民广场的垃圾桶 #1，
+// 空间索引中还残留一个数据库中不存在的垃圾桶 #99（如已回滚的记录）
+func newTestDB(t *testing.T) (*gorm.DB, *spatial.Index) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库的每个连接都是独立的数据库
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.TrashCan{}); err != nil {
+		t.Fatal(err)
+	}
+	existing := model.TrashCan{ID: 1, Latitude: 31.2304, Longitude: 121.4737, Status: model.StatusActive}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+	idx := spatial.NewIndex()
+	idx.Load([]spatial.Point{
+		{ID: 1, Lat: existing.Latitude, Lng: existing.Longitude},
+		{ID: 99, Lat: 31.2400, Lng: 121.4800},
+	})
+	return db, idx
+}
+
+func TestImport(t *testing.T) {
+	const csvData = "lat,lng,address\n" +
+		"31.23041,121.47371,与已有垃圾桶重复\n" + // 第2行
+		"31.2400,121.4800,只与索引中残留的记录重复\n" + // 第3行
+		"31.2500,121.4900,新位置\n" + // 第4行
+		"31.25003,121.49002,与第4行重复\n" + // 第5行
+		"0,0,缺少坐标\n" + // 第6行
+		"91,121.47,纬度超出范围\n" // 第7行
+	wantRows := []RowResult{
+		{Row: 2, Status: StatusSkipped, DuplicateOf: 1},
+		{Row: 3, Status: StatusAccepted},
+		{Row: 4, Status: StatusAccepted},
+		{Row: 5, Status: StatusSkipped},
+		{Row: 6, Status: StatusFailed},
+		{Row: 7, Status: StatusFailed},
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		name := "导入"
+		if dryRun {
+			name = "试运行"
+		}
+		t.Run(name, func(t *testing.T) {
+			db, idx := newTestDB(t)
+			userID := uint(9)
+			report, err := Import(db, strings.NewReader(csvData), Options{
+				Format:   FormatCSV,
+				CoordSys: utils.CanonicalCoordSys,
+				DryRun:   dryRun,
+				UserID:   &userID,
+				Index:    idx,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.DryRun != dryRun || report.Total != 6 || report.Accepted != 2 || report.Skipped != 2 || report.Failed != 2 {
+				t.Errorf("报告 = %+v", report)
+			}
+			for i, want := range wantRows {
+				got := report.Rows[i]
+				if got.Row != want.Row || got.Status != want.Status || got.DuplicateOf != want.DuplicateOf {
+					t.Errorf("第 %d 行的结果 = %+v, want %+v", want.Row, got, want)
+				}
+				if got.Status != StatusAccepted && got.Reason == "" {
+					t.Errorf("第 %d 行没有原因", want.Row)
+				}
+				if (got.ID != 0) != (got.Status == StatusAccepted && !dryRun) {
+					t.Errorf("第 %d 行的ID = %d", want.Row, got.ID)
+				}
+			}
+
+			var trashCans []model.TrashCan
+			if err := db.Order("id").Find(&trashCans).Error; err != nil {
+				t.Fatal(err)
+			}
+			wantCount := 3
+			if dryRun {
+				wantCount = 1
+			}
+			if len(trashCans) != wantCount {
+				t.Fatalf("数据库中有 %d 个垃圾桶, want %d", len(trashCans), wantCount)
+			}
+			for _, tc := range trashCans[1:] {
+				if tc.UserID == nil || *tc.UserID != userID || tc.Status != model.StatusActive || tc.Geohash == "" {
+					t.Errorf("导入的垃圾桶 = %+v", tc)
+				}
+			}
+		})
+	}
+}
+
+func TestImportCoordSys(t *testing.T) {
+	db, idx := newTestDB(t)
+	// 人民广场的 WGS84 坐标，转换为 GCJ02 后与已有垃圾桶重复
+	lat, lng := utils.FromCanonicalCoord(31.2304, 121.4737, utils.CoordWGS84)
+	data := `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[` +
+		strconv.FormatFloat(lng, 'f', -1, 64) + `,` + strconv.FormatFloat(lat, 'f', -1, 64) + `]}}]}`
+	report, err := Import(db, strings.NewReader(data), Options{Format: FormatGeoJSON, CoordSys: utils.CoordWGS84, DryRun: true, Index: idx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows[0].Status != StatusSkipped || report.Rows[0].DuplicateOf != 1 {
+		t.Errorf("结果 = %+v, 应与已有垃圾桶 #1 重复", report.Rows[0])
+	}
+}
+
+func TestImportInputError(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts Options
+	}{
+		{"不支持的格式", "lat,lng\n31.25,121.49\n", Options{Format: "xlsx"}},
+		{"缺少必需的列", "address\na\n", Options{Format: FormatCSV}},
+		{"记录过多", "lat,lng\n31.25,121.49\n31.26,121.49\n", Options{Format: FormatCSV, MaxRows: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, idx := newTestDB(t)
+			tt.opts.Index = idx
+			_, err := Import(db, strings.NewReader(tt.data), tt.opts)
+			var inputErr *InputError
+			if !errors.As(err, &inputErr) {
+				t.Errorf("Import error = %v, want InputError", err)
+			}
+		})
+	}
+}
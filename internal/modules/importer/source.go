@@ -0,0 +1,243 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 导入文件格式
+const (
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+)
+
+// 可映射的字段
+const (
+	FieldLatitude    = "latitude"
+	FieldLongitude   = "longitude"
+	FieldAddress     = "address"
+	FieldDescription = "description"
+)
+
+// fieldAliases 未指定列映射时自动识别的列名（不区分大小写）
+var fieldAliases = map[string][]string{
+	FieldLatitude:    {"latitude", "lat", "纬度"},
+	FieldLongitude:   {"longitude", "lng", "lon", "经度"},
+	FieldAddress:     {"address", "地址", "位置"},
+	FieldDescription: {"description", "desc", "描述", "备注"},
+}
+
+// ColumnMapping 字段到CSV列名（GeoJSON中为属性名）的映射，未映射的字段按 fieldAliases 自动识别
+type ColumnMapping map[string]string
+
+// ParseColumnMapping 解析形如 "latitude=纬度,longitude=经度,address=地址" 的列映射
+// 字段名也可以使用简写 lat、lng
+func ParseColumnMapping(s string) (ColumnMapping, error) {
+	mapping := ColumnMapping{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("列映射格式错误: %s，应为 字段=列名", pair)
+		}
+		switch field {
+		case "lat":
+			field = FieldLatitude
+		case "lng", "lon":
+			field = FieldLongitude
+		}
+		if _, known := fieldAliases[field]; !known {
+			return nil, fmt.Errorf("未知的字段: %s，只支持 latitude、longitude、address、description", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// candidates 字段可能对应的列名，指定了映射时只使用映射的列名
+func (m ColumnMapping) candidates(field string) []string {
+	if column, ok := m[field]; ok {
+		return []string{column}
+	}
+	return fieldAliases[field]
+}
+
+// record 从文件中读出的一条记录
+type record struct {
+	Row         int // CSV中为行号（表头为第1行），GeoJSON中为要素序号（从1开始）
+	Latitude    float64
+	Longitude   float64
+	Address     string
+	Description string
+	Err         error // 解析失败的原因
+}
+
+// readCSV 读取CSV，第一行为表头，支持Excel导出的带BOM的UTF-8文件
+func readCSV(r io.Reader, mapping ColumnMapping) ([]record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV文件为空")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV表头失败: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make(map[string]int, 4)
+	for field := range fieldAliases {
+		index := -1
+		for _, name := range mapping.candidates(field) {
+			for i, h := range header {
+				if strings.EqualFold(strings.TrimSpace(h), name) {
+					index = i
+					break
+				}
+			}
+			if index >= 0 {
+				break
+			}
+		}
+		if index < 0 && (field == FieldLatitude || field == FieldLongitude) {
+			return nil, fmt.Errorf("CSV中找不到%s列（可识别的列名：%s），请通过列映射指定",
+				field, strings.Join(mapping.candidates(field), "、"))
+		}
+		columns[field] = index
+	}
+
+	var records []record
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				records = append(records, record{Row: parseErr.StartLine, Err: fmt.Errorf("CSV格式错误: %v", parseErr.Err)})
+				continue
+			}
+			return nil, err
+		}
+		if isBlank(values) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		column := func(field string) string {
+			if i := columns[field]; i >= 0 && i < len(values) {
+				return strings.TrimSpace(values[i])
+			}
+			return ""
+		}
+		rec := record{
+			Row:         line,
+			Address:     column(FieldAddress),
+			Description: column(FieldDescription),
+		}
+		rec.Latitude, rec.Err = parseCoordinate(column(FieldLatitude), "纬度")
+		if rec.Err == nil {
+			rec.Longitude, rec.Err = parseCoordinate(column(FieldLongitude), "经度")
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// isBlank 是否为空行
+func isBlank(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCoordinate 解析经度或纬度
+func parseCoordinate(s, name string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%s为空", name)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s格式错误: %s", name, s)
+	}
+	return v, nil
+}
+
+// geoJSONFeatureCollection 导入的 GeoJSON，要素的几何类型需为 Point
+type geoJSONFeatureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type     string `json:"type"`
+		Geometry *struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"features"`
+}
+
+// readGeoJSON 读取 GeoJSON FeatureCollection，地址和描述取自要素属性
+func readGeoJSON(r io.Reader, mapping ColumnMapping) ([]record, error) {
+	var fc geoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("GeoJSON格式错误: %v", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("GeoJSON 需要是 FeatureCollection")
+	}
+
+	records := make([]record, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		rec := record{
+			Row:         i + 1,
+			Address:     stringProperty(f.Properties, mapping.candidates(FieldAddress)),
+			Description: stringProperty(f.Properties, mapping.candidates(FieldDescription)),
+		}
+		var coordinates []float64
+		switch {
+		case f.Geometry == nil:
+			rec.Err = errors.New("缺少 geometry")
+		case f.Geometry.Type != "Point":
+			rec.Err = fmt.Errorf("不支持的几何类型: %s，仅支持 Point", f.Geometry.Type)
+		case json.Unmarshal(f.Geometry.Coordinates, &coordinates) != nil || len(coordinates) < 2:
+			rec.Err = errors.New("Point 坐标格式错误")
+		default:
+			rec.Longitude, rec.Latitude = coordinates[0], coordinates[1]
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// stringProperty 按候选属性名读取第一个存在的属性，转换为字符串
+func stringProperty(properties map[string]interface{}, names []string) string {
+	for _, name := range names {
+		for key, value := range properties {
+			if !strings.EqualFold(key, name) || value == nil {
+				continue
+			}
+			if s, ok := value.(string); ok {
+				return strings.TrimSpace(s)
+			}
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseColumnMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    ColumnMapping
+		wantErr bool
+	}{
+		{"空字符串", "", ColumnMapping{}, false},
+		{"完整字段名", "latitude=纬度,longitude=经度,address=地址", ColumnMapping{FieldLatitude: "纬度", FieldLongitude: "经度", FieldAddress: "地址"}, false},
+		{"简写和空格", " lat = Y , LON=X ,", ColumnMapping{FieldLatitude: "Y", FieldLongitude: "X"}, false},
+		{"缺少等号", "latitude", nil, true},
+		{"列名为空", "latitude=", nil, true},
+		{"未知字段", "name=名称", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseColumnMapping(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseColumnMapping(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseColumnMapping(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+			for field, column := range tt.want {
+				if got[field] != column {
+					t.Errorf("%s = %q, want %q", field, got[field], column)
+				}
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		mapping ColumnMapping
+		want    []record
+		wantErr bool
+	}{
+		{
+			name: "自动识别列名并去掉BOM",
+			data: "\ufeff经度,纬度,地址,备注\n121.47,31.23,人民广场,东门\n",
+			want: []record{{Row: 2, Latitude: 31.23, Longitude: 121.47, Address: "人民广场", Description: "东门"}},
+		},
+		{
+			name:    "指定列映射",
+			data:    "X,Y,lat\n121.47,31.23,0\n",
+			mapping: ColumnMapping{FieldLatitude: "Y", FieldLongitude: "X"},
+			want:    []record{{Row: 2, Latitude: 31.23, Longitude: 121.47}},
+		},
+		{
+			name: "跳过空行，多行字段不影响行号",
+			data: "lat,lng,address\n31.23,121.47,\"第一行\n第二行\"\n,,\n31.24,121.48,b\n",
+			want: []record{
+				{Row: 2, Latitude: 31.23, Longitude: 121.47, Address: "第一行\n第二行"},
+				{Row: 5, Latitude: 31.24, Longitude: 121.48, Address: "b"},
+			},
+		},
+		{
+			name: "坐标有误的行记录错误",
+			data: "lat,lng\nabc,121.47\n31.23,\n31.23\n",
+			want: []record{{Row: 2, Err: errMarker}, {Row: 3, Err: errMarker}, {Row: 4, Err: errMarker}},
+		},
+		{name: "文件为空", data: "", wantErr: true},
+		{name: "缺少经度列", data: "lat,address\n31.23,a\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCSV(strings.NewReader(tt.data), tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readCSV error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assertRecords(t, got, tt.want)
+		})
+	}
+}
+
+func TestReadGeoJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		mapping ColumnMapping
+		want    []record
+		wantErr bool
+	}{
+		{
+			name: "读取坐标和属性",
+			data: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[121.47,31.23]},"properties":{"Address":" 人民广场 ","desc":12}},
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[121.48,31.24,5]},"properties":null}]}`,
+			want: []record{
+				{Row: 1, Latitude: 31.23, Longitude: 121.47, Address: "人民广场", Description: "12"},
+				{Row: 2, Latitude: 31.24, Longitude: 121.48},
+			},
+		},
+		{
+			name:    "指定属性名",
+			data:    `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[121.47,31.23]},"properties":{"address":"a","name":"b"}}]}`,
+			mapping: ColumnMapping{FieldAddress: "name"},
+			want:    []record{{Row: 1, Latitude: 31.23, Longitude: 121.47, Address: "b"}},
+		},
+		{
+			name: "几何有误的要素记录错误",
+			data: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":null},
+				{"type":"Feature","geometry":{"type":"LineString","coordinates":[[121.47,31.23],[121.48,31.24]]}},
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[121.47]}}]}`,
+			want: []record{{Row: 1, Err: errMarker}, {Row: 2, Err: errMarker}, {Row: 3, Err: errMarker}},
+		},
+		{name: "不是FeatureCollection", data: `{"type":"Feature"}`, wantErr: true},
+		{name: "JSON格式错误", data: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readGeoJSON(strings.NewReader(tt.data), tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readGeoJSON error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assertRecords(t, got, tt.want)
+		})
+	}
+}
+
+// errMarker 表示期望该记录有错误，不比较具体的错误信息
+var errMarker = errors.New("任意错误")
+
+// assertRecords 比较读出的记录，期望有错误的记录只比较行号
+func assertRecords(t *testing.T, got, want []record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("记录数量 = %d, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if w.Err != nil {
+			if g.Err == nil || g.Row != w.Row {
+				t.Errorf("第 %d 条记录 = %+v, 应为第 %d 行的错误", i, g, w.Row)
+			}
+			continue
+		}
+		if g != w {
+			t.Errorf("第 %d 条记录 = %+v, want %+v", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package spatial
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"template/utils"
+)
+
+// rebuildMinChanges 增量变更达到该数量（且超过总量的1/16）时在后台重建KD树
+const rebuildMinChanges = 1024
+
+// Point 索引中的一个垃圾桶坐标
+type Point struct {
+	ID  uint
+	Lat float64
+	Lng float64
+}
+
+// Result 查询结果，Distance 单位为公里
+type Result struct {
+	Point
+	Distance float64
+}
+
+// Index 垃圾桶坐标的内存空间索引
+// 由一棵静态KD树和少量增量变更组成：新增或移动的点放在 extra 中线性扫描，
+// 树中已删除或已移动的点记录在 stale 中跳过，变更累积到一定数量后在后台重建KD树
+type Index struct {
+	mu         sync.RWMutex
+	points     map[uint]Point    // 当前全部有效的点
+	tree       *kdTree           // KD树，可能包含已失效的点
+	stale      map[uint]struct{} // KD树中已失效的点ID
+	extra      map[uint]Point    // 不在KD树中的新增或移动后的点
+	ready      bool
+	rebuilding bool
+	changed    map[uint]struct{} // 后台重建期间发生变更的点ID
+	listeners  []ChangeFunc
+}
+
+// ChangeFunc 索引变更回调：新增时 old 为nil，删除时 new 为nil，位置以外的属性变化时两者相同，
+// 两者均为nil表示索引被整体重新加载或无法确定变更范围，应使全部缓存失效
+type ChangeFunc func(old, new *Point)
+
+// NewIndex 创建空索引
+func NewIndex() *Index {
+	return &Index{
+		points: make(map[uint]Point),
+		tree:   &kdTree{},
+		stale:  make(map[uint]struct{}),
+		extra:  make(map[uint]Point),
+	}
+}
+
+// Load 用全部点重建索引，并将索引标记为可用
+func (idx *Index) Load(points []Point) {
+	tree := buildKDTree(points)
+	pointMap := make(map[uint]Point, len(points))
+	for _, p := range points {
+		pointMap[p.ID] = p
+	}
+
+	idx.mu.Lock()
+	idx.points = pointMap
+	idx.tree = tree
+	idx.stale = make(map[uint]struct{})
+	idx.extra = make(map[uint]Point)
+	idx.changed = nil
+	idx.rebuilding = false
+	idx.ready = true
+	listeners := idx.listeners
+	idx.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(nil, nil)
+	}
+}
+
+// OnChange 注册索引变更回调，可用于使依赖垃圾桶位置的缓存失效
+func (idx *Index) OnChange(fn ChangeFunc) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.listeners = append(idx.listeners, fn)
+}
+
+// Ready 索引是否已加载完成
+func (idx *Index) Ready() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.ready
+}
+
+// Len 索引中的点数量
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.points)
+}
+
+// Get 获取指定ID的点
+func (idx *Index) Get(id uint) (Point, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	p, ok := idx.points[id]
+	return p, ok
+}
+
+// Upsert 新增或更新一个点
+func (idx *Index) Upsert(p Point) {
+	idx.mu.Lock()
+	old, existed := idx.points[p.ID]
+	if existed && old == p {
+		idx.mu.Unlock()
+		return
+	}
+	idx.points[p.ID] = p
+	idx.stale[p.ID] = struct{}{}
+	idx.extra[p.ID] = p
+	idx.markChanged(p.ID)
+	listeners := idx.listeners
+	idx.mu.Unlock()
+
+	var oldPoint *Point
+	if existed {
+		oldPoint = &old
+	}
+	for _, fn := range listeners {
+		fn(oldPoint, &p)
+	}
+}
+
+// Remove 删除一个点
+func (idx *Index) Remove(id uint) {
+	idx.mu.Lock()
+	old, ok := idx.points[id]
+	if !ok {
+		idx.mu.Unlock()
+		return
+	}
+	delete(idx.points, id)
+	delete(idx.extra, id)
+	idx.stale[id] = struct{}{}
+	idx.markChanged(id)
+	listeners := idx.listeners
+	idx.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(&old, nil)
+	}
+}
+
+// Touch 通知监听者该点位置以外的属性（如点赞数、分类）发生了变化，用于使依赖这些属性的缓存失效
+func (idx *Index) Touch(id uint) {
+	idx.mu.RLock()
+	p, ok := idx.points[id]
+	listeners := idx.listeners
+	idx.mu.RUnlock()
+	if !ok {
+		return
+	}
+	for _, fn := range listeners {
+		fn(&p, &p)
+	}
+}
+
+// TouchAll 通知监听者无法确定哪些点的其他属性发生了变化，监听者应使全部缓存失效
+func (idx *Index) TouchAll() {
+	idx.mu.RLock()
+	listeners := idx.listeners
+	idx.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(nil, nil)
+	}
+}
+
+// markChanged 记录变更，必要时触发后台重建（需持有写锁）
+func (idx *Index) markChanged(id uint) {
+	if idx.rebuilding {
+		idx.changed[id] = struct{}{}
+		return
+	}
+
+	pending := len(idx.stale) + len(idx.extra)
+	if pending < rebuildMinChanges || pending < len(idx.points)/16 {
+		return
+	}
+
+	idx.rebuilding = true
+	idx.changed = make(map[uint]struct{})
+	snapshot := make([]Point, 0, len(idx.points))
+	for _, p := range idx.points {
+		snapshot = append(snapshot, p)
+	}
+	go idx.rebuild(snapshot)
+}
+
+// rebuild 在后台用快照重建KD树，完成后将重建期间的变更重新放回增量部分
+func (idx *Index) rebuild(snapshot []Point) {
+	tree := buildKDTree(snapshot)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.rebuilding {
+		// 重建期间索引已被 Load 整体替换
+		return
+	}
+	idx.tree = tree
+	idx.stale = make(map[uint]struct{}, len(idx.changed))
+	idx.extra = make(map[uint]Point, len(idx.changed))
+	for id := range idx.changed {
+		idx.stale[id] = struct{}{}
+		if p, ok := idx.points[id]; ok {
+			idx.extra[id] = p
+		}
+	}
+	idx.changed = nil
+	idx.rebuilding = false
+}
+
+// Within 查询半径（公里）范围内的所有点，按距离由近到远排序
+func (idx *Index) Within(lat, lng, radius float64) []Result {
+	if radius < 0 {
+		return nil
+	}
+	q := toXYZ(lat, lng)
+	maxSq := chordSquared(radius)
+
+	idx.mu.RLock()
+	var results []Result
+	idx.tree.within(q, maxSq, func(p *kdPoint) {
+		if _, ok := idx.stale[p.ID]; !ok {
+			results = append(results, Result{Point: p.Point})
+		}
+	})
+	for _, p := range idx.extra {
+		if squaredDistance(q, toXYZ(p.Lat, p.Lng)) <= maxSq {
+			results = append(results, Result{Point: p})
+		}
+	}
+	idx.mu.RUnlock()
+
+	// 使用与数据库查询一致的Haversine公式计算精确距离
+	filtered := results[:0]
+	for _, r := range results {
+		r.Distance = utils.CalculateDistance(lat, lng, r.Lat, r.Lng)
+		if r.Distance <= radius {
+			filtered = append(filtered, r)
+		}
+	}
+	SortByDistance(filtered)
+	return filtered
+}
+
+// Nearest 查询最近的 k 个点，maxDistance 为最大距离（公里），小于等于0表示不限制
+func (idx *Index) Nearest(lat, lng float64, k int, maxDistance float64) []Result {
+	return idx.NearestAfter(lat, lng, k, maxDistance, nil)
+}
+
+// NearestAfter 查询按（距离, ID）排序位于 after 之后最近的 k 个点，after 为nil时与 Nearest 相同
+// 用于从上一页的最后一个点继续向外分页查询
+func (idx *Index) NearestAfter(lat, lng float64, k int, maxDistance float64, after *Result) []Result {
+	if k <= 0 {
+		return nil
+	}
+	q := toXYZ(lat, lng)
+	maxSq := math.Inf(1)
+	if maxDistance > 0 {
+		maxSq = chordSquared(maxDistance)
+	}
+	behind := func(p Point) bool {
+		if after == nil {
+			return false
+		}
+		r := Result{Point: p, Distance: utils.CalculateDistance(lat, lng, p.Lat, p.Lng)}
+		return !r.After(after)
+	}
+
+	idx.mu.RLock()
+	h := neighborHeap(idx.tree.nearest(q, k, maxSq, func(p Point) bool {
+		if _, ok := idx.stale[p.ID]; ok {
+			return true
+		}
+		return behind(p)
+	}))
+	for _, p := range idx.extra {
+		if d := squaredDistance(q, toXYZ(p.Lat, p.Lng)); d <= maxSq && !behind(p) {
+			h.offer(neighbor{point: p, sq: d}, k)
+		}
+	}
+	idx.mu.RUnlock()
+
+	results := make([]Result, 0, len(h))
+	for _, n := range h {
+		r := Result{Point: n.point, Distance: utils.CalculateDistance(lat, lng, n.point.Lat, n.point.Lng)}
+		if maxDistance > 0 && r.Distance > maxDistance {
+			continue
+		}
+		results = append(results, r)
+	}
+	SortByDistance(results)
+	return results
+}
+
+// After 按（距离, ID）排序时结果是否位于 cursor 之后，cursor 为nil时总是返回true
+func (r Result) After(cursor *Result) bool {
+	if cursor == nil {
+		return true
+	}
+	if r.Distance != cursor.Distance {
+		return r.Distance > cursor.Distance
+	}
+	return r.ID > cursor.ID
+}
+
+// SortByDistance 按距离排序，距离相同时按ID排序保证结果稳定
+func SortByDistance(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].ID < results[j].ID
+	})
+}
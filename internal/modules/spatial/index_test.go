@@ -0,0 +1,294 @@
+package spatial
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"template/utils"
+)
+
+// randomPoints 在中心点附近生成 n 个随机点，spread 为经纬度的最大偏移（度）
+func randomPoints(rng *rand.Rand, n int, firstID uint, lat, lng, spread float64) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			ID:  firstID + uint(i),
+			Lat: math.Max(-90, math.Min(90, lat+(rng.Float64()*2-1)*spread)),
+			Lng: utils.NormalizeLng(lng + (rng.Float64()*2-1)*spread),
+		}
+	}
+	return points
+}
+
+// testPoints 测试使用的点集：上海市区、180度经线两侧、北极附近，以及坐标完全相同的点
+func testPoints() []Point {
+	rng := rand.New(rand.NewSource(1))
+	var points []Point
+	points = append(points, randomPoints(rng, 2000, 1, 31.23, 121.47, 0.2)...)
+	points = append(points, randomPoints(rng, 300, 10001, -16.5, 180, 0.5)...)
+	points = append(points, randomPoints(rng, 300, 20001, 89.8, 0, 0.2)...)
+	for i := uint(0); i < 20; i++ {
+		points = append(points, Point{ID: 30001 + i, Lat: 31.2304, Lng: 121.4737})
+	}
+	return points
+}
+
+// bruteForce 计算全部点到查询点的距离，按（距离, ID）排序
+func bruteForce(points []Point, lat, lng float64) []Result {
+	results := make([]Result, len(points))
+	for i, p := range points {
+		results[i] = Result{Point: p, Distance: utils.CalculateDistance(lat, lng, p.Lat, p.Lng)}
+	}
+	SortByDistance(results)
+	return results
+}
+
+var testQueries = []struct {
+	name     string
+	lat, lng float64
+}{
+	{"上海市中心（有重复坐标）", 31.2304, 121.4737},
+	{"上海郊区", 31.05, 121.3},
+	{"180度经线东侧", -16.5, -179.9},
+	{"180度经线西侧", -16.6, 179.95},
+	{"北极", 90, 0},
+	{"远离所有点", -45, -60},
+}
+
+func TestIndexNearest(t *testing.T) {
+	points := testPoints()
+	idx := NewIndex()
+	idx.Load(points)
+
+	for _, q := range testQueries {
+		all := bruteForce(points, q.lat, q.lng)
+		for _, tt := range []struct {
+			k           int
+			maxDistance float64
+		}{
+			{1, 0}, {10, 0}, {50, 0}, {25, 1}, {100, 30}, {len(points) + 10, 0},
+		} {
+			var want []Result
+			for _, r := range all {
+				if tt.maxDistance > 0 && r.Distance > tt.maxDistance {
+					break
+				}
+				if len(want) == tt.k {
+					break
+				}
+				want = append(want, r)
+			}
+
+			got := idx.Nearest(q.lat, q.lng, tt.k, tt.maxDistance)
+			assertSameResults(t, q.name, got, want)
+		}
+	}
+
+	if got := idx.Nearest(31.2304, 121.4737, 0, 0); len(got) != 0 {
+		t.Errorf("k=0 时返回了 %d 个结果", len(got))
+	}
+}
+
+func TestIndexNearestAfter(t *testing.T) {
+	points := testPoints()
+	idx := NewIndex()
+	idx.Load(points)
+
+	for _, q := range testQueries {
+		for _, tt := range []struct {
+			pageSize    int
+			maxDistance float64
+		}{
+			{7, 2}, {1, 0.5}, {64, 50}, {500, 0},
+		} {
+			var want []Result
+			for _, r := range bruteForce(points, q.lat, q.lng) {
+				if tt.maxDistance > 0 && r.Distance > tt.maxDistance {
+					break
+				}
+				want = append(want, r)
+			}
+
+			// 逐页取出全部结果，应与一次性排序的结果完全一致，不重复也不遗漏
+			var got []Result
+			var after *Result
+			for page := 0; ; page++ {
+				batch := idx.NearestAfter(q.lat, q.lng, tt.pageSize, tt.maxDistance, after)
+				got = append(got, batch...)
+				if len(batch) < tt.pageSize {
+					break
+				}
+				if page > len(points) {
+					t.Fatalf("%s: 分页没有结束", q.name)
+				}
+				after = &batch[len(batch)-1]
+			}
+			assertSameResults(t, q.name, got, want)
+		}
+	}
+}
+
+func TestIndexWithin(t *testing.T) {
+	points := testPoints()
+	idx := NewIndex()
+	idx.Load(points)
+
+	for _, q := range testQueries {
+		for _, radius := range []float64{0, 0.1, 1, 5, 100} {
+			var want []Result
+			for _, r := range bruteForce(points, q.lat, q.lng) {
+				if r.Distance > radius {
+					break
+				}
+				want = append(want, r)
+			}
+			assertSameResults(t, q.name, idx.Within(q.lat, q.lng, radius), want)
+		}
+	}
+
+	if got := idx.Within(31.2304, 121.4737, -1); got != nil {
+		t.Errorf("半径为负时返回了 %d 个结果", len(got))
+	}
+}
+
+func TestIndexIncrementalChanges(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	initial := randomPoints(rng, 3000, 1, 31.23, 121.47, 0.2)
+	idx := NewIndex()
+	idx.Load(initial)
+
+	var changes int
+	idx.OnChange(func(old, new *Point) { changes++ })
+
+	current := make(map[uint]Point, len(initial))
+	for _, p := range initial {
+		current[p.ID] = p
+	}
+	// 变更数量超过 rebuildMinChanges，会触发后台重建；重建前后查询结果都应正确
+	for i := 0; i < 2*rebuildMinChanges; i++ {
+		switch rng.Intn(3) {
+		case 0: // 新增
+			p := randomPoints(rng, 1, uint(10000+i), 31.23, 121.47, 0.2)[0]
+			idx.Upsert(p)
+			current[p.ID] = p
+		case 1: // 移动，可能移动已删除的点（相当于重新新增）
+			id := initial[rng.Intn(len(initial))].ID
+			p := randomPoints(rng, 1, id, 31.23, 121.47, 0.2)[0]
+			idx.Upsert(p)
+			current[p.ID] = p
+		default: // 删除，可能删除已删除的点
+			id := initial[rng.Intn(len(initial))].ID
+			idx.Remove(id)
+			delete(current, id)
+		}
+	}
+	if idx.Len() != len(current) {
+		t.Fatalf("Len() = %d, want %d", idx.Len(), len(current))
+	}
+	points := make([]Point, 0, len(current))
+	for _, p := range current {
+		points = append(points, p)
+		if got, ok := idx.Get(p.ID); !ok || got != p {
+			t.Fatalf("Get(%d) = %+v, %v, want %+v", p.ID, got, ok, p)
+		}
+	}
+	for _, q := range testQueries[:2] {
+		all := bruteForce(points, q.lat, q.lng)
+		assertSameResults(t, q.name, idx.Nearest(q.lat, q.lng, 50, 0), all[:50])
+		var want []Result
+		for _, r := range all {
+			if r.Distance > 1 {
+				break
+			}
+			want = append(want, r)
+		}
+		assertSameResults(t, q.name, idx.Within(q.lat, q.lng, 1), want)
+	}
+	if changes == 0 {
+		t.Error("变更回调没有被调用")
+	}
+
+	// Touch 通知位置不变的变更，不存在的点不通知
+	var touched []*Point
+	idx.OnChange(func(old, new *Point) { touched = append(touched, old, new) })
+	idx.Touch(points[0].ID)
+	idx.Touch(math.MaxUint32)
+	if len(touched) != 2 || *touched[0] != points[0] || *touched[1] != points[0] {
+		t.Errorf("Touch 通知的变更 = %v, want 两次 %+v", touched, points[0])
+	}
+}
+
+// assertSameResults 比较两组查询结果的ID、顺序和距离
+func assertSameResults(t *testing.T, name string, got, want []Result) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: 结果数量 = %d, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID || math.Abs(got[i].Distance-want[i].Distance) > 1e-9 {
+			t.Fatalf("%s: 第 %d 个结果 = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// benchmarkIndex 在上海市区约40公里见方的范围内生成 n 个点并建立索引，与 scripts/bench_nearby.go 的测试数据分布一致
+func benchmarkIndex(b *testing.B, n int) *Index {
+	b.Helper()
+	idx := NewIndex()
+	idx.Load(randomPoints(rand.New(rand.NewSource(1)), n, 1, 31.2304, 121.4737, 0.2))
+	return idx
+}
+
+// BenchmarkNearest 附近搜索（GET /api/trashcans/nearby）的第一页：查询1公里内最近的20个垃圾桶
+func BenchmarkNearest(b *testing.B) {
+	for _, n := range []int{10000, 100000, 1000000} {
+		idx := benchmarkIndex(b, n)
+		rng := rand.New(rand.NewSource(2))
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lat := 31.2304 + (rng.Float64()-0.5)*0.4
+				lng := 121.4737 + (rng.Float64()-0.5)*0.4
+				idx.Nearest(lat, lng, 20, 1)
+			}
+		})
+	}
+}
+
+// BenchmarkNearestAfter 附近搜索按游标翻到第5页
+func BenchmarkNearestAfter(b *testing.B) {
+	for _, n := range []int{10000, 100000, 1000000} {
+		idx := benchmarkIndex(b, n)
+		rng := rand.New(rand.NewSource(2))
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lat := 31.2304 + (rng.Float64()-0.5)*0.4
+				lng := 121.4737 + (rng.Float64()-0.5)*0.4
+				var after *Result
+				for page := 0; page < 5; page++ {
+					results := idx.NearestAfter(lat, lng, 20, 1, after)
+					if len(results) < 20 {
+						break
+					}
+					after = &results[len(results)-1]
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWithin 查询1公里内的全部垃圾桶，创建垃圾桶时的重复检测使用该查询
+func BenchmarkWithin(b *testing.B) {
+	for _, n := range []int{10000, 100000, 1000000} {
+		idx := benchmarkIndex(b, n)
+		rng := rand.New(rand.NewSource(2))
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lat := 31.2304 + (rng.Float64()-0.5)*0.4
+				lng := 121.4737 + (rng.Float64()-0.5)*0.4
+				idx.Within(lat, lng, 1)
+			}
+		})
+	}
+}
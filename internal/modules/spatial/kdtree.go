@@ -0,0 +1,208 @@
+package spatial
+
+import (
+	"container/heap"
+	"math"
+
+	"template/utils"
+)
+
+// kdPoint KD树中的点，坐标转换为单位球面上的三维坐标
+// 三维空间中的直线距离与球面距离单调对应，因此无需处理180度经线和极点附近的特殊情况
+type kdPoint struct {
+	Point
+	xyz [3]float64
+}
+
+func newKDPoint(p Point) kdPoint {
+	return kdPoint{Point: p, xyz: toXYZ(p.Lat, p.Lng)}
+}
+
+func toXYZ(lat, lng float64) [3]float64 {
+	latRad := lat * math.Pi / 180.0
+	lngRad := lng * math.Pi / 180.0
+	return [3]float64{
+		math.Cos(latRad) * math.Cos(lngRad),
+		math.Cos(latRad) * math.Sin(lngRad),
+		math.Sin(latRad),
+	}
+}
+
+// chordSquared 球面距离（公里）对应的单位球弦长的平方
+func chordSquared(distance float64) float64 {
+	angle := distance / utils.EarthRadius
+	if angle >= math.Pi {
+		return 4
+	}
+	c := 2 * math.Sin(angle/2)
+	return c * c
+}
+
+func squaredDistance(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// kdTree 隐式存储的静态KD树：区间 [lo, hi) 的中点为该子树的根节点，切分维度为深度对3取模
+type kdTree struct {
+	points []kdPoint
+}
+
+func buildKDTree(points []Point) *kdTree {
+	t := &kdTree{points: make([]kdPoint, len(points))}
+	for i, p := range points {
+		t.points[i] = newKDPoint(p)
+	}
+	t.build(0, len(t.points), 0)
+	return t
+}
+
+func (t *kdTree) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	mid := (lo + hi) / 2
+	t.selectNth(lo, hi, mid, depth%3)
+	t.build(lo, mid, depth+1)
+	t.build(mid+1, hi, depth+1)
+}
+
+// selectNth 快速选择：使第 n 个元素就位，左侧均不大于它，右侧均不小于它
+func (t *kdTree) selectNth(lo, hi, n, axis int) {
+	pts := t.points
+	for hi-lo > 1 {
+		// 三数取中作为枢轴
+		mid := (lo + hi) / 2
+		if pts[mid].xyz[axis] < pts[lo].xyz[axis] {
+			pts[mid], pts[lo] = pts[lo], pts[mid]
+		}
+		if pts[hi-1].xyz[axis] < pts[lo].xyz[axis] {
+			pts[hi-1], pts[lo] = pts[lo], pts[hi-1]
+		}
+		if pts[hi-1].xyz[axis] < pts[mid].xyz[axis] {
+			pts[hi-1], pts[mid] = pts[mid], pts[hi-1]
+		}
+		pivot := pts[mid].xyz[axis]
+
+		i, j := lo, hi-1
+		for i <= j {
+			for pts[i].xyz[axis] < pivot {
+				i++
+			}
+			for pts[j].xyz[axis] > pivot {
+				j--
+			}
+			if i <= j {
+				pts[i], pts[j] = pts[j], pts[i]
+				i++
+				j--
+			}
+		}
+		if n <= j {
+			hi = j + 1
+		} else if n >= i {
+			lo = i
+		} else {
+			return
+		}
+	}
+}
+
+// within 查找弦长平方不超过 maxSq 的所有点
+func (t *kdTree) within(q [3]float64, maxSq float64, visit func(p *kdPoint)) {
+	t.withinRange(q, maxSq, 0, len(t.points), 0, visit)
+}
+
+func (t *kdTree) withinRange(q [3]float64, maxSq float64, lo, hi, depth int, visit func(p *kdPoint)) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	p := &t.points[mid]
+	if squaredDistance(q, p.xyz) <= maxSq {
+		visit(p)
+	}
+
+	axis := depth % 3
+	diff := q[axis] - p.xyz[axis]
+	if diff <= 0 {
+		t.withinRange(q, maxSq, lo, mid, depth+1, visit)
+		if diff*diff <= maxSq {
+			t.withinRange(q, maxSq, mid+1, hi, depth+1, visit)
+		}
+	} else {
+		t.withinRange(q, maxSq, mid+1, hi, depth+1, visit)
+		if diff*diff <= maxSq {
+			t.withinRange(q, maxSq, lo, mid, depth+1, visit)
+		}
+	}
+}
+
+// nearest 查找距离最近的 k 个点，skip 返回 true 的点不参与计算
+func (t *kdTree) nearest(q [3]float64, k int, maxSq float64, skip func(p Point) bool) []neighbor {
+	h := &neighborHeap{}
+	t.nearestRange(q, k, maxSq, 0, len(t.points), 0, skip, h)
+	return *h
+}
+
+func (t *kdTree) nearestRange(q [3]float64, k int, maxSq float64, lo, hi, depth int, skip func(p Point) bool, h *neighborHeap) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	p := &t.points[mid]
+	if d := squaredDistance(q, p.xyz); d <= maxSq && !skip(p.Point) {
+		h.offer(neighbor{point: p.Point, sq: d}, k)
+	}
+
+	axis := depth % 3
+	diff := q[axis] - p.xyz[axis]
+	near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+	if diff > 0 {
+		near, far = far, near
+	}
+	t.nearestRange(q, k, maxSq, near[0], near[1], depth+1, skip, h)
+	// 与堆顶距离相同时另一侧仍可能有ID更小的点（如坐标完全相同的垃圾桶），需要继续查找
+	if diff*diff <= maxSq && (h.Len() < k || diff*diff <= (*h)[0].sq) {
+		t.nearestRange(q, k, maxSq, far[0], far[1], depth+1, skip, h)
+	}
+}
+
+// neighbor 近邻查询的候选结果
+type neighbor struct {
+	point Point
+	sq    float64
+}
+
+// neighborHeap 按距离排列的大顶堆，堆顶为当前候选中最远的点
+type neighborHeap []neighbor
+
+func (h neighborHeap) Len() int { return len(h) }
+func (h neighborHeap) Less(i, j int) bool {
+	if h[i].sq != h[j].sq {
+		return h[i].sq > h[j].sq
+	}
+	return h[i].point.ID > h[j].point.ID
+}
+func (h neighborHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x interface{}) { *h = append(*h, x.(neighbor)) }
+func (h *neighborHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// offer 候选点加入堆中，超过 k 个时淘汰最远的点
+func (h *neighborHeap) offer(n neighbor, k int) {
+	if h.Len() < k {
+		heap.Push(h, n)
+		return
+	}
+	top := (*h)[0]
+	if n.sq < top.sq || (n.sq == top.sq && n.point.ID < top.point.ID) {
+		(*h)[0] = n
+		heap.Fix(h, 0)
+	}
+}
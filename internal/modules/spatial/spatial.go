@@ -0,0 +1,359 @@
+package spatial
+
+import (
+	"math"
+	"reflect"
+
+	"gorm.io/gorm"
+
+	"template/ginServer/model"
+	"template/global"
+	"template/utils"
+)
+
+// TrashCanIndex 公开的（正常、被报告不存在）垃圾桶坐标的空间索引，启动时加载，之后随垃圾桶的增删改增量更新
+// 待审核和已撤除的垃圾桶不在索引中，基于索引的查询无需再按状态过滤
+var TrashCanIndex = NewIndex()
+
+// Init 从数据库加载索引，并注册GORM回调以便在写入垃圾桶时同步更新索引
+func Init() error {
+	if err := registerCallbacks(global.DB, TrashCanIndex); err != nil {
+		return err
+	}
+	points, err := loadPoints(global.DB)
+	if err != nil {
+		return err
+	}
+	TrashCanIndex.Load(points)
+	global.SugarLogger.Infof("空间索引加载完成，共 %d 个垃圾桶", len(points))
+	return nil
+}
+
+// LoadIndex 从指定数据库构建一个独立的索引，供命令行工具等场景使用
+func LoadIndex(db *gorm.DB) (*Index, error) {
+	points, err := loadPoints(db)
+	if err != nil {
+		return nil, err
+	}
+	idx := NewIndex()
+	idx.Load(points)
+	return idx, nil
+}
+
+// Nearest 查询最近的 k 个垃圾桶，索引未就绪时回退到数据库查询
+func Nearest(lat, lng float64, k int, maxDistance float64) ([]Result, error) {
+	return NearestAfter(lat, lng, k, maxDistance, nil)
+}
+
+// NearestAfter 查询按（距离, ID）排序位于 after 之后最近的 k 个垃圾桶，索引未就绪时回退到数据库查询
+func NearestAfter(lat, lng float64, k int, maxDistance float64, after *Result) ([]Result, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	if TrashCanIndex.Ready() {
+		return TrashCanIndex.NearestAfter(lat, lng, k, maxDistance, after), nil
+	}
+	if maxDistance <= 0 {
+		maxDistance = math.Pi * utils.EarthRadius
+	}
+	results, err := QueryWithin(global.DB.Scopes(model.PublicStatus), lat, lng, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := results[:0]
+	for _, r := range results {
+		if r.After(after) {
+			filtered = append(filtered, r)
+		}
+	}
+	if len(filtered) > k {
+		filtered = filtered[:k]
+	}
+	return filtered, nil
+}
+
+// Within 查询半径（公里）范围内的全部垃圾桶，索引未就绪时回退到数据库查询
+func Within(lat, lng, radius float64) ([]Result, error) {
+	if TrashCanIndex.Ready() {
+		return TrashCanIndex.Within(lat, lng, radius), nil
+	}
+	return QueryWithin(global.DB.Scopes(model.PublicStatus), lat, lng, radius)
+}
+
+// QueryWithin 在数据库中按矩形范围粗筛，再精确计算距离，结果按（距离, ID）排序
+// 不经过索引，可以通过 db 附加索引之外的条件（如包含待审核的垃圾桶）
+func QueryWithin(db *gorm.DB, lat, lng, radius float64) ([]Result, error) {
+	var candidates []struct {
+		ID        uint
+		Latitude  float64
+		Longitude float64
+	}
+	if err := db.Model(&model.TrashCan{}).
+		Scopes(model.InBoundingBox(utils.NewBoundingBox(lat, lng, radius))).
+		Select("id, latitude, longitude").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(candidates))
+	for _, c := range candidates {
+		distance := utils.CalculateDistance(lat, lng, c.Latitude, c.Longitude)
+		if distance <= radius {
+			results = append(results, Result{
+				Point:    Point{ID: c.ID, Lat: c.Latitude, Lng: c.Longitude},
+				Distance: distance,
+			})
+		}
+	}
+	SortByDistance(results)
+	return results, nil
+}
+
+// loadPoints 分批读取全部公开的垃圾桶坐标
+func loadPoints(db *gorm.DB) ([]Point, error) {
+	var points []Point
+	var batch []model.TrashCan
+	err := db.Scopes(model.PublicStatus).Select("id, latitude, longitude").
+		FindInBatches(&batch, 5000, func(tx *gorm.DB, _ int) error {
+			for _, tc := range batch {
+				points = append(points, Point{ID: tc.ID, Lat: tc.Latitude, Lng: tc.Longitude})
+			}
+			return nil
+		}).Error
+	return points, err
+}
+
+// registerCallbacks 注册GORM回调，垃圾桶创建、更新、删除后同步更新索引，点赞点踩写入后通知监听者
+// 回调在事务内执行，变更先暂存在事务上，提交成功后才应用到索引，事务回滚时索引保持不变
+func registerCallbacks(db *gorm.DB, idx *Index) error {
+	trackTransactions(db, idx)
+
+	if err := db.Callback().Create().After("gorm:create").
+		Register("spatial:after_create", func(tx *gorm.DB) {
+			if isVoteWrite(tx) {
+				touch(tx, idx, voteTrashCanIDs(tx))
+				return
+			}
+			if !isTrashCanWrite(tx) {
+				return
+			}
+			changes := pendingChanges(tx)
+			eachTrashCan(tx, func(p Point, status model.TrashCanStatus) {
+				// 未设置状态时使用数据库默认值（正常）
+				if status != "" && !status.Public() {
+					return
+				}
+				if changes != nil {
+					changes.upsert(p)
+					return
+				}
+				idx.Upsert(p)
+			})
+		}); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().After("gorm:update").
+		Register("spatial:after_update", func(tx *gorm.DB) {
+			if isVoteWrite(tx) {
+				touch(tx, idx, voteTrashCanIDs(tx))
+				return
+			}
+			if !isTrashCanWrite(tx) {
+				return
+			}
+			ids := trashCanIDs(tx)
+			if !touchesIndex(tx) {
+				// 分类、封面等位置以外的属性变化，通知监听者使依赖这些属性的缓存失效
+				touch(tx, idx, ids)
+				return
+			}
+			changes := pendingChanges(tx)
+			switch {
+			case len(ids) == 0 && changes != nil:
+				// 无法确定更新了哪些记录（例如按条件批量更新），提交后整体重新加载
+				changes.reloadAll()
+			case len(ids) == 0:
+				reload(tx, idx)
+			case changes != nil:
+				changes.refresh(ids)
+			default:
+				refresh(tx, idx, ids)
+			}
+		}); err != nil {
+		return err
+	}
+
+	return db.Callback().Delete().After("gorm:delete").
+		Register("spatial:after_delete", func(tx *gorm.DB) {
+			if isVoteWrite(tx) {
+				touch(tx, idx, voteTrashCanIDs(tx))
+				return
+			}
+			if !isTrashCanWrite(tx) {
+				return
+			}
+			changes := pendingChanges(tx)
+			ids := trashCanIDs(tx)
+			switch {
+			case len(ids) == 0 && changes != nil:
+				changes.reloadAll()
+			case len(ids) == 0:
+				reload(tx, idx)
+			case changes != nil:
+				// 提交后重新读取时已不存在的ID会从索引中删除
+				changes.refresh(ids)
+			default:
+				for _, id := range ids {
+					idx.Remove(id)
+				}
+			}
+		})
+}
+
+func isTrashCanWrite(tx *gorm.DB) bool {
+	return tx.Error == nil && tx.Statement.Schema != nil &&
+		tx.Statement.Schema.Table == model.TrashCan{}.TableName()
+}
+
+// isVoteWrite 是否写入了点赞点踩，点赞数包含在聚合、瓦片等缓存的内容中
+func isVoteWrite(tx *gorm.DB) bool {
+	return tx.Error == nil && tx.Statement.Schema != nil &&
+		tx.Statement.Schema.ModelType == reflect.TypeOf(model.TrashCanLike{})
+}
+
+// touchesIndex 本次更新是否可能修改了经纬度或状态（决定是否在索引中）
+func touchesIndex(tx *gorm.DB) bool {
+	if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		_, hasLat := updates["latitude"]
+		_, hasLng := updates["longitude"]
+		_, hasStatus := updates["status"]
+		return hasLat || hasLng || hasStatus
+	}
+	return true
+}
+
+// touch 通知监听者指定垃圾桶位置以外的属性发生了变化，ids 为空表示无法确定涉及哪些垃圾桶
+func touch(tx *gorm.DB, idx *Index, ids []uint) {
+	changes := pendingChanges(tx)
+	switch {
+	case len(ids) == 0 && changes != nil:
+		changes.touchAll()
+	case len(ids) == 0:
+		idx.TouchAll()
+	case changes != nil:
+		changes.touch(ids)
+	default:
+		for _, id := range ids {
+			idx.Touch(id)
+		}
+	}
+}
+
+// eachRecord 遍历本次写入的记录（支持单条和批量写入）
+func eachRecord(tx *gorm.DB, fn func(rv reflect.Value)) {
+	visit := func(rv reflect.Value) {
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return
+			}
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Struct {
+			fn(rv)
+		}
+	}
+
+	rv := tx.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			visit(rv.Index(i))
+		}
+	default:
+		visit(rv)
+	}
+}
+
+// eachTrashCan 遍历本次写入的垃圾桶的坐标和状态
+func eachTrashCan(tx *gorm.DB, fn func(p Point, status model.TrashCanStatus)) {
+	schema := tx.Statement.Schema
+	idField := schema.LookUpField("ID")
+	latField := schema.LookUpField("Latitude")
+	lngField := schema.LookUpField("Longitude")
+	statusField := schema.LookUpField("Status")
+	if idField == nil || latField == nil || lngField == nil || statusField == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	eachRecord(tx, func(rv reflect.Value) {
+		id, zero := idField.ValueOf(ctx, rv)
+		if zero {
+			return
+		}
+		lat, _ := latField.ValueOf(ctx, rv)
+		lng, _ := lngField.ValueOf(ctx, rv)
+		status, _ := statusField.ValueOf(ctx, rv)
+		fn(Point{ID: id.(uint), Lat: lat.(float64), Lng: lng.(float64)}, status.(model.TrashCanStatus))
+	})
+}
+
+// trashCanIDs 获取本次写入涉及的垃圾桶ID
+func trashCanIDs(tx *gorm.DB) []uint {
+	var ids []uint
+	eachTrashCan(tx, func(p Point, _ model.TrashCanStatus) {
+		ids = append(ids, p.ID)
+	})
+	return ids
+}
+
+// voteTrashCanIDs 获取本次写入的点赞点踩所属的垃圾桶ID，按条件批量写入时为空
+func voteTrashCanIDs(tx *gorm.DB) []uint {
+	field := tx.Statement.Schema.LookUpField("TrashCanID")
+	if field == nil {
+		return nil
+	}
+	var ids []uint
+	eachRecord(tx, func(rv reflect.Value) {
+		if id, zero := field.ValueOf(tx.Statement.Context, rv); !zero {
+			ids = append(ids, id.(uint))
+		}
+	})
+	return ids
+}
+
+// refresh 从数据库重新读取指定垃圾桶的坐标，已删除或不再公开的从索引中删除，tx 在事务中时与事务使用同一连接
+func refresh(tx *gorm.DB, idx *Index, ids []uint) {
+	var trashCans []model.TrashCan
+	if err := tx.Session(&gorm.Session{NewDB: true}).
+		Scopes(model.PublicStatus).
+		Select("id, latitude, longitude").
+		Where("id IN ?", ids).
+		Find(&trashCans).Error; err != nil {
+		global.SugarLogger.Errorf("刷新空间索引失败: %v", err)
+		return
+	}
+
+	found := make(map[uint]struct{}, len(trashCans))
+	for _, tc := range trashCans {
+		found[tc.ID] = struct{}{}
+		idx.Upsert(Point{ID: tc.ID, Lat: tc.Latitude, Lng: tc.Longitude})
+	}
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			idx.Remove(id)
+		}
+	}
+}
+
+// reload 重新加载整个索引
+func reload(tx *gorm.DB, idx *Index) {
+	points, err := loadPoints(tx.Session(&gorm.Session{NewDB: true}))
+	if err != nil {
+		global.SugarLogger.Errorf("重新加载空间索引失败: %v", err)
+		return
+	}
+	idx.Load(points)
+}
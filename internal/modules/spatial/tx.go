@@ -0,0 +1,168 @@
+package spatial
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// indexChanges 事务中累积的索引变更，事务提交后才应用到索引，回滚时直接丢弃
+type indexChanges struct {
+	mu      sync.Mutex
+	upserts []Point // 新创建的垃圾桶，坐标已知
+	ids     []uint  // 更新或删除的垃圾桶，提交后从数据库重新读取
+	touches []uint  // 位置以外的属性发生变化的垃圾桶，提交后通知监听者
+	touched bool    // 位置以外的属性发生变化但无法确定涉及哪些垃圾桶，提交后通知监听者全部失效
+	reload  bool    // 无法确定涉及哪些垃圾桶，提交后整体重新加载
+}
+
+func (c *indexChanges) upsert(p Point) {
+	c.mu.Lock()
+	c.upserts = append(c.upserts, p)
+	c.mu.Unlock()
+}
+
+func (c *indexChanges) refresh(ids []uint) {
+	c.mu.Lock()
+	c.ids = append(c.ids, ids...)
+	c.mu.Unlock()
+}
+
+func (c *indexChanges) touch(ids []uint) {
+	c.mu.Lock()
+	c.touches = append(c.touches, ids...)
+	c.mu.Unlock()
+}
+
+func (c *indexChanges) touchAll() {
+	c.mu.Lock()
+	c.touched = true
+	c.mu.Unlock()
+}
+
+func (c *indexChanges) reloadAll() {
+	c.mu.Lock()
+	c.reload = true
+	c.mu.Unlock()
+}
+
+// apply 将变更应用到索引，db 为事务之外的连接，读取的是已提交的数据
+func (c *indexChanges) apply(db *gorm.DB, idx *Index) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reload {
+		// 重新加载后监听者会清空全部缓存，无需再单独通知
+		reload(db, idx)
+		return
+	}
+	for _, p := range c.upserts {
+		idx.Upsert(p)
+	}
+	for start := 0; start < len(c.ids); start += refreshBatchSize {
+		end := min(start+refreshBatchSize, len(c.ids))
+		refresh(db, idx, c.ids[start:end])
+	}
+	if c.touched {
+		idx.TouchAll()
+		return
+	}
+	for _, id := range c.touches {
+		idx.Touch(id)
+	}
+}
+
+// refreshBatchSize 提交后重新读取坐标时每批查询的垃圾桶数量，避免超出SQL参数数量限制
+const refreshBatchSize = 1000
+
+// trackingPool 包装数据库连接池，使其开启的事务能够暂存索引变更：
+// 事务提交成功后才应用到索引，回滚时丢弃，避免回滚的删除或移动使索引与数据库不一致
+type trackingPool struct {
+	gorm.ConnPool
+	db  *gorm.DB
+	idx *Index
+}
+
+// BeginTx 开启事务，返回可以暂存索引变更的事务
+func (p *trackingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		connPool, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = connPool
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	return &trackingTx{ConnPool: tx, pool: p}, nil
+}
+
+// GetDBConn 返回底层的 *sql.DB，供 gorm.DB.DB() 使用
+func (p *trackingPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// trackingTx 由 trackingPool 开启的事务
+type trackingTx struct {
+	gorm.ConnPool
+	pool    *trackingPool
+	changes indexChanges
+}
+
+// Commit 提交事务，成功后应用暂存的索引变更
+func (t *trackingTx) Commit() error {
+	committer, ok := t.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	if err := committer.Commit(); err != nil {
+		return err
+	}
+	t.changes.apply(t.pool.db, t.pool.idx)
+	return nil
+}
+
+// Rollback 回滚事务，丢弃暂存的索引变更
+func (t *trackingTx) Rollback() error {
+	committer, ok := t.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	return committer.Rollback()
+}
+
+// GetDBConn 返回底层的 *sql.DB，供 gorm.DB.DB() 使用
+func (t *trackingTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.GetDBConn()
+}
+
+// pendingChanges 返回当前事务暂存的索引变更，不在事务中时返回nil，此时应立即更新索引
+func pendingChanges(tx *gorm.DB) *indexChanges {
+	if t, ok := tx.Statement.ConnPool.(*trackingTx); ok {
+		return &t.changes
+	}
+	return nil
+}
+
+// trackTransactions 替换 db 的连接池，之后通过 db 开启的事务（包括GORM为单条写入自动开启的事务）
+// 都会在提交后才将索引变更应用到 idx
+func trackTransactions(db *gorm.DB, idx *Index) {
+	pool := &trackingPool{ConnPool: db.ConnPool, db: db, idx: idx}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+}
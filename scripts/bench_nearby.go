@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"template/config"
+	"template/ginServer/api"
+	"template/ginServer/model"
+	"template/global"
+	Orm "template/initialize/orm"
+	"template/internal/modules/spatial"
+)
+
+// 附近搜索性能测试，直接调用附近搜索接口的处理函数
+// 用法：go run scripts/bench_nearby.go -n 1000000 -radius 1
+// 会在独立的数据库文件中生成测试数据，不会影响 sqlite.db
+func main() {
+	dbPath := flag.String("db", "bench.db", "测试数据库文件")
+	total := flag.Int("n", 1000000, "测试数据条数")
+	radius := flag.Float64("radius", 1, "搜索半径（公里）")
+	runs := flag.Int("runs", 20, "查询的执行次数")
+	flag.Parse()
+
+	db, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		fmt.Printf("❌ 无法连接到数据库: %v\n", err)
+		os.Exit(1)
+	}
+	global.DB = db
+	global.SugarLogger = zap.NewNop().Sugar()
+	global.CONFIG.JWTConfig = &config.JWTConfig{Secret: "bench"}
+	Orm.RegisterTables()
+
+	// 上海市中心
+	centerLat := 31.2304
+	centerLng := 121.4737
+
+	var count int64
+	db.Model(&model.TrashCan{}).Count(&count)
+	if int(count) < *total {
+		fmt.Printf("📝 生成测试数据 %d 条...\n", *total-int(count))
+		start := time.Now()
+		if err := seed(db, *total-int(count), centerLat, centerLng); err != nil {
+			fmt.Printf("❌ 生成测试数据失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ 生成完成，耗时 %v\n", time.Since(start))
+	}
+	db.Model(&model.TrashCan{}).Count(&count)
+	fmt.Printf("📊 数据量: %d 条，搜索半径: %.2f 公里\n", count, *radius)
+
+	start := time.Now()
+	if err := spatial.Init(); err != nil {
+		fmt.Printf("❌ 加载空间索引失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 空间索引加载完成，耗时 %v\n", time.Since(start))
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/trashcans/nearby", api.GetNearbyTrashCans)
+
+	rng := rand.New(rand.NewSource(1))
+	bench("附近搜索", *runs, func() int {
+		lat := centerLat + (rng.Float64()-0.5)*0.4
+		lng := centerLng + (rng.Float64()-0.5)*0.4
+		req := httptest.NewRequest("GET", fmt.Sprintf("/trashcans/nearby?lat=%f&lng=%f&radius=%g", lat, lng, *radius), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.Len()
+	})
+}
+
+// seed 在中心点周围约50公里范围内随机生成垃圾桶
+func seed(db *gorm.DB, n int, centerLat, centerLng float64) error {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	const batchSize = 1000
+	return db.Transaction(func(tx *gorm.DB) error {
+		batch := make([]model.TrashCan, 0, batchSize)
+		for i := 0; i < n; i++ {
+			batch = append(batch, model.TrashCan{
+				Latitude:    centerLat + (rng.Float64()-0.5)*0.9,
+				Longitude:   centerLng + (rng.Float64()-0.5)*1.0,
+				Address:     fmt.Sprintf("测试地址 %d", i),
+				Description: "性能测试数据",
+			})
+			if len(batch) == batchSize || i == n-1 {
+				if err := tx.CreateInBatches(batch, batchSize).Error; err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return nil
+	})
+}
+
+// bench 执行 runs 次查询，fn 返回响应的字节数
+func bench(name string, runs int, fn func() int) {
+	var totalTime time.Duration
+	var maxTime time.Duration
+	var totalBytes int
+	for i := 0; i < runs; i++ {
+		start := time.Now()
+		totalBytes += fn()
+		elapsed := time.Since(start)
+		totalTime += elapsed
+		if elapsed > maxTime {
+			maxTime = elapsed
+		}
+	}
+	fmt.Printf("⏱️  %s: 平均 %v，最慢 %v，平均响应 %d 字节\n",
+		name, totalTime/time.Duration(runs), maxTime, totalBytes/runs)
+}
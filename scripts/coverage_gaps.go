@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"template/ginServer/model"
+	"template/internal/modules/coverage"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// 覆盖缺口分析：找出范围内离最近的垃圾桶超过阈值距离的区域，按面积由大到小排列
+// 用法：go run scripts/coverage_gaps.go -bbox 31.1,121.3,31.3,121.6 -threshold 300 -out gaps.geojson
+// 输出的 GeoJSON 可以直接在 QGIS 等GIS软件或 geojson.io 中查看
+func main() {
+	dbPath := flag.String("db", "sqlite.db", "数据库文件")
+	bbox := flag.String("bbox", "", "分析范围：西南角纬度,西南角经度,东北角纬度,东北角经度")
+	threshold := flag.Float64("threshold", 300, "阈值距离（米）")
+	step := flag.Float64("step", 0, "采样间距（米），默认为阈值的一半")
+	coordSys := flag.String("coord-sys", "gcj02", "范围及输出结果的坐标系 wgs84/gcj02/bd09")
+	limit := flag.Int("limit", 20, "最多输出的缺口区域数量，0表示全部")
+	maxSamples := flag.Int("max-samples", 1000000, "最多的采样点数量")
+	outPath := flag.String("out", "", "将缺口区域写入该 GeoJSON 文件（可选）")
+	flag.Parse()
+
+	sys, ok := utils.ParseCoordSys(*coordSys)
+	if !ok {
+		fmt.Println("❌ coord-sys 只支持 wgs84、gcj02、bd09")
+		os.Exit(2)
+	}
+	box, err := parseBBox(*bbox, sys)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		fmt.Printf("❌ 无法连接到数据库: %v\n", err)
+		os.Exit(1)
+	}
+	if !db.Migrator().HasTable(&model.TrashCan{}) {
+		fmt.Println("❌ 数据库中没有垃圾桶表，请先启动一次服务完成建表")
+		os.Exit(1)
+	}
+	idx, err := spatial.LoadIndex(db)
+	if err != nil {
+		fmt.Printf("❌ 加载垃圾桶失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	result, err := coverage.Analyze(coverage.Options{
+		Box:        box,
+		Threshold:  *threshold,
+		Step:       *step,
+		MaxSamples: *maxSamples,
+		MaxRegions: *limit,
+		Index:      idx,
+	})
+	if err != nil {
+		fmt.Printf("❌ 分析失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("📐 采样 %d×%d=%d 个点，间距 %.0f 米，共 %d 个垃圾桶\n",
+		result.Rows, result.Columns, result.Samples, result.Step, idx.Len())
+	fmt.Printf("📊 %.1f%% 的采样点在 %.0f 米内有垃圾桶，缺口区域 %d 个，总面积 %.2f 平方公里\n\n",
+		result.Coverage*100, result.Threshold, result.RegionCount, result.GapArea/1e6)
+	for i, region := range result.Regions {
+		region = region.Convert(sys)
+		result.Regions[i] = region
+		fmt.Printf("#%-3d 面积 %8.3f 平方公里  最远 %6.0f 米  建议位置 %.6f,%.6f\n",
+			region.Rank, region.Area/1e6, region.MaxDistance, region.Farthest.Latitude, region.Farthest.Longitude)
+	}
+
+	if *outPath != "" {
+		if err := writeGeoJSON(*outPath, result); err != nil {
+			fmt.Printf("❌ 写入GeoJSON失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n📄 缺口区域已写入 %s\n", *outPath)
+	}
+}
+
+// parseBBox 解析 "西南角纬度,西南角经度,东北角纬度,东北角经度"，并转换为统一存储的坐标系
+func parseBBox(s string, sys utils.CoordSys) (utils.BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return utils.BoundingBox{}, fmt.Errorf("bbox 格式错误: %q，应为 西南角纬度,西南角经度,东北角纬度,东北角经度", s)
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return utils.BoundingBox{}, fmt.Errorf("bbox 格式错误: %s", part)
+		}
+		values[i] = v
+	}
+	box := utils.BoundingBox{MinLat: values[0], MinLng: values[1], MaxLat: values[2], MaxLng: values[3]}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat >= box.MaxLat ||
+		box.MinLng < -180 || box.MinLng > 180 || box.MaxLng < -180 || box.MaxLng > 180 {
+		return utils.BoundingBox{}, fmt.Errorf("bbox 超出范围: %s", s)
+	}
+	box.MinLat, box.MinLng = utils.ToCanonicalCoord(box.MinLat, box.MinLng, sys)
+	box.MaxLat, box.MaxLng = utils.ToCanonicalCoord(box.MaxLat, box.MaxLng, sys)
+	return box, nil
+}
+
+// writeGeoJSON 将缺口区域写为 FeatureCollection，每个区域一个 MultiPolygon 要素
+func writeGeoJSON(path string, result *coverage.Result) error {
+	features := make([]map[string]interface{}, 0, len(result.Regions))
+	for _, region := range result.Regions {
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "MultiPolygon",
+				"coordinates": region.Polygon,
+			},
+			"properties": map[string]interface{}{
+				"rank":          region.Rank,
+				"area":          region.Area,
+				"max_distance":  region.MaxDistance,
+				"farthest_lat":  region.Farthest.Latitude,
+				"farthest_lng":  region.Farthest.Longitude,
+				"cell_count":    region.CellCount,
+				"threshold":     result.Threshold,
+				"sampling_step": result.Step,
+			},
+		})
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"template/ginServer/model"
+	"template/internal/modules/importer"
+	"template/internal/modules/spatial"
+	"template/utils"
+)
+
+// 从CSV或GeoJSON文件批量导入垃圾桶
+// 用法：go run scripts/import_trashcans.go -file bins.csv -mapping "latitude=纬度,longitude=经度,address=地址" -dry-run
+// 导入后需要重启服务，服务的空间索引才会包含新导入的垃圾桶；服务运行时也可以使用管理员导入接口
+func main() {
+	dbPath := flag.String("db", "sqlite.db", "数据库文件")
+	filePath := flag.String("file", "", "导入文件（.csv 或 .geojson）")
+	format := flag.String("format", "", "文件格式 csv/geojson，默认按扩展名判断")
+	mapping := flag.String("mapping", "", "列映射，如 latitude=纬度,longitude=经度,address=地址,description=备注")
+	coordSys := flag.String("coord-sys", "", "文件中经纬度的坐标系 wgs84/gcj02/bd09，CSV默认gcj02，GeoJSON默认wgs84")
+	dryRun := flag.Bool("dry-run", false, "只校验和检测重复，不写入数据库")
+	radius := flag.Float64("radius", importer.DefaultDuplicateRadius, "重复检测半径（米）")
+	batchSize := flag.Int("batch", importer.DefaultBatchSize, "每批插入的记录数")
+	userID := flag.Uint("user", 0, "记录为该用户上传（可选）")
+	reportPath := flag.String("report", "", "将完整的逐行报告写入该JSON文件（可选）")
+	flag.Parse()
+
+	if *filePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*filePath)) {
+		case ".csv":
+			*format = importer.FormatCSV
+		case ".geojson", ".json":
+			*format = importer.FormatGeoJSON
+		}
+	}
+	columns, err := importer.ParseColumnMapping(*mapping)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(2)
+	}
+	sys := utils.CoordGCJ02
+	if *format == importer.FormatGeoJSON {
+		sys = utils.CoordWGS84
+	}
+	if *coordSys != "" {
+		var ok bool
+		if sys, ok = utils.ParseCoordSys(*coordSys); !ok {
+			fmt.Println("❌ coord-sys 只支持 wgs84、gcj02、bd09")
+			os.Exit(2)
+		}
+	}
+
+	db, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		fmt.Printf("❌ 无法连接到数据库: %v\n", err)
+		os.Exit(1)
+	}
+	if !db.Migrator().HasTable(&model.TrashCan{}) {
+		fmt.Println("❌ 数据库中没有垃圾桶表，请先启动一次服务完成建表")
+		os.Exit(1)
+	}
+
+	idx, err := spatial.LoadIndex(db)
+	if err != nil {
+		fmt.Printf("❌ 加载已有垃圾桶失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*filePath)
+	if err != nil {
+		fmt.Printf("❌ 打开文件失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	opts := importer.Options{
+		Format:          *format,
+		Mapping:         columns,
+		CoordSys:        sys,
+		DryRun:          *dryRun,
+		BatchSize:       *batchSize,
+		DuplicateRadius: *radius,
+		Index:           idx,
+	}
+	if *userID > 0 {
+		id := *userID
+		opts.UserID = &id
+	}
+	report, err := importer.Import(db, f, opts)
+	if err != nil {
+		fmt.Printf("❌ 导入失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case importer.StatusSkipped:
+			fmt.Printf("⏭️  第 %d 行跳过: %s\n", row.Row, row.Reason)
+		case importer.StatusFailed:
+			fmt.Printf("❌ 第 %d 行失败: %s\n", row.Row, row.Reason)
+		}
+	}
+
+	if *reportPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+			fmt.Printf("❌ 写入报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 逐行报告已写入 %s\n", *reportPath)
+		}
+	}
+
+	if report.DryRun {
+		fmt.Printf("\n🔍 试运行：共 %d 条，可导入 %d 条，重复 %d 条，失败 %d 条（未写入数据库）\n",
+			report.Total, report.Accepted, report.Skipped, report.Failed)
+		return
+	}
+	fmt.Printf("\n📊 共 %d 条，导入 %d 条，重复跳过 %d 条，失败 %d 条\n",
+		report.Total, report.Accepted, report.Skipped, report.Failed)
+}
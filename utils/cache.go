@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache 带过期时间的并发安全内存缓存
+type TTLCache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	items      map[K]cacheEntry[V]
+	ttl        time.Duration
+	maxEntries int
+}
+
+type cacheEntry[V any] struct {
+	value    V
+	expireAt time.Time
+}
+
+// NewTTLCache 创建缓存，maxEntries 为最大条目数，超出时先清理过期条目，仍超出则随机淘汰
+func NewTTLCache[K comparable, V any](ttl time.Duration, maxEntries int) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		items:      make(map[K]cacheEntry[V]),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// Get 获取未过期的缓存
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	entry, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expireAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set 写入缓存
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		c.evict()
+	}
+	c.items[key] = cacheEntry[V]{value: value, expireAt: time.Now().Add(c.ttl)}
+}
+
+// Delete 删除缓存
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
+
+// DeleteFunc 删除满足条件的缓存
+func (c *TTLCache[K, V]) DeleteFunc(match func(key K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.items {
+		if match(key) {
+			delete(c.items, key)
+		}
+	}
+}
+
+// Clear 清空缓存
+func (c *TTLCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]cacheEntry[V])
+}
+
+// evict 清理过期条目，仍然超出上限时淘汰部分条目（需持有写锁）
+func (c *TTLCache[K, V]) evict() {
+	now := time.Now()
+	for key, entry := range c.items {
+		if now.After(entry.expireAt) {
+			delete(c.items, key)
+		}
+	}
+	for key := range c.items {
+		if len(c.items) < c.maxEntries {
+			break
+		}
+		delete(c.items, key)
+	}
+}
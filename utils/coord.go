@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"math"
+	"strings"
+)
+
+// CoordSys 坐标系
+type CoordSys string
+
+const (
+	CoordWGS84 CoordSys = "wgs84" // GPS设备、OpenStreetMap等使用的国际标准坐标系
+	CoordGCJ02 CoordSys = "gcj02" // 国测局坐标系，高德地图、腾讯地图使用
+	CoordBD09  CoordSys = "bd09"  // 百度地图坐标系
+
+	// CanonicalCoordSys 数据库中统一存储的坐标系，与前端高德地图保持一致
+	CanonicalCoordSys = CoordGCJ02
+)
+
+const (
+	krasovskyA  = 6378245.0              // 克拉索夫斯基椭球长半轴
+	krasovskyEE = 0.00669342162296594323 // 克拉索夫斯基椭球第一偏心率平方
+	bdXPi       = math.Pi * 3000.0 / 180.0
+)
+
+// ParseCoordSys 解析坐标系参数，为空时返回统一存储的坐标系
+func ParseCoordSys(s string) (CoordSys, bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", "")) {
+	case "":
+		return CanonicalCoordSys, true
+	case "wgs84", "gps":
+		return CoordWGS84, true
+	case "gcj02", "amap":
+		return CoordGCJ02, true
+	case "bd09", "baidu":
+		return CoordBD09, true
+	}
+	return "", false
+}
+
+// ConvertCoord 在不同坐标系之间转换经纬度
+func ConvertCoord(lat, lng float64, from, to CoordSys) (float64, float64) {
+	if from == to {
+		return lat, lng
+	}
+
+	// 先统一转换为GCJ-02，再转换为目标坐标系
+	switch from {
+	case CoordWGS84:
+		lat, lng = WGS84ToGCJ02(lat, lng)
+	case CoordBD09:
+		lat, lng = BD09ToGCJ02(lat, lng)
+	}
+
+	switch to {
+	case CoordWGS84:
+		return GCJ02ToWGS84(lat, lng)
+	case CoordBD09:
+		return GCJ02ToBD09(lat, lng)
+	}
+	return lat, lng
+}
+
+// ToCanonicalCoord 将指定坐标系的经纬度转换为统一存储的坐标系
+func ToCanonicalCoord(lat, lng float64, from CoordSys) (float64, float64) {
+	return ConvertCoord(lat, lng, from, CanonicalCoordSys)
+}
+
+// FromCanonicalCoord 将统一存储的经纬度转换为指定坐标系
+func FromCanonicalCoord(lat, lng float64, to CoordSys) (float64, float64) {
+	return ConvertCoord(lat, lng, CanonicalCoordSys, to)
+}
+
+// outOfChina 国外的坐标不做偏移
+func outOfChina(lat, lng float64) bool {
+	return lng < 72.004 || lng > 137.8347 || lat < 0.8293 || lat > 55.8271
+}
+
+// WGS84ToGCJ02 WGS-84 转 GCJ-02
+func WGS84ToGCJ02(lat, lng float64) (float64, float64) {
+	if outOfChina(lat, lng) {
+		return lat, lng
+	}
+	dLat, dLng := gcj02Offset(lat, lng)
+	return lat + dLat, lng + dLng
+}
+
+// GCJ02ToWGS84 GCJ-02 转 WGS-84
+// 通过迭代逼近求逆，误差小于0.01米
+func GCJ02ToWGS84(lat, lng float64) (float64, float64) {
+	if outOfChina(lat, lng) {
+		return lat, lng
+	}
+	wgsLat, wgsLng := lat, lng
+	for i := 0; i < 10; i++ {
+		gcjLat, gcjLng := WGS84ToGCJ02(wgsLat, wgsLng)
+		dLat, dLng := gcjLat-lat, gcjLng-lng
+		wgsLat -= dLat
+		wgsLng -= dLng
+		if math.Abs(dLat) < 1e-9 && math.Abs(dLng) < 1e-9 {
+			break
+		}
+	}
+	return wgsLat, wgsLng
+}
+
+// GCJ02ToBD09 GCJ-02 转 BD-09
+func GCJ02ToBD09(lat, lng float64) (float64, float64) {
+	z := math.Sqrt(lng*lng+lat*lat) + 0.00002*math.Sin(lat*bdXPi)
+	theta := math.Atan2(lat, lng) + 0.000003*math.Cos(lng*bdXPi)
+	return z*math.Sin(theta) + 0.006, z*math.Cos(theta) + 0.0065
+}
+
+// BD09ToGCJ02 BD-09 转 GCJ-02
+func BD09ToGCJ02(lat, lng float64) (float64, float64) {
+	x := lng - 0.0065
+	y := lat - 0.006
+	z := math.Sqrt(x*x+y*y) - 0.00002*math.Sin(y*bdXPi)
+	theta := math.Atan2(y, x) - 0.000003*math.Cos(x*bdXPi)
+	return z * math.Sin(theta), z * math.Cos(theta)
+}
+
+// gcj02Offset 计算WGS-84到GCJ-02的偏移量（度）
+func gcj02Offset(lat, lng float64) (float64, float64) {
+	x, y := lng-105.0, lat-35.0
+
+	dLat := -100.0 + 2.0*x + 3.0*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	dLat += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	dLat += (20.0*math.Sin(y*math.Pi) + 40.0*math.Sin(y/3.0*math.Pi)) * 2.0 / 3.0
+	dLat += (160.0*math.Sin(y/12.0*math.Pi) + 320*math.Sin(y*math.Pi/30.0)) * 2.0 / 3.0
+
+	dLng := 300.0 + x + 2.0*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	dLng += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	dLng += (20.0*math.Sin(x*math.Pi) + 40.0*math.Sin(x/3.0*math.Pi)) * 2.0 / 3.0
+	dLng += (150.0*math.Sin(x/12.0*math.Pi) + 300.0*math.Sin(x/30.0*math.Pi)) * 2.0 / 3.0
+
+	radLat := lat / 180.0 * math.Pi
+	magic := math.Sin(radLat)
+	magic = 1 - krasovskyEE*magic*magic
+	sqrtMagic := math.Sqrt(magic)
+	dLat = (dLat * 180.0) / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLng = (dLng * 180.0) / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLat, dLng
+}
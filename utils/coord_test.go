@@ -0,0 +1,117 @@
+package utils
+
+import "testing"
+
+func TestParseCoordSys(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   CoordSys
+		wantOK bool
+	}{
+		{"", CanonicalCoordSys, true},
+		{"wgs84", CoordWGS84, true},
+		{" WGS-84 ", CoordWGS84, true},
+		{"gps", CoordWGS84, true},
+		{"GCJ02", CoordGCJ02, true},
+		{"amap", CoordGCJ02, true},
+		{"bd-09", CoordBD09, true},
+		{"baidu", CoordBD09, true},
+		{"utm", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseCoordSys(tt.in)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseCoordSys(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWGS84ToGCJ02(t *testing.T) {
+	tests := []struct {
+		name          string
+		lat, lng      float64
+		minOffset     float64 // 偏移距离的范围（米）
+		maxOffset     float64
+		wantUnchanged bool
+	}{
+		{"北京天安门", 39.908692, 116.397477, 100, 1000, false},
+		{"上海人民广场", 31.2304, 121.4737, 100, 1000, false},
+		{"广州", 23.1291, 113.2644, 100, 1000, false},
+		{"乌鲁木齐", 43.8256, 87.6168, 10, 1000, false},
+		{"东京在中国范围之外", 35.6762, 139.6503, 0, 0, true},
+		{"纽约", 40.7128, -74.0060, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcjLat, gcjLng := WGS84ToGCJ02(tt.lat, tt.lng)
+			if tt.wantUnchanged {
+				if gcjLat != tt.lat || gcjLng != tt.lng {
+					t.Errorf("WGS84ToGCJ02(%v, %v) = (%v, %v), 中国范围之外的坐标不应偏移", tt.lat, tt.lng, gcjLat, gcjLng)
+				}
+				return
+			}
+			offset := CalculateDistance(tt.lat, tt.lng, gcjLat, gcjLng) * 1000
+			if offset < tt.minOffset || offset > tt.maxOffset {
+				t.Errorf("WGS84ToGCJ02(%v, %v) 偏移 %.1f 米, want %v~%v 米", tt.lat, tt.lng, offset, tt.minOffset, tt.maxOffset)
+			}
+
+			// 反向转换的误差小于0.01米
+			wgsLat, wgsLng := GCJ02ToWGS84(gcjLat, gcjLng)
+			if d := CalculateDistance(tt.lat, tt.lng, wgsLat, wgsLng) * 1000; d > 0.01 {
+				t.Errorf("GCJ02ToWGS84 往返误差 %.4f 米", d)
+			}
+		})
+	}
+}
+
+func TestGCJ02ToBD09(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		wantLat  float64 // 百度地图坐标拾取的参考值
+		wantLng  float64
+	}{
+		{"北京天安门", 39.908823, 116.39747, 39.915, 116.404},
+		{"上海人民广场", 31.2304, 121.4737, 31.2362, 121.4802},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bdLat, bdLng := GCJ02ToBD09(tt.lat, tt.lng)
+			if d := CalculateDistance(bdLat, bdLng, tt.wantLat, tt.wantLng) * 1000; d > 100 {
+				t.Errorf("GCJ02ToBD09(%v, %v) = (%v, %v), 与参考值相差 %.1f 米", tt.lat, tt.lng, bdLat, bdLng, d)
+			}
+			gcjLat, gcjLng := BD09ToGCJ02(bdLat, bdLng)
+			if d := CalculateDistance(tt.lat, tt.lng, gcjLat, gcjLng) * 1000; d > 1 {
+				t.Errorf("BD09ToGCJ02 往返误差 %.4f 米", d)
+			}
+		})
+	}
+}
+
+func TestConvertCoord(t *testing.T) {
+	const lat, lng = 31.2304, 121.4737
+	systems := []CoordSys{CoordWGS84, CoordGCJ02, CoordBD09}
+	for _, from := range systems {
+		for _, to := range systems {
+			t.Run(string(from)+"→"+string(to), func(t *testing.T) {
+				gotLat, gotLng := ConvertCoord(lat, lng, from, to)
+				if from == to && (gotLat != lat || gotLng != lng) {
+					t.Errorf("相同坐标系之间转换不应改变坐标: (%v, %v)", gotLat, gotLng)
+				}
+				backLat, backLng := ConvertCoord(gotLat, gotLng, to, from)
+				if d := CalculateDistance(lat, lng, backLat, backLng) * 1000; d > 1 {
+					t.Errorf("往返误差 %.4f 米", d)
+				}
+			})
+		}
+	}
+
+	// 统一存储的坐标系与 ConvertCoord 一致
+	canonLat, canonLng := ToCanonicalCoord(lat, lng, CoordWGS84)
+	wantLat, wantLng := ConvertCoord(lat, lng, CoordWGS84, CanonicalCoordSys)
+	if canonLat != wantLat || canonLng != wantLng {
+		t.Errorf("ToCanonicalCoord = (%v, %v), want (%v, %v)", canonLat, canonLng, wantLat, wantLng)
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// cursorMACSize 游标中签名的字节数
+const cursorMACSize = 16
+
+// ErrInvalidCursor 游标格式错误或签名校验失败
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// SignCursor 对分页游标的内容签名，返回可放在URL中的不透明字符串
+// scope 用于区分不同接口的游标，避免一个接口的游标被用于另一个接口
+func SignCursor(secret []byte, scope string, payload []byte) string {
+	data := make([]byte, 0, len(payload)+cursorMACSize)
+	data = append(data, payload...)
+	data = append(data, cursorMAC(secret, scope, payload)...)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// VerifyCursor 校验 SignCursor 生成的游标并返回其内容，游标被篡改时返回 ErrInvalidCursor
+func VerifyCursor(secret []byte, scope string, cursor string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) < cursorMACSize {
+		return nil, ErrInvalidCursor
+	}
+	payload, mac := data[:len(data)-cursorMACSize], data[len(data)-cursorMACSize:]
+	if !hmac.Equal(mac, cursorMAC(secret, scope, payload)) {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+func cursorMAC(secret []byte, scope string, payload []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return h.Sum(nil)[:cursorMACSize]
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestSignCursor(t *testing.T) {
+	secret := []byte("test-secret")
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"空内容", nil},
+		{"普通内容", []byte(`{"d":1.25,"id":42}`)},
+		{"二进制内容", []byte{0, 1, 2, 0xff, 0xfe}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := SignCursor(secret, "nearby", tt.payload)
+			got, err := VerifyCursor(secret, "nearby", cursor)
+			if err != nil {
+				t.Fatalf("VerifyCursor error = %v", err)
+			}
+			if !bytes.Equal(got, tt.payload) {
+				t.Errorf("VerifyCursor = %q, want %q", got, tt.payload)
+			}
+		})
+	}
+}
+
+func TestVerifyCursorInvalid(t *testing.T) {
+	secret := []byte("test-secret")
+	payload := []byte(`{"d":1.25,"id":42}`)
+	cursor := SignCursor(secret, "nearby", payload)
+	data, _ := base64.RawURLEncoding.DecodeString(cursor)
+
+	tampered := append([]byte(nil), data...)
+	tampered[0] ^= 1
+	badMAC := append([]byte(nil), data...)
+	badMAC[len(badMAC)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		secret []byte
+		scope  string
+		cursor string
+	}{
+		{"其他接口的游标", secret, "ranked", cursor},
+		{"其他密钥签名的游标", []byte("other-secret"), "nearby", cursor},
+		{"篡改内容", secret, "nearby", base64.RawURLEncoding.EncodeToString(tampered)},
+		{"篡改签名", secret, "nearby", base64.RawURLEncoding.EncodeToString(badMAC)},
+		{"长度不足", secret, "nearby", base64.RawURLEncoding.EncodeToString(data[:cursorMACSize-1])},
+		{"非base64", secret, "nearby", "!!!"},
+		{"空字符串", secret, "nearby", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyCursor(tt.secret, tt.scope, tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("VerifyCursor error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
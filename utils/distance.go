@@ -1,6 +1,9 @@
 package utils
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 const (
 	// EarthRadius 地球半径（公里）
@@ -29,3 +32,123 @@ func CalculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
 
 	return distance
 }
+
+// BoundingBox 经纬度矩形范围
+// 当 MinLng > MaxLng 时表示该范围跨越了180度经线
+type BoundingBox struct {
+	MinLat float64
+	MaxLat float64
+	MinLng float64
+	MaxLng float64
+}
+
+// NewBoundingBox 根据中心点和半径（公里）计算能够完全包含该圆形区域的矩形范围
+// 用于在数据库中先做粗筛，再用 CalculateDistance 精确过滤
+func NewBoundingBox(lat, lng, radius float64) BoundingBox {
+	latDelta := radius / EarthRadius * 180.0 / math.Pi
+
+	box := BoundingBox{
+		MinLat: lat - latDelta,
+		MaxLat: lat + latDelta,
+	}
+
+	// 圆形区域包含了极点时，经度范围为全部
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat = math.Max(box.MinLat, -90)
+		box.MaxLat = math.Min(box.MaxLat, 90)
+		box.MinLng = -180
+		box.MaxLng = 180
+		return box
+	}
+
+	// 纬度越高，同样的距离对应的经度跨度越大
+	lngDelta := math.Asin(math.Sin(radius/EarthRadius)/math.Cos(lat*math.Pi/180.0)) * 180.0 / math.Pi
+	if lngDelta >= 180 || math.IsNaN(lngDelta) {
+		box.MinLng = -180
+		box.MaxLng = 180
+		return box
+	}
+
+	box.MinLng = NormalizeLng(lng - lngDelta)
+	box.MaxLng = NormalizeLng(lng + lngDelta)
+	return box
+}
+
+// Expand 将矩形向四周扩大指定距离（公里），得到的矩形能包含距原矩形该距离以内的所有点
+func (b BoundingBox) Expand(distance float64) BoundingBox {
+	latDelta := distance / EarthRadius * 180.0 / math.Pi
+
+	box := BoundingBox{
+		MinLat: b.MinLat - latDelta,
+		MaxLat: b.MaxLat + latDelta,
+	}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat = math.Max(box.MinLat, -90)
+		box.MaxLat = math.Min(box.MaxLat, 90)
+		box.MinLng = -180
+		box.MaxLng = 180
+		return box
+	}
+
+	// 按离赤道最远的纬度计算经度跨度，保证整个矩形都被覆盖
+	maxLat := math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat))
+	lngDelta := latDelta / math.Cos(maxLat*math.Pi/180.0)
+	width := b.MaxLng - b.MinLng
+	if b.CrossesAntimeridian() {
+		width += 360
+	}
+	if width+2*lngDelta >= 360 {
+		box.MinLng = -180
+		box.MaxLng = 180
+		return box
+	}
+
+	box.MinLng = NormalizeLng(b.MinLng - lngDelta)
+	box.MaxLng = NormalizeLng(b.MaxLng + lngDelta)
+	return box
+}
+
+// CrossesAntimeridian 是否跨越180度经线
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+// Contains 判断点是否在矩形范围内
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return lng >= b.MinLng || lng <= b.MaxLng
+	}
+	return lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// NormalizeLng 将经度规范到 [-180, 180] 区间，NaN 和无穷大原样返回，由调用方校验
+func NormalizeLng(lng float64) float64 {
+	if lng >= -180 && lng <= 180 {
+		return lng
+	}
+	if math.IsNaN(lng) || math.IsInf(lng, 0) {
+		return lng
+	}
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
+
+// ValidateCoordinate 严格校验经纬度：不能是 NaN 或无穷大，纬度在 [-90, 90]、经度在 [-180, 180] 之间，且不能是 (0, 0)
+// (0, 0) 位于几内亚湾的海面上，通常是定位失败或缺少经纬度时的默认值
+func ValidateCoordinate(lat, lng float64) error {
+	switch {
+	case math.IsNaN(lat) || math.IsInf(lat, 0) || lat < -90 || lat > 90:
+		return fmt.Errorf("纬度超出范围: %v", lat)
+	case math.IsNaN(lng) || math.IsInf(lng, 0) || lng < -180 || lng > 180:
+		return fmt.Errorf("经度超出范围: %v", lng)
+	case lat == 0 && lng == 0:
+		return fmt.Errorf("坐标为 (0, 0)，可能缺少经纬度")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNormalizeLng(t *testing.T) {
+	tests := []struct {
+		name string
+		lng  float64
+		want float64
+	}{
+		{"范围内不变", 121.47, 121.47},
+		{"180不变", 180, 180},
+		{"-180不变", -180, -180},
+		{"略大于180", 181, -179},
+		{"略小于-180", -181, 179},
+		{"多圈", 121.47 + 720, 121.47},
+		{"负方向多圈", -121.47 - 1080, -121.47},
+		{"恰好一圈", 360, 0},
+		{"很大的值", 1e15 + 30, math.Mod(1e15+30+180, 360) - 180},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLng(tt.lng); !approxEqual(got, tt.want, 1e-9) {
+				t.Errorf("NormalizeLng(%v) = %v, want %v", tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLngNonFinite(t *testing.T) {
+	tests := []struct {
+		name string
+		lng  float64
+	}{
+		{"正无穷", math.Inf(1)},
+		{"负无穷", math.Inf(-1)},
+		{"NaN", math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 修复前对无穷大会无限循环
+			done := make(chan float64, 1)
+			go func() { done <- NormalizeLng(tt.lng) }()
+			select {
+			case got := <-done:
+				if !math.IsNaN(tt.lng) && got != tt.lng || math.IsNaN(tt.lng) && !math.IsNaN(got) {
+					t.Errorf("NormalizeLng(%v) = %v, want %v", tt.lng, got, tt.lng)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("NormalizeLng(%v) 没有返回", tt.lng)
+			}
+		})
+	}
+}
+
+func TestCalculateDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64 // 公里
+		tolerance              float64
+	}{
+		{"同一点", 31.2304, 121.4737, 31.2304, 121.4737, 0, 1e-12},
+		{"赤道上1度经度", 0, 0, 0, 1, EarthRadius * math.Pi / 180, 1e-9},
+		{"经线上1度纬度", 10, 50, 11, 50, EarthRadius * math.Pi / 180, 1e-9},
+		{"跨越180度经线", 0, 179.5, 0, -179.5, EarthRadius * math.Pi / 180, 1e-9},
+		{"对跖点", 0, 0, 0, 180, EarthRadius * math.Pi, 1e-9},
+		{"北京到上海", 39.9042, 116.4074, 31.2304, 121.4737, 1067, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateDistance(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if !approxEqual(got, tt.want, tt.tolerance) {
+				t.Errorf("CalculateDistance = %v, want %v ± %v", got, tt.want, tt.tolerance)
+			}
+			if back := CalculateDistance(tt.lat2, tt.lng2, tt.lat1, tt.lng1); !approxEqual(back, got, 1e-9) {
+				t.Errorf("距离不对称: %v != %v", back, got)
+			}
+		})
+	}
+}
+
+func TestNewBoundingBox(t *testing.T) {
+	tests := []struct {
+		name           string
+		lat, lng       float64
+		radius         float64
+		wantFullLng    bool // 经度范围为全部
+		wantAntimerid  bool // 跨越180度经线
+		wantPolarClamp bool // 纬度范围被限制在极点
+	}{
+		{"上海1公里", 31.2304, 121.4737, 1, false, false, false},
+		{"靠近180度经线", -16.5, 179.99, 5, false, true, false},
+		{"包含北极", 89.99, 0, 10, true, false, true},
+		{"高纬度大半径跨过极点", 80, 0, 1500, true, false, true},
+		{"高纬度不含极点", 80, 0, 900, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := NewBoundingBox(tt.lat, tt.lng, tt.radius)
+			if full := box.MinLng == -180 && box.MaxLng == 180; full != tt.wantFullLng {
+				t.Errorf("经度范围为全部 = %v, want %v (%+v)", full, tt.wantFullLng, box)
+			}
+			if got := box.CrossesAntimeridian(); got != tt.wantAntimerid {
+				t.Errorf("CrossesAntimeridian() = %v, want %v (%+v)", got, tt.wantAntimerid, box)
+			}
+			if clamped := box.MaxLat == 90 || box.MinLat == -90; clamped != tt.wantPolarClamp {
+				t.Errorf("纬度限制在极点 = %v, want %v (%+v)", clamped, tt.wantPolarClamp, box)
+			}
+			// 圆周上的点都在矩形内
+			for bearing := 0.0; bearing < 360; bearing += 15 {
+				lat, lng := destination(tt.lat, tt.lng, bearing, tt.radius*0.999)
+				if !box.Contains(lat, lng) {
+					t.Fatalf("方位角 %v 上的点 (%v, %v) 不在矩形 %+v 内", bearing, lat, lng, box)
+				}
+			}
+		})
+	}
+}
+
+// destination 从起点沿方位角（度）移动指定距离（公里）后的位置
+func destination(lat, lng, bearing, distance float64) (float64, float64) {
+	phi1, lambda1 := lat*math.Pi/180, lng*math.Pi/180
+	theta, delta := bearing*math.Pi/180, distance/EarthRadius
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return phi2 * 180 / math.Pi, NormalizeLng(lambda2 * 180 / math.Pi)
+}
+
+func approxEqual(a, b, tolerance float64) bool {
+	return a-b <= tolerance && b-a <= tolerance
+}
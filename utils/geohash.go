@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// GeohashMaxPrecision geohash最大精度（12位约为 3.7cm x 1.9cm）
+	GeohashMaxPrecision = 12
+	geohashBase32       = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// GeohashEncode 将经纬度编码为指定精度的geohash
+func GeohashEncode(lat, lng float64, precision int) string {
+	if precision < 1 {
+		precision = 1
+	}
+	if precision > GeohashMaxPrecision {
+		precision = GeohashMaxPrecision
+	}
+
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+	var sb strings.Builder
+	sb.Grow(precision)
+
+	bit, ch := 0, 0
+	even := true // 偶数位编码经度，奇数位编码纬度
+	for sb.Len() < precision {
+		if even {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				minLng = mid
+			} else {
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			sb.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// GeohashBox 返回geohash单元格的经纬度范围
+func GeohashBox(hash string) BoundingBox {
+	box := BoundingBox{MinLat: -90, MaxLat: 90, MinLng: -180, MaxLng: 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		idx := strings.IndexByte(geohashBase32, hash[i])
+		if idx < 0 {
+			break
+		}
+		for bit := 4; bit >= 0; bit-- {
+			on := idx&(1<<bit) != 0
+			if even {
+				mid := (box.MinLng + box.MaxLng) / 2
+				if on {
+					box.MinLng = mid
+				} else {
+					box.MaxLng = mid
+				}
+			} else {
+				mid := (box.MinLat + box.MaxLat) / 2
+				if on {
+					box.MinLat = mid
+				} else {
+					box.MaxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return box
+}
+
+// geohashCellSize 返回指定精度下单元格的高度和宽度（度）
+func geohashCellSize(precision int) (float64, float64) {
+	bits := precision * 5
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180.0 / math.Exp2(float64(latBits)), 360.0 / math.Exp2(float64(lngBits))
+}
+
+// geohashCellRange 返回矩形范围在指定精度下覆盖的单元格行列号范围
+func geohashCellRange(minLat, maxLat, minLng, maxLng float64, precision int) (int, int, int, int) {
+	height, width := geohashCellSize(precision)
+	maxRow := int(math.Round(180/height)) - 1
+	maxCol := int(math.Round(360/width)) - 1
+	clamp := func(v, max int) int {
+		if v < 0 {
+			return 0
+		}
+		if v > max {
+			return max
+		}
+		return v
+	}
+	return clamp(int(math.Floor((minLat+90)/height)), maxRow),
+		clamp(int(math.Floor((maxLat+90)/height)), maxRow),
+		clamp(int(math.Floor((minLng+180)/width)), maxCol),
+		clamp(int(math.Floor((maxLng+180)/width)), maxCol)
+}
+
+// GeohashCover 返回能够覆盖矩形范围的geohash单元格列表
+// 会自动选择不超过 maxCells 个单元格的最高精度，精度越高覆盖范围越贴合
+func GeohashCover(box BoundingBox, maxCells int) []string {
+	parts := []BoundingBox{box}
+	if box.CrossesAntimeridian() {
+		parts = []BoundingBox{
+			{MinLat: box.MinLat, MaxLat: box.MaxLat, MinLng: box.MinLng, MaxLng: 180},
+			{MinLat: box.MinLat, MaxLat: box.MaxLat, MinLng: -180, MaxLng: box.MaxLng},
+		}
+	}
+
+	countCells := func(precision int) int {
+		total := 0
+		for _, p := range parts {
+			r0, r1, c0, c1 := geohashCellRange(p.MinLat, p.MaxLat, p.MinLng, p.MaxLng, precision)
+			total += (r1 - r0 + 1) * (c1 - c0 + 1)
+		}
+		return total
+	}
+
+	precision := 1
+	for precision < GeohashMaxPrecision && countCells(precision+1) <= maxCells {
+		precision++
+	}
+
+	height, width := geohashCellSize(precision)
+	seen := make(map[string]struct{})
+	var cells []string
+	for _, p := range parts {
+		r0, r1, c0, c1 := geohashCellRange(p.MinLat, p.MaxLat, p.MinLng, p.MaxLng, precision)
+		for r := r0; r <= r1; r++ {
+			for col := c0; col <= c1; col++ {
+				// 使用单元格中心点编码，避免边界上的浮点误差
+				lat := -90 + (float64(r)+0.5)*height
+				lng := -180 + (float64(col)+0.5)*width
+				hash := GeohashEncode(lat, lng, precision)
+				if _, ok := seen[hash]; !ok {
+					seen[hash] = struct{}{}
+					cells = append(cells, hash)
+				}
+			}
+		}
+	}
+	sort.Strings(cells)
+	return cells
+}
+
+// GeohashRange 前缀匹配对应的字符串区间 [Start, End)
+// 可直接用于 geohash >= Start AND geohash < End 的索引范围查询
+type GeohashRange struct {
+	Start string
+	End   string
+}
+
+// GeohashRanges 将单元格列表转换为前缀区间，相邻的单元格会被合并为一个区间
+func GeohashRanges(cells []string) []GeohashRange {
+	sorted := append([]string(nil), cells...)
+	sort.Strings(sorted)
+
+	var ranges []GeohashRange
+	for _, cell := range sorted {
+		end := geohashNextPrefix(cell)
+		if n := len(ranges); n > 0 && ranges[n-1].End == cell {
+			ranges[n-1].End = end
+			continue
+		}
+		ranges = append(ranges, GeohashRange{Start: cell, End: end})
+	}
+	return ranges
+}
+
+// geohashNextPrefix 返回字典序上紧跟在所有以 prefix 开头的字符串之后的前缀
+func geohashNextPrefix(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		idx := strings.IndexByte(geohashBase32, b[i])
+		if idx >= 0 && idx < len(geohashBase32)-1 {
+			b[i] = geohashBase32[idx+1]
+			return string(b[:i+1])
+		}
+	}
+	// 全部为最大字符时，使用比 'z' 大的字符作为上界
+	return prefix + "{"
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestGeohashEncode(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{"奥尔堡", 57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{"截断到较低精度", 57.64911, 10.40744, 5, "u4pru"},
+		{"原点", 0, 0, 6, "s00000"},
+		{"西南角", -90, -180, 4, "0000"},
+		{"东北角", 90, 180, 4, "zzzz"},
+		{"精度小于1按1处理", 57.64911, 10.40744, 0, "u"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GeohashEncode(tt.lat, tt.lng, tt.precision); got != tt.want {
+				t.Errorf("GeohashEncode(%v, %v, %d) = %q, want %q", tt.lat, tt.lng, tt.precision, got, tt.want)
+			}
+		})
+	}
+
+	// 精度超过上限时按最大精度编码，较低精度的编码是较高精度编码的前缀
+	full := GeohashEncode(57.64911, 10.40744, 20)
+	if len(full) != GeohashMaxPrecision || !strings.HasPrefix(full, "u4pruydqqvj") {
+		t.Errorf("GeohashEncode 精度20 = %q, want 以 u4pruydqqvj 开头的 %d 位编码", full, GeohashMaxPrecision)
+	}
+}
+
+func TestGeohashBox(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		lat, lng := rng.Float64()*180-90, rng.Float64()*360-180
+		for precision := 1; precision <= GeohashMaxPrecision; precision++ {
+			hash := GeohashEncode(lat, lng, precision)
+			box := GeohashBox(hash)
+			if !box.Contains(lat, lng) {
+				t.Fatalf("GeohashBox(%q) = %+v, 不包含编码的点 (%v, %v)", hash, box, lat, lng)
+			}
+			height, width := geohashCellSize(precision)
+			if !approxEqual(box.MaxLat-box.MinLat, height, 1e-9) || !approxEqual(box.MaxLng-box.MinLng, width, 1e-9) {
+				t.Fatalf("GeohashBox(%q) 的大小为 %v x %v, want %v x %v",
+					hash, box.MaxLat-box.MinLat, box.MaxLng-box.MinLng, height, width)
+			}
+		}
+	}
+}
+
+func TestGeohashCover(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      BoundingBox
+		maxCells int
+	}{
+		{"上海市区", BoundingBox{MinLat: 31.1, MaxLat: 31.3, MinLng: 121.3, MaxLng: 121.6}, 16},
+		{"很小的范围", NewBoundingBox(31.2304, 121.4737, 0.05), 9},
+		{"跨越赤道和本初子午线", BoundingBox{MinLat: -1, MaxLat: 1, MinLng: -1, MaxLng: 1}, 32},
+		{"跨越180度经线", BoundingBox{MinLat: -17, MaxLat: -16, MinLng: 179.5, MaxLng: -179.5}, 16},
+		{"只允许一个单元格", BoundingBox{MinLat: 10, MaxLat: 20, MinLng: 10, MaxLng: 20}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells := GeohashCover(tt.box, tt.maxCells)
+			if len(cells) == 0 {
+				t.Fatal("GeohashCover 返回空列表")
+			}
+			// 精度为1时单元格数量可能超过 maxCells，其余情况不能超过
+			if len(cells[0]) > 1 && len(cells) > tt.maxCells {
+				t.Errorf("单元格数量 %d 超过上限 %d", len(cells), tt.maxCells)
+			}
+			for i := 1; i < len(cells); i++ {
+				if cells[i-1] >= cells[i] {
+					t.Fatalf("单元格没有排序或有重复: %q, %q", cells[i-1], cells[i])
+				}
+			}
+
+			// 范围内随机的点以及四个角都应落在某个单元格中
+			width := tt.box.MaxLng - tt.box.MinLng
+			if tt.box.CrossesAntimeridian() {
+				width += 360
+			}
+			rng := rand.New(rand.NewSource(2))
+			points := [][2]float64{
+				{tt.box.MinLat, tt.box.MinLng}, {tt.box.MinLat, tt.box.MaxLng},
+				{tt.box.MaxLat, tt.box.MinLng}, {tt.box.MaxLat, tt.box.MaxLng},
+			}
+			for i := 0; i < 500; i++ {
+				lat := tt.box.MinLat + rng.Float64()*(tt.box.MaxLat-tt.box.MinLat)
+				points = append(points, [2]float64{lat, NormalizeLng(tt.box.MinLng + rng.Float64()*width)})
+			}
+			for _, p := range points {
+				hash := GeohashEncode(p[0], p[1], GeohashMaxPrecision)
+				if !coveredBy(hash, cells) {
+					t.Fatalf("点 (%v, %v) 的geohash %q 不在覆盖的单元格中: %v", p[0], p[1], hash, cells)
+				}
+			}
+		})
+	}
+}
+
+func TestGeohashRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []string
+		want  []GeohashRange
+	}{
+		{"空列表", nil, nil},
+		{"单个单元格", []string{"wtw3"}, []GeohashRange{{"wtw3", "wtw4"}}},
+		{"相邻单元格合并", []string{"wtw4", "wtw3", "wtw5"}, []GeohashRange{{"wtw3", "wtw6"}}},
+		{"不相邻的单元格", []string{"wtw3", "wtw6"}, []GeohashRange{{"wtw3", "wtw4"}, {"wtw6", "wtw7"}}},
+		{"末位为最大字符时进位", []string{"wtwz"}, []GeohashRange{{"wtwz", "wtx"}}},
+		{"全部为最大字符", []string{"zz"}, []GeohashRange{{"zz", "zz{"}}},
+		{"跨越进位的相邻单元格", []string{"wtwz", "wtx0"}, []GeohashRange{{"wtwz", "wtx"}, {"wtx0", "wtx1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GeohashRanges(tt.cells)
+			if len(got) != len(tt.want) {
+				t.Fatalf("GeohashRanges(%v) = %v, want %v", tt.cells, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("GeohashRanges(%v) = %v, want %v", tt.cells, got, tt.want)
+				}
+			}
+			// 区间内恰好包含以这些单元格为前缀的geohash
+			for _, cell := range tt.cells {
+				if !inRanges(cell+"0", got) || !inRanges(cell+"zzz", got) {
+					t.Errorf("以 %q 开头的geohash不在区间 %v 中", cell, got)
+				}
+			}
+		})
+	}
+}
+
+// coveredBy 判断geohash是否以某个单元格为前缀
+func coveredBy(hash string, cells []string) bool {
+	for _, cell := range cells {
+		if strings.HasPrefix(hash, cell) {
+			return true
+		}
+	}
+	return false
+}
+
+// inRanges 判断geohash是否落在某个区间 [Start, End) 中
+func inRanges(hash string, ranges []GeohashRange) bool {
+	for _, r := range ranges {
+		if hash >= r.Start && hash < r.End {
+			return true
+		}
+	}
+	return false
+}
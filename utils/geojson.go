@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// GeoJSON 中坐标的顺序为 [经度, 纬度]
+type (
+	// Ring 多边形的一个环，首尾两点相同
+	Ring [][2]float64
+	// Polygon 多边形，第一个环为外环，其余为内环（洞）
+	Polygon []Ring
+	// MultiPolygon 多个多边形
+	MultiPolygon []Polygon
+)
+
+// geoJSONObject 用于解析 Geometry 或 Feature
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    json.RawMessage `json:"geometry"`
+}
+
+// parseGeoJSONGeometry 解析 GeoJSON Geometry，若为 Feature 则返回其中的 geometry
+func parseGeoJSONGeometry(data []byte) (geoJSONObject, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return obj, fmt.Errorf("GeoJSON格式错误: %v", err)
+	}
+	if obj.Type != "Feature" {
+		return obj, nil
+	}
+	if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+		return obj, errors.New("Feature 缺少 geometry")
+	}
+	return parseGeoJSONGeometry(obj.Geometry)
+}
+
+// ParsePolygonGeoJSON 解析 GeoJSON 的 Polygon 或 MultiPolygon（也可以是包含它们的 Feature）
+func ParsePolygonGeoJSON(data []byte) (MultiPolygon, error) {
+	obj, err := parseGeoJSONGeometry(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var mp MultiPolygon
+	switch obj.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err := json.Unmarshal(obj.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("Polygon 坐标格式错误: %v", err)
+		}
+		mp = MultiPolygon{polygon}
+	case "MultiPolygon":
+		if err := json.Unmarshal(obj.Coordinates, &mp); err != nil {
+			return nil, fmt.Errorf("MultiPolygon 坐标格式错误: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的几何类型: %s，仅支持 Polygon 和 MultiPolygon", obj.Type)
+	}
+
+	if err := mp.Normalize(); err != nil {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// ParseLineStringGeoJSON 解析 GeoJSON 的 LineString（也可以是包含它的 Feature）
+func ParseLineStringGeoJSON(data []byte) (LineString, error) {
+	obj, err := parseGeoJSONGeometry(data)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != "LineString" {
+		return nil, fmt.Errorf("不支持的几何类型: %s，仅支持 LineString", obj.Type)
+	}
+
+	var line LineString
+	if err := json.Unmarshal(obj.Coordinates, &line); err != nil {
+		return nil, fmt.Errorf("LineString 坐标格式错误: %v", err)
+	}
+	if err := line.validate(); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// Normalize 校验坐标并补全未闭合的环
+func (mp MultiPolygon) Normalize() error {
+	if len(mp) == 0 {
+		return errors.New("多边形不能为空")
+	}
+	for i, polygon := range mp {
+		if len(polygon) == 0 {
+			return errors.New("多边形缺少外环")
+		}
+		for j, ring := range polygon {
+			for _, p := range ring {
+				if err := checkLngLat(p); err != nil {
+					return err
+				}
+			}
+			if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+				ring = append(ring, ring[0])
+				mp[i][j] = ring
+			}
+			if len(ring) < 4 {
+				return errors.New("多边形的每个环至少需要3个不同的点")
+			}
+			for k := 1; k < len(ring); k++ {
+				if crossesAntimeridian(ring[k-1], ring[k]) {
+					return errors.New("多边形的边跨越了180度经线，暂不支持，请拆分为经线两侧的两个多边形")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// crossesAntimeridian 相邻两点的经度差超过180度，即两点间较短的连线跨越了180度经线
+// 按经纬度直接计算的包含判断和外接矩形都会把这样的边当作绕地球一周的另一侧，因此不予支持
+func crossesAntimeridian(a, b [2]float64) bool {
+	return math.Abs(a[0]-b[0]) > 180
+}
+
+// checkLngLat 校验 [经度, 纬度] 是否在合法范围内
+func checkLngLat(p [2]float64) error {
+	if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+		return fmt.Errorf("坐标超出范围: [%v, %v]", p[0], p[1])
+	}
+	return nil
+}
+
+// Contains 判断点是否在多边形内（在外环内且不在任何洞内）
+func (mp MultiPolygon) Contains(lat, lng float64) bool {
+	for _, polygon := range mp {
+		if polygon.Contains(lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 判断点是否在多边形内（在外环内且不在任何洞内）
+func (p Polygon) Contains(lat, lng float64) bool {
+	if len(p) == 0 || !p[0].Contains(lat, lng) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains 射线法判断点是否在环内
+func (r Ring) Contains(lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// BoundingBox 返回多边形的外接矩形
+func (mp MultiPolygon) BoundingBox() BoundingBox {
+	box := BoundingBox{MinLat: math.Inf(1), MaxLat: math.Inf(-1), MinLng: math.Inf(1), MaxLng: math.Inf(-1)}
+	for _, polygon := range mp {
+		if len(polygon) == 0 {
+			continue
+		}
+		// 只需要外环即可确定范围
+		for _, p := range polygon[0] {
+			box.MinLng = math.Min(box.MinLng, p[0])
+			box.MaxLng = math.Max(box.MaxLng, p[0])
+			box.MinLat = math.Min(box.MinLat, p[1])
+			box.MaxLat = math.Max(box.MaxLat, p[1])
+		}
+	}
+	return box
+}
+
+// ToCanonicalCoord 将多边形的全部顶点从指定坐标系转换为统一存储的坐标系
+func (mp MultiPolygon) ToCanonicalCoord(from CoordSys) MultiPolygon {
+	if from == CanonicalCoordSys {
+		return mp
+	}
+	converted := make(MultiPolygon, len(mp))
+	for i, polygon := range mp {
+		converted[i] = make(Polygon, len(polygon))
+		for j, ring := range polygon {
+			converted[i][j] = make(Ring, len(ring))
+			for k, p := range ring {
+				lat, lng := ToCanonicalCoord(p[1], p[0], from)
+				converted[i][j][k] = [2]float64{lng, lat}
+			}
+		}
+	}
+	return converted
+}
+
+// FromCanonicalCoord 将多边形的全部顶点从统一存储的坐标系转换为指定坐标系
+func (mp MultiPolygon) FromCanonicalCoord(to CoordSys) MultiPolygon {
+	if to == CanonicalCoordSys {
+		return mp
+	}
+	converted := make(MultiPolygon, len(mp))
+	for i, polygon := range mp {
+		converted[i] = make(Polygon, len(polygon))
+		for j, ring := range polygon {
+			converted[i][j] = make(Ring, len(ring))
+			for k, p := range ring {
+				lat, lng := FromCanonicalCoord(p[1], p[0], to)
+				converted[i][j][k] = [2]float64{lng, lat}
+			}
+		}
+	}
+	return converted
+}
@@ -0,0 +1,123 @@
+package utils
+
+import "testing"
+
+func TestParsePolygonGeoJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantErr  bool
+		polygons int
+		rings    []int // 每个多边形的环数
+	}{
+		{
+			name:     "Polygon",
+			data:     `{"type":"Polygon","coordinates":[[[121,31],[122,31],[122,32],[121,32],[121,31]]]}`,
+			polygons: 1, rings: []int{1},
+		},
+		{
+			name:     "未闭合的环自动补全",
+			data:     `{"type":"Polygon","coordinates":[[[121,31],[122,31],[122,32]]]}`,
+			polygons: 1, rings: []int{1},
+		},
+		{
+			name: "带洞的Polygon",
+			data: `{"type":"Polygon","coordinates":[
+				[[121,31],[122,31],[122,32],[121,32],[121,31]],
+				[[121.4,31.4],[121.4,31.6],[121.6,31.6],[121.6,31.4],[121.4,31.4]]]}`,
+			polygons: 1, rings: []int{2},
+		},
+		{
+			name: "MultiPolygon",
+			data: `{"type":"MultiPolygon","coordinates":[
+				[[[121,31],[122,31],[122,32],[121,31]]],
+				[[[116,39],[117,39],[117,40],[116,39]]]]}`,
+			polygons: 2, rings: []int{1, 1},
+		},
+		{
+			name:     "Feature",
+			data:     `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[121,31],[122,31],[122,32],[121,31]]]}}`,
+			polygons: 1, rings: []int{1},
+		},
+		{"Feature缺少geometry", `{"type":"Feature","geometry":null}`, true, 0, nil},
+		{"不支持的几何类型", `{"type":"Point","coordinates":[121,31]}`, true, 0, nil},
+		{"坐标超出范围", `{"type":"Polygon","coordinates":[[[121,31],[190,31],[122,32],[121,31]]]}`, true, 0, nil},
+		{"边跨越180度经线", `{"type":"Polygon","coordinates":[[[179,0],[-179,0],[-179,1],[179,1],[179,0]]]}`, true, 0, nil},
+		{"点太少", `{"type":"Polygon","coordinates":[[[121,31],[122,31],[121,31]]]}`, true, 0, nil},
+		{"没有多边形", `{"type":"MultiPolygon","coordinates":[]}`, true, 0, nil},
+		{"JSON格式错误", `{"type":`, true, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp, err := ParsePolygonGeoJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolygonGeoJSON error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(mp) != tt.polygons {
+				t.Fatalf("多边形数量 = %d, want %d", len(mp), tt.polygons)
+			}
+			for i, polygon := range mp {
+				if len(polygon) != tt.rings[i] {
+					t.Errorf("第 %d 个多边形的环数 = %d, want %d", i, len(polygon), tt.rings[i])
+				}
+				for _, ring := range polygon {
+					if ring[0] != ring[len(ring)-1] {
+						t.Errorf("环没有闭合: %v", ring)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMultiPolygonContains(t *testing.T) {
+	// 上海附近带一个洞的正方形，以及北京附近的三角形
+	mp, err := ParsePolygonGeoJSON([]byte(`{"type":"MultiPolygon","coordinates":[
+		[[[121,31],[122,31],[122,32],[121,32]],[[121.4,31.4],[121.4,31.6],[121.6,31.6],[121.6,31.4]]],
+		[[[116,39],[117,39],[116,40]]]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     bool
+	}{
+		{"正方形内", 31.2, 121.2, true},
+		{"洞内", 31.5, 121.5, false},
+		{"洞与外环之间", 31.7, 121.5, true},
+		{"三角形内", 39.2, 116.2, true},
+		{"三角形斜边外", 39.9, 116.9, false},
+		{"两个多边形之外", 35, 119, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mp.Contains(tt.lat, tt.lng); got != tt.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+
+	box := mp.BoundingBox()
+	if box != (BoundingBox{MinLat: 31, MaxLat: 40, MinLng: 116, MaxLng: 122}) {
+		t.Errorf("BoundingBox() = %+v", box)
+	}
+}
+
+func TestMultiPolygonCoordRoundTrip(t *testing.T) {
+	mp := MultiPolygon{{{{121.47, 31.23}, {121.48, 31.23}, {121.48, 31.24}, {121.47, 31.23}}}}
+	for _, coordSys := range []CoordSys{CoordWGS84, CoordBD09} {
+		back := mp.FromCanonicalCoord(coordSys).ToCanonicalCoord(coordSys)
+		for i, p := range back[0][0] {
+			if !approxEqual(p[0], mp[0][0][i][0], 1e-5) || !approxEqual(p[1], mp[0][0][i][1], 1e-5) {
+				t.Errorf("%s: 第 %d 个顶点转换后为 %v, want %v", coordSys, i, p, mp[0][0][i])
+			}
+		}
+	}
+	if got := mp.ToCanonicalCoord(CanonicalCoordSys); &got[0] != &mp[0] {
+		t.Error("坐标系相同时不应复制")
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// 高亮关键字使用的标签
+const (
+	HighlightOpen  = "<mark>"
+	HighlightClose = "</mark>"
+)
+
+// Highlight 用 <mark> 标记文本中出现的关键字（英文字母不区分大小写），其余内容做HTML转义
+// maxRunes 大于0且文本较长时，只截取第一个关键字附近的 maxRunes 个字符作为摘要，省略部分用“…”表示
+func Highlight(text string, terms []string, maxRunes int) string {
+	lower := asciiLower(text)
+	marked := make([]bool, len(text))
+	first := -1
+	for _, term := range terms {
+		term = asciiLower(term)
+		if term == "" {
+			continue
+		}
+		for i := 0; ; {
+			j := strings.Index(lower[i:], term)
+			if j < 0 {
+				break
+			}
+			start := i + j
+			for k := start; k < start+len(term); k++ {
+				marked[k] = true
+			}
+			if first < 0 || start < first {
+				first = start
+			}
+			i = start + 1
+		}
+	}
+
+	// 截取摘要，关键字前保留约四分之一的长度
+	start, end := 0, len(text)
+	if maxRunes > 0 && utf8.RuneCountInString(text) > maxRunes {
+		var offsets []int
+		for i := range text {
+			offsets = append(offsets, i)
+		}
+		center := 0
+		if first > 0 {
+			center = utf8.RuneCountInString(text[:first])
+		}
+		from := center - maxRunes/4
+		if from < 0 {
+			from = 0
+		}
+		to := from + maxRunes
+		if to > len(offsets) {
+			to = len(offsets)
+			from = to - maxRunes
+		}
+		start = offsets[from]
+		if to < len(offsets) {
+			end = offsets[to]
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if marked[i] != inMark {
+			inMark = marked[i]
+			if inMark {
+				b.WriteString(HighlightOpen)
+			} else {
+				b.WriteString(HighlightClose)
+			}
+		}
+		b.WriteString(html.EscapeString(text[i : i+size]))
+		i += size
+	}
+	if inMark {
+		b.WriteString(HighlightClose)
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// asciiLower 将ASCII字母转为小写，不改变字节长度，保证与原文本的下标一致
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		maxRunes int
+		want     string
+	}{
+		{"没有关键字", "人民广场", nil, 0, "人民广场"},
+		{"中文关键字", "人民广场地铁站出口", []string{"广场"}, 0, "人民<mark>广场</mark>地铁站出口"},
+		{"英文不区分大小写", "Near KFC and kfc", []string{"Kfc"}, 0, "Near <mark>KFC</mark> and <mark>kfc</mark>"},
+		{"相邻的关键字合并", "地铁站", []string{"地铁", "站"}, 0, "<mark>地铁站</mark>"},
+		{"重叠的关键字", "aaa", []string{"aa"}, 0, "<mark>aaa</mark>"},
+		{"转义HTML", "<b>门口</b>", []string{"门口"}, 0, "&lt;b&gt;<mark>门口</mark>&lt;/b&gt;"},
+		{"截取关键字附近的摘要", "一二三四五六七八九十关键字一二三四五六七八九十", []string{"关键字"}, 8, "…九十<mark>关键字</mark>一二三…"},
+		{"关键字在开头", "关键字一二三四五六七八九十", []string{"关键字"}, 5, "<mark>关键字</mark>一二…"},
+		{"关键字在末尾", "一二三四五六七八九十关键字", []string{"关键字"}, 5, "…九十<mark>关键字</mark>"},
+		{"文本不超过长度时不截取", "一二三", []string{"二"}, 3, "一<mark>二</mark>三"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms, tt.maxRunes); got != tt.want {
+				t.Errorf("Highlight(%q, %q, %d) = %q, want %q", tt.text, tt.terms, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
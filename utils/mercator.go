@@ -0,0 +1,90 @@
+package utils
+
+import "math"
+
+const (
+	// TileSize Web墨卡托瓦片的像素大小
+	TileSize = 256
+	// MaxMercatorLat Web墨卡托投影的最大纬度
+	MaxMercatorLat = 85.05112878
+)
+
+// LngLatToPixel 将经纬度转换为指定缩放级别下的全局像素坐标（Web墨卡托）
+func LngLatToPixel(lat, lng float64, zoom int) (float64, float64) {
+	lat = math.Max(-MaxMercatorLat, math.Min(MaxMercatorLat, lat))
+	scale := float64(TileSize) * math.Exp2(float64(zoom))
+	latRad := lat * math.Pi / 180.0
+	x := (lng + 180.0) / 360.0 * scale
+	y := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * scale
+	return x, y
+}
+
+// PixelToLngLat 将指定缩放级别下的全局像素坐标转换为经纬度（Web墨卡托），是 LngLatToPixel 的逆运算
+func PixelToLngLat(x, y float64, zoom int) (float64, float64) {
+	scale := float64(TileSize) * math.Exp2(float64(zoom))
+	lng := NormalizeLng(x/scale*360.0 - 180.0)
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*y/scale))) * 180.0 / math.Pi
+	return lat, lng
+}
+
+// LngLatToTile 返回经纬度所在的瓦片行列号
+func LngLatToTile(lat, lng float64, zoom int) (int, int) {
+	x, y := LngLatToPixel(lat, lng, zoom)
+	maxIndex := int(math.Exp2(float64(zoom))) - 1
+	clamp := func(v int) int {
+		if v < 0 {
+			return 0
+		}
+		if v > maxIndex {
+			return maxIndex
+		}
+		return v
+	}
+	return clamp(int(math.Floor(x / TileSize))), clamp(int(math.Floor(y / TileSize)))
+}
+
+// TileBoundingBox 返回瓦片的经纬度范围
+func TileBoundingBox(zoom, x, y int) BoundingBox {
+	n := math.Exp2(float64(zoom))
+	tileLat := func(y float64) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180.0 / math.Pi
+	}
+	return BoundingBox{
+		MinLat: tileLat(float64(y + 1)),
+		MaxLat: tileLat(float64(y)),
+		MinLng: float64(x)/n*360.0 - 180.0,
+		MaxLng: float64(x+1)/n*360.0 - 180.0,
+	}
+}
+
+// TilesInBoundingBox 返回与矩形范围相交的全部瓦片，超过 maxTiles 个时返回 false
+func TilesInBoundingBox(box BoundingBox, zoom, maxTiles int) ([][2]int, bool) {
+	x0, y1 := LngLatToTile(box.MinLat, box.MinLng, zoom)
+	x1, y0 := LngLatToTile(box.MaxLat, box.MaxLng, zoom)
+	n := int(math.Exp2(float64(zoom)))
+
+	var columns []int
+	if box.CrossesAntimeridian() {
+		for x := x0; x < n; x++ {
+			columns = append(columns, x)
+		}
+		for x := 0; x <= x1; x++ {
+			columns = append(columns, x)
+		}
+	} else {
+		for x := x0; x <= x1; x++ {
+			columns = append(columns, x)
+		}
+	}
+
+	if len(columns)*(y1-y0+1) > maxTiles {
+		return nil, false
+	}
+	tiles := make([][2]int, 0, len(columns)*(y1-y0+1))
+	for _, x := range columns {
+		for y := y0; y <= y1; y++ {
+			tiles = append(tiles, [2]int{x, y})
+		}
+	}
+	return tiles, true
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestLngLatToPixel(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		zoom     int
+		x, y     float64
+	}{
+		{"原点", 0, 0, 0, 128, 128},
+		{"西北角", MaxMercatorLat, -180, 0, 0, 0},
+		{"东南角", -MaxMercatorLat, 180, 1, 512, 512},
+		{"超出最大纬度时取最大纬度", 89, 0, 2, 512, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := LngLatToPixel(tt.lat, tt.lng, tt.zoom)
+			if !approxEqual(x, tt.x, 1e-6) || !approxEqual(y, tt.y, 1e-6) {
+				t.Errorf("LngLatToPixel(%v, %v, %d) = (%v, %v), want (%v, %v)", tt.lat, tt.lng, tt.zoom, x, y, tt.x, tt.y)
+			}
+		})
+	}
+}
+
+func TestPixelRoundTrip(t *testing.T) {
+	for _, p := range [][2]float64{{31.2304, 121.4737}, {-33.8688, 151.2093}, {64.1466, -21.9426}, {0, -179.999}} {
+		for _, zoom := range []int{0, 10, 18} {
+			x, y := LngLatToPixel(p[0], p[1], zoom)
+			lat, lng := PixelToLngLat(x, y, zoom)
+			if !approxEqual(lat, p[0], 1e-9) || !approxEqual(lng, p[1], 1e-9) {
+				t.Errorf("zoom %d: %v 转换后为 (%v, %v)", zoom, p, lat, lng)
+			}
+		}
+	}
+}
+
+func TestLngLatToTile(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		zoom     int
+		x, y     int
+	}{
+		{"第0级只有一个瓦片", 31.2304, 121.4737, 0, 0, 0},
+		{"上海第15级", 31.2304, 121.4737, 15, 27440, 13389},
+		{"东南角取最后一个瓦片", -90, 180, 3, 7, 7},
+		{"西北角", 90, -180, 3, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := LngLatToTile(tt.lat, tt.lng, tt.zoom)
+			if x != tt.x || y != tt.y {
+				t.Errorf("LngLatToTile(%v, %v, %d) = (%d, %d), want (%d, %d)", tt.lat, tt.lng, tt.zoom, x, y, tt.x, tt.y)
+			}
+			if tt.lat > -MaxMercatorLat && tt.lat < MaxMercatorLat && tt.lng < 180 {
+				if box := TileBoundingBox(tt.zoom, x, y); !box.Contains(tt.lat, tt.lng) {
+					t.Errorf("瓦片 %d/%d/%d 的范围 %+v 不包含该点", tt.zoom, x, y, box)
+				}
+			}
+		})
+	}
+}
+
+func TestTileBoundingBox(t *testing.T) {
+	box := TileBoundingBox(1, 1, 0)
+	want := BoundingBox{MinLat: 0, MaxLat: MaxMercatorLat, MinLng: 0, MaxLng: 180}
+	if !approxEqual(box.MinLat, want.MinLat, 1e-9) || !approxEqual(box.MaxLat, want.MaxLat, 1e-6) ||
+		box.MinLng != want.MinLng || box.MaxLng != want.MaxLng {
+		t.Errorf("TileBoundingBox(1, 1, 0) = %+v, want %+v", box, want)
+	}
+}
+
+func TestTilesInBoundingBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      BoundingBox
+		zoom     int
+		maxTiles int
+		want     [][2]int
+		wantOK   bool
+	}{
+		{"单个瓦片", BoundingBox{MinLat: 10, MaxLat: 20, MinLng: 10, MaxLng: 20}, 1, 10, [][2]int{{1, 0}}, true},
+		{"四个瓦片", BoundingBox{MinLat: -10, MaxLat: 10, MinLng: -10, MaxLng: 10}, 1, 10, [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}}, true},
+		{"跨越180度经线", BoundingBox{MinLat: 10, MaxLat: 20, MinLng: 170, MaxLng: -170}, 2, 10, [][2]int{{0, 1}, {3, 1}}, true},
+		{"超过最大数量", BoundingBox{MinLat: -10, MaxLat: 10, MinLng: -10, MaxLng: 10}, 1, 3, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiles, ok := TilesInBoundingBox(tt.box, tt.zoom, tt.maxTiles)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			sort.Slice(tiles, func(i, j int) bool {
+				return tiles[i][0] < tiles[j][0] || tiles[i][0] == tiles[j][0] && tiles[i][1] < tiles[j][1]
+			})
+			if len(tiles) != len(tt.want) {
+				t.Fatalf("瓦片 = %v, want %v", tiles, tt.want)
+			}
+			for i := range tiles {
+				if tiles[i] != tt.want[i] {
+					t.Fatalf("瓦片 = %v, want %v", tiles, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// MVTExtent 矢量瓦片内坐标的默认范围
+const MVTExtent = 4096
+
+// MVTLayer Mapbox Vector Tile（v2）的一个图层，目前只支持点要素
+// 规范见 https://github.com/mapbox/vector-tile-spec/tree/master/2.1
+type MVTLayer struct {
+	Name     string
+	Extent   uint32
+	features [][]byte
+	keys     []string
+	keyIndex map[string]uint32
+	values   [][]byte
+	valIndex map[string]uint32
+}
+
+// NewMVTLayer 创建图层
+func NewMVTLayer(name string) *MVTLayer {
+	return &MVTLayer{
+		Name:     name,
+		Extent:   MVTExtent,
+		keyIndex: make(map[string]uint32),
+		valIndex: make(map[string]uint32),
+	}
+}
+
+// Len 图层中的要素数量
+func (l *MVTLayer) Len() int {
+	return len(l.features)
+}
+
+// MVTProperty 要素的一个属性，值支持 string、bool、整数和浮点数，其余类型会被忽略
+type MVTProperty struct {
+	Key   string
+	Value interface{}
+}
+
+// AddPoint 添加一个点要素，x、y 为瓦片内坐标（0到Extent，缓冲区内的点可以超出该范围）
+func (l *MVTLayer) AddPoint(id uint64, x, y int, properties ...MVTProperty) {
+	var tags []uint32
+	for _, p := range properties {
+		value, ok := encodeMVTValue(p.Value)
+		if !ok {
+			continue
+		}
+		tags = append(tags, l.key(p.Key), l.value(value))
+	}
+
+	var feature []byte
+	feature = appendVarintField(feature, 1, id)
+	if len(tags) > 0 {
+		feature = appendPackedField(feature, 2, tags)
+	}
+	feature = appendVarintField(feature, 3, 1) // GeomType POINT
+	// MoveTo 命令，1个点，坐标使用zigzag编码
+	geometry := []uint32{1&0x7 | 1<<3, zigzag32(x), zigzag32(y)}
+	feature = appendPackedField(feature, 4, geometry)
+	l.features = append(l.features, feature)
+}
+
+// key 返回属性名的下标，属性名在图层内去重
+func (l *MVTLayer) key(key string) uint32 {
+	if i, ok := l.keyIndex[key]; ok {
+		return i
+	}
+	i := uint32(len(l.keys))
+	l.keys = append(l.keys, key)
+	l.keyIndex[key] = i
+	return i
+}
+
+// value 返回已编码属性值的下标，属性值在图层内去重
+func (l *MVTLayer) value(encoded []byte) uint32 {
+	if i, ok := l.valIndex[string(encoded)]; ok {
+		return i
+	}
+	i := uint32(len(l.values))
+	l.values = append(l.values, encoded)
+	l.valIndex[string(encoded)] = i
+	return i
+}
+
+// encode 编码为 Layer 消息
+func (l *MVTLayer) encode() []byte {
+	var buf []byte
+	buf = appendVarintField(buf, 15, 2) // version
+	buf = appendBytesField(buf, 1, []byte(l.Name))
+	for _, f := range l.features {
+		buf = appendBytesField(buf, 2, f)
+	}
+	for _, k := range l.keys {
+		buf = appendBytesField(buf, 3, []byte(k))
+	}
+	for _, v := range l.values {
+		buf = appendBytesField(buf, 4, v)
+	}
+	return appendVarintField(buf, 5, uint64(l.Extent))
+}
+
+// EncodeMVT 将图层编码为矢量瓦片，没有要素的图层会被省略
+func EncodeMVT(layers ...*MVTLayer) []byte {
+	var buf []byte
+	for _, l := range layers {
+		if l.Len() > 0 {
+			buf = appendBytesField(buf, 3, l.encode())
+		}
+	}
+	return buf
+}
+
+// encodeMVTValue 编码 Value 消息
+func encodeMVTValue(v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return appendBytesField(nil, 1, []byte(v)), true
+	case float64:
+		buf := binary.AppendUvarint(nil, 3<<3|1)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), true
+	case float32:
+		return encodeMVTValue(float64(v))
+	case int:
+		return encodeMVTInt(int64(v)), true
+	case int64:
+		return encodeMVTInt(v), true
+	case uint:
+		return appendVarintField(nil, 5, uint64(v)), true
+	case uint64:
+		return appendVarintField(nil, 5, v), true
+	case bool:
+		b := uint64(0)
+		if v {
+			b = 1
+		}
+		return appendVarintField(nil, 7, b), true
+	}
+	return nil, false
+}
+
+// encodeMVTInt 非负整数使用 uint_value，负数使用 sint_value
+func encodeMVTInt(v int64) []byte {
+	if v >= 0 {
+		return appendVarintField(nil, 5, uint64(v))
+	}
+	return appendVarintField(nil, 6, uint64((v<<1)^(v>>63)))
+}
+
+func zigzag32(v int) uint32 {
+	n := int32(v)
+	return uint32((n << 1) ^ (n >> 31))
+}
+
+// appendVarintField 追加 varint 类型的 protobuf 字段
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3)
+	return binary.AppendUvarint(buf, v)
+}
+
+// appendBytesField 追加 length-delimited 类型的 protobuf 字段
+func appendBytesField(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// appendPackedField 追加 packed repeated uint32 字段
+func appendPackedField(buf []byte, field int, values []uint32) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, uint64(v))
+	}
+	return appendBytesField(buf, field, packed)
+}
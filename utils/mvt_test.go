@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// pbField 解码得到的一个 protobuf 字段，varint 类型的值在 Varint 中，其余类型的内容在 Bytes 中
+type pbField struct {
+	Num    int
+	Varint uint64
+	Bytes  []byte
+}
+
+// decodePB 按 wire type 解码一条 protobuf 消息的全部字段
+func decodePB(t *testing.T, data []byte) []pbField {
+	t.Helper()
+	var fields []pbField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("无效的字段键: %x", data)
+		}
+		data = data[n:]
+		f := pbField{Num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("无效的varint: %x", data)
+			}
+			f.Varint, data = v, data[n:]
+		case 1:
+			f.Bytes, data = data[:8], data[8:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || int(size) > len(data)-n {
+				t.Fatalf("无效的长度: %x", data)
+			}
+			f.Bytes, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			t.Fatalf("不支持的wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// decodePacked 解码 packed repeated uint32 字段
+func decodePacked(t *testing.T, data []byte) []uint32 {
+	t.Helper()
+	var values []uint32
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("无效的packed字段: %x", data)
+		}
+		values, data = append(values, uint32(v)), data[n:]
+	}
+	return values
+}
+
+// decodedFeature 解码后的点要素
+type decodedFeature struct {
+	ID         uint64
+	X, Y       int
+	Properties map[string]interface{}
+}
+
+// decodedLayer 解码后的图层
+type decodedLayer struct {
+	Version  uint64
+	Name     string
+	Extent   uint64
+	Features []decodedFeature
+}
+
+// decodeMVT 按 Mapbox Vector Tile 规范解码瓦片，只支持点要素
+func decodeMVT(t *testing.T, tile []byte) []decodedLayer {
+	t.Helper()
+	var layers []decodedLayer
+	for _, lf := range decodePB(t, tile) {
+		if lf.Num != 3 {
+			t.Fatalf("瓦片中出现未知字段 %d", lf.Num)
+		}
+		var layer decodedLayer
+		var keys []string
+		var values []interface{}
+		var features [][]pbField
+		for _, f := range decodePB(t, lf.Bytes) {
+			switch f.Num {
+			case 15:
+				layer.Version = f.Varint
+			case 1:
+				layer.Name = string(f.Bytes)
+			case 2:
+				features = append(features, decodePB(t, f.Bytes))
+			case 3:
+				keys = append(keys, string(f.Bytes))
+			case 4:
+				values = append(values, decodeMVTTestValue(t, f.Bytes))
+			case 5:
+				layer.Extent = f.Varint
+			}
+		}
+		for _, fields := range features {
+			feature := decodedFeature{Properties: make(map[string]interface{})}
+			for _, f := range fields {
+				switch f.Num {
+				case 1:
+					feature.ID = f.Varint
+				case 2:
+					tags := decodePacked(t, f.Bytes)
+					for i := 0; i+1 < len(tags); i += 2 {
+						feature.Properties[keys[tags[i]]] = values[tags[i+1]]
+					}
+				case 3:
+					if f.Varint != 1 {
+						t.Fatalf("GeomType = %d, want POINT", f.Varint)
+					}
+				case 4:
+					geometry := decodePacked(t, f.Bytes)
+					if len(geometry) != 3 || geometry[0] != 1|1<<3 {
+						t.Fatalf("点要素的几何编码有误: %v", geometry)
+					}
+					unzigzag := func(v uint32) int { return int(int32(v>>1) ^ -int32(v&1)) }
+					feature.X, feature.Y = unzigzag(geometry[1]), unzigzag(geometry[2])
+				}
+			}
+			layer.Features = append(layer.Features, feature)
+		}
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// decodeMVTTestValue 解码 Value 消息
+func decodeMVTTestValue(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	fields := decodePB(t, data)
+	if len(fields) != 1 {
+		t.Fatalf("Value 消息应只有一个字段: %x", data)
+	}
+	f := fields[0]
+	switch f.Num {
+	case 1:
+		return string(f.Bytes)
+	case 3:
+		return math.Float64frombits(binary.LittleEndian.Uint64(f.Bytes))
+	case 5:
+		return int64(f.Varint)
+	case 6:
+		return int64(f.Varint>>1) ^ -int64(f.Varint&1)
+	case 7:
+		return f.Varint != 0
+	}
+	t.Fatalf("未知的Value字段 %d", f.Num)
+	return nil
+}
+
+func TestEncodeMVT(t *testing.T) {
+	layer := NewMVTLayer("trashcans")
+	layer.AddPoint(1, 0, 0, MVTProperty{"count", 1}, MVTProperty{"has_image", true})
+	layer.AddPoint(42, 4095, 2048, MVTProperty{"count", 3}, MVTProperty{"has_image", false}, MVTProperty{"name", "人民广场"})
+	layer.AddPoint(7, -64, 4160,
+		MVTProperty{"count", 1},
+		MVTProperty{"like_count", int64(-2)},
+		MVTProperty{"score", 0.5},
+		MVTProperty{"ratio", float32(0.25)},
+		MVTProperty{"big", uint64(1) << 40},
+		MVTProperty{"ignored", []int{1}}, // 不支持的类型被忽略
+	)
+	empty := NewMVTLayer("empty")
+
+	layers := decodeMVT(t, EncodeMVT(layer, empty))
+	if len(layers) != 1 {
+		t.Fatalf("图层数量 = %d, want 1（没有要素的图层应被省略）", len(layers))
+	}
+	got := layers[0]
+	if got.Version != 2 || got.Name != "trashcans" || got.Extent != MVTExtent {
+		t.Errorf("图层 = version %d, name %q, extent %d", got.Version, got.Name, got.Extent)
+	}
+
+	want := []decodedFeature{
+		{ID: 1, X: 0, Y: 0, Properties: map[string]interface{}{"count": int64(1), "has_image": true}},
+		{ID: 42, X: 4095, Y: 2048, Properties: map[string]interface{}{"count": int64(3), "has_image": false, "name": "人民广场"}},
+		{ID: 7, X: -64, Y: 4160, Properties: map[string]interface{}{
+			"count": int64(1), "like_count": int64(-2), "score": 0.5, "ratio": 0.25, "big": int64(1) << 40,
+		}},
+	}
+	if len(got.Features) != len(want) {
+		t.Fatalf("要素数量 = %d, want %d", len(got.Features), len(want))
+	}
+	for i, w := range want {
+		f := got.Features[i]
+		if f.ID != w.ID || f.X != w.X || f.Y != w.Y {
+			t.Errorf("要素 %d = id %d (%d, %d), want id %d (%d, %d)", i, f.ID, f.X, f.Y, w.ID, w.X, w.Y)
+		}
+		if len(f.Properties) != len(w.Properties) {
+			t.Errorf("要素 %d 的属性 = %v, want %v", i, f.Properties, w.Properties)
+		}
+		for k, v := range w.Properties {
+			if f.Properties[k] != v {
+				t.Errorf("要素 %d 的属性 %s = %#v, want %#v", i, k, f.Properties[k], v)
+			}
+		}
+	}
+}
+
+func TestMVTLayerDeduplicatesKeysAndValues(t *testing.T) {
+	layer := NewMVTLayer("trashcans")
+	for i := 0; i < 100; i++ {
+		layer.AddPoint(uint64(i), i, i, MVTProperty{"count", i % 3}, MVTProperty{"has_image", i%2 == 0})
+	}
+	if layer.Len() != 100 {
+		t.Fatalf("Len() = %d, want 100", layer.Len())
+	}
+	if len(layer.keys) != 2 {
+		t.Errorf("属性名数量 = %d, want 2", len(layer.keys))
+	}
+	// count 有 0、1、2 三种取值，has_image 有 true、false 两种
+	if len(layer.values) != 5 {
+		t.Errorf("属性值数量 = %d, want 5", len(layer.values))
+	}
+	if tile := EncodeMVT(NewMVTLayer("empty")); len(tile) != 0 {
+		t.Errorf("只有空图层时瓦片长度 = %d, want 0", len(tile))
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"math"
+)
+
+// DefaultPolylinePrecision 编码折线默认的精度（小数位数），与高德、谷歌地图一致
+const DefaultPolylinePrecision = 5
+
+// DecodePolyline 解码 Encoded Polyline Algorithm Format 格式的折线
+// precision 为坐标的小数位数，小于等于0时使用默认精度5
+func DecodePolyline(encoded string, precision int) (LineString, error) {
+	if precision <= 0 {
+		precision = DefaultPolylinePrecision
+	}
+	if precision > 10 {
+		return nil, errors.New("折线精度不能超过10")
+	}
+	factor := math.Pow10(precision)
+
+	var line LineString
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		var delta [2]int64
+		for k := range delta {
+			var result int64
+			var shift uint
+			for {
+				if i >= len(encoded) {
+					return nil, errors.New("折线编码不完整")
+				}
+				b := int64(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, errors.New("折线编码包含非法字符")
+				}
+				if shift > 60 {
+					return nil, errors.New("折线编码格式错误")
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				delta[k] = ^(result >> 1)
+			} else {
+				delta[k] = result >> 1
+			}
+		}
+		lat += delta[0]
+		lng += delta[1]
+		line = append(line, [2]float64{float64(lng) / factor, float64(lat) / factor})
+	}
+
+	if err := line.validate(); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
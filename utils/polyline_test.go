@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoded   string
+		precision int
+		want      LineString
+		wantErr   string
+	}{
+		{
+			name:    "谷歌文档中的示例",
+			encoded: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			want:    LineString{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name:      "精度6",
+			encoded:   "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI",
+			precision: 6,
+			want:      LineString{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{name: "空字符串", encoded: "", wantErr: "至少需要2个点"},
+		{name: "只有一个点", encoded: "_p~iF~ps|U", wantErr: "至少需要2个点"},
+		{name: "编码不完整", encoded: "_p~iF~ps|U_ulL", wantErr: "不完整"},
+		{name: "非法字符", encoded: "_p~iF~ps|U_ul L", wantErr: "非法字符"},
+		{name: "数值过长", encoded: strings.Repeat("~", 20) + "?", wantErr: "格式错误"},
+		{name: "精度超过10", encoded: "_p~iF~ps|U_ulLnnqC", precision: 11, wantErr: "不能超过10"},
+		{name: "纬度超出范围", encoded: "??_mljP?", wantErr: "超出范围"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePolyline(tt.encoded, tt.precision)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodePolyline(%q) error = %v, want 包含 %q", tt.encoded, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodePolyline(%q) error = %v", tt.encoded, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("DecodePolyline(%q) = %v, want %v", tt.encoded, got, tt.want)
+			}
+			for i := range got {
+				if !approxEqual(got[i][0], tt.want[i][0], 1e-9) || !approxEqual(got[i][1], tt.want[i][1], 1e-9) {
+					t.Fatalf("DecodePolyline(%q) = %v, want %v", tt.encoded, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"math"
+)
+
+// LineString 折线，坐标顺序与 GeoJSON 一致为 [经度, 纬度]
+type LineString [][2]float64
+
+// validate 校验折线至少包含两个点、坐标合法且不跨越180度经线
+func (l LineString) validate() error {
+	if len(l) < 2 {
+		return errors.New("路线至少需要2个点")
+	}
+	for i, p := range l {
+		if err := checkLngLat(p); err != nil {
+			return err
+		}
+		if i > 0 && crossesAntimeridian(l[i-1], p) {
+			return errors.New("路线跨越了180度经线，暂不支持，请在经线处拆分后分别查询")
+		}
+	}
+	return nil
+}
+
+// Length 折线总长度（公里）
+func (l LineString) Length() float64 {
+	var length float64
+	for i := 1; i < len(l); i++ {
+		length += CalculateDistance(l[i-1][1], l[i-1][0], l[i][1], l[i][0])
+	}
+	return length
+}
+
+// BoundingBox 返回折线的外接矩形
+func (l LineString) BoundingBox() BoundingBox {
+	box := BoundingBox{MinLat: math.Inf(1), MaxLat: math.Inf(-1), MinLng: math.Inf(1), MaxLng: math.Inf(-1)}
+	for _, p := range l {
+		box.MinLng = math.Min(box.MinLng, p[0])
+		box.MaxLng = math.Max(box.MaxLng, p[0])
+		box.MinLat = math.Min(box.MinLat, p[1])
+		box.MaxLat = math.Max(box.MaxLat, p[1])
+	}
+	return box
+}
+
+// ToCanonicalCoord 将折线的全部顶点从指定坐标系转换为统一存储的坐标系
+func (l LineString) ToCanonicalCoord(from CoordSys) LineString {
+	if from == CanonicalCoordSys {
+		return l
+	}
+	converted := make(LineString, len(l))
+	for i, p := range l {
+		lat, lng := ToCanonicalCoord(p[1], p[0], from)
+		converted[i] = [2]float64{lng, lat}
+	}
+	return converted
+}
+
+// Project 计算点到折线的最短距离 offset，以及最近点沿折线距起点的距离 along（单位均为公里）
+// 点离折线的多个位置同样近时取最靠前的位置
+func (l LineString) Project(lat, lng float64) (along, offset float64) {
+	offset = math.Inf(1)
+	var start float64
+	for i := 1; i < len(l); i++ {
+		a, b := l[i-1], l[i]
+		segAlong, segOffset, segLength := PointToSegment(lat, lng, a[1], a[0], b[1], b[0])
+		if segOffset < offset {
+			offset = segOffset
+			along = start + segAlong
+		}
+		start += segLength
+	}
+	return along, offset
+}
+
+// PointToSegment 计算点 P 到大圆线段 AB 的最短距离
+// 返回最近点距 A 的距离 along、P 到线段的距离 offset 以及线段长度 length（单位均为公里）
+func PointToSegment(lat, lng, latA, lngA, latB, lngB float64) (along, offset, length float64) {
+	length = CalculateDistance(latA, lngA, latB, lngB)
+	dAP := CalculateDistance(latA, lngA, lat, lng)
+	if length == 0 || dAP == 0 {
+		return 0, dAP, length
+	}
+
+	// 球面上的交叉航迹距离与沿航迹距离
+	delta13 := dAP / EarthRadius
+	theta := bearing(latA, lngA, lat, lng) - bearing(latA, lngA, latB, lngB)
+	crossTrack := math.Asin(math.Max(-1, math.Min(1, math.Sin(delta13)*math.Sin(theta))))
+	alongTrack := math.Acos(math.Max(-1, math.Min(1, math.Cos(delta13)/math.Cos(crossTrack))))
+	if math.Cos(theta) < 0 {
+		alongTrack = -alongTrack
+	}
+	alongTrack *= EarthRadius
+
+	switch {
+	case alongTrack <= 0:
+		// 垂足在 A 之前，最近点为 A
+		return 0, dAP, length
+	case alongTrack >= length:
+		// 垂足在 B 之后，最近点为 B
+		return length, CalculateDistance(latB, lngB, lat, lng), length
+	}
+	return alongTrack, math.Abs(crossTrack) * EarthRadius, length
+}
+
+// bearing 计算从第一个点到第二个点的初始方位角（弧度）
+func bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180.0
+	phi2 := lat2 * math.Pi / 180.0
+	deltaLng := (lng2 - lng1) * math.Pi / 180.0
+	y := math.Sin(deltaLng) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(deltaLng)
+	return math.Atan2(y, x)
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+// 赤道上1度对应的距离（公里）
+var oneDegree = EarthRadius * math.Pi / 180
+
+func TestPointToSegment(t *testing.T) {
+	tests := []struct {
+		name           string
+		lat, lng       float64
+		along, offset  float64
+		alongTolerance float64
+	}{
+		{"垂足在线段中间", 0.1, 0.5, 0.5 * oneDegree, 0.1 * oneDegree, 1e-3},
+		{"在线段上", 0, 0.25, 0.25 * oneDegree, 0, 1e-6},
+		{"在起点之前", 0, -0.5, 0, 0.5 * oneDegree, 1e-9},
+		{"在终点之后", 0.1, 1.5, oneDegree, CalculateDistance(0, 1, 0.1, 1.5), 1e-9},
+		{"与起点重合", 0, 0, 0, 0, 1e-9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 线段为赤道上从经度0到经度1
+			along, offset, length := PointToSegment(tt.lat, tt.lng, 0, 0, 0, 1)
+			if !approxEqual(length, oneDegree, 1e-9) {
+				t.Errorf("length = %v, want %v", length, oneDegree)
+			}
+			if !approxEqual(along, tt.along, tt.alongTolerance) {
+				t.Errorf("along = %v, want %v", along, tt.along)
+			}
+			if !approxEqual(offset, tt.offset, 1e-3) {
+				t.Errorf("offset = %v, want %v", offset, tt.offset)
+			}
+		})
+	}
+
+	// 线段长度为0时返回到该点的距离
+	if along, offset, length := PointToSegment(0, 1, 0, 0, 0, 0); along != 0 || length != 0 || !approxEqual(offset, oneDegree, 1e-9) {
+		t.Errorf("退化线段: along %v, offset %v, length %v", along, offset, length)
+	}
+}
+
+func TestLineStringProject(t *testing.T) {
+	// 先沿赤道向东1度，再向北1度
+	line := LineString{{0, 0}, {1, 0}, {1, 1}}
+	if got := line.Length(); !approxEqual(got, 2*oneDegree, 1e-9) {
+		t.Errorf("Length() = %v, want %v", got, 2*oneDegree)
+	}
+
+	tests := []struct {
+		name          string
+		lat, lng      float64
+		along, offset float64
+	}{
+		{"靠近第一段", -0.05, 0.5, 0.5 * oneDegree, 0.05 * oneDegree},
+		{"靠近第二段", 0.5, 1.05, 1.5 * oneDegree, 0.05 * oneDegree},
+		{"拐角", 0, 1, oneDegree, 0},
+		{"终点之后", 1.2, 1, 2 * oneDegree, 0.2 * oneDegree},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			along, offset := line.Project(tt.lat, tt.lng)
+			if !approxEqual(along, tt.along, 0.05) || !approxEqual(offset, tt.offset, 0.05) {
+				t.Errorf("Project(%v, %v) = (%v, %v), want (%v, %v)", tt.lat, tt.lng, along, offset, tt.along, tt.offset)
+			}
+		})
+	}
+}
+
+func TestParseLineStringGeoJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		points  int
+		wantErr bool
+	}{
+		{"LineString", `{"type":"LineString","coordinates":[[121.47,31.23],[121.48,31.24],[121.5,31.24]]}`, 3, false},
+		{"Feature", `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[121.47,31.23],[121.48,31.24]]}}`, 2, false},
+		{"只有一个点", `{"type":"LineString","coordinates":[[121.47,31.23]]}`, 0, true},
+		{"坐标超出范围", `{"type":"LineString","coordinates":[[121.47,31.23],[121.48,91]]}`, 0, true},
+		{"跨越180度经线", `{"type":"LineString","coordinates":[[179.9,0],[-179.9,0]]}`, 0, true},
+		{"不支持的几何类型", `{"type":"Polygon","coordinates":[]}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := ParseLineStringGeoJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLineStringGeoJSON error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(line) != tt.points {
+				t.Errorf("点数 = %d, want %d", len(line), tt.points)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "math"
+
+// WilsonLowerBound 计算比例 positive/total 的Wilson置信区间下界
+// 投票数越少下界越低，用于在样本量不同的情况下比较好评率；total 为0时返回0
+func WilsonLowerBound(positive, total int64, z float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	n := float64(total)
+	p := float64(positive) / n
+	z2 := z * z
+	center := p + z2/(2*n)
+	margin := z * math.Sqrt((p*(1-p)+z2/(4*n))/n)
+	return math.Max(0, (center-margin)/(1+z2/n))
+}
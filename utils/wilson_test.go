@@ -0,0 +1,53 @@
+package utils
+
+import "testing"
+
+func TestWilsonLowerBound(t *testing.T) {
+	tests := []struct {
+		name            string
+		positive, total int64
+		z               float64
+		want            float64
+	}{
+		{"没有投票", 0, 0, 1.96, 0},
+		{"总数为负", 1, -1, 1.96, 0},
+		{"全部为差评", 0, 10, 1.96, 0},
+		{"1票好评", 1, 1, 1.96, 0.206543},
+		{"10票全部好评", 10, 10, 1.96, 0.722460},
+		{"一半好评", 50, 100, 1.96, 0.403830},
+		{"z为0时等于好评率", 3, 4, 0, 0.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WilsonLowerBound(tt.positive, tt.total, tt.z); !approxEqual(got, tt.want, 1e-6) {
+				t.Errorf("WilsonLowerBound(%d, %d, %v) = %v, want %v", tt.positive, tt.total, tt.z, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWilsonLowerBoundOrdering(t *testing.T) {
+	// 好评率相同时票数越多下界越高，且下界不超过好评率
+	tests := []struct {
+		name         string
+		lower, upper [2]int64 // {positive, total}
+	}{
+		{"票数更多", [2]int64{1, 1}, [2]int64{100, 100}},
+		{"好评率相同票数更多", [2]int64{8, 10}, [2]int64{80, 100}},
+		{"少量好评不如大量高好评率", [2]int64{2, 2}, [2]int64{95, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := WilsonLowerBound(tt.lower[0], tt.lower[1], 1.96)
+			b := WilsonLowerBound(tt.upper[0], tt.upper[1], 1.96)
+			if a >= b {
+				t.Errorf("WilsonLowerBound%v = %v, 应小于 WilsonLowerBound%v = %v", tt.lower, a, tt.upper, b)
+			}
+			for _, v := range [][2]int64{tt.lower, tt.upper} {
+				if got, rate := WilsonLowerBound(v[0], v[1], 1.96), float64(v[0])/float64(v[1]); got > rate {
+					t.Errorf("WilsonLowerBound%v = %v, 超过了好评率 %v", v, got, rate)
+				}
+			}
+		})
+	}
+}